@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// roundingModes are the accepted --rounding values.
+var roundingModes = []string{"half-even", "half-up", "down", "up"}
+
+// amountFormat controls how exported amounts are written.
+type amountFormat struct {
+	raw      bool   // write integers in the smallest unit, e.g. attoFIL
+	decimals int    // round to at most this many decimal places; -1 for all
+	rounding string // one of roundingModes; half-even if empty
+
+	decimalComma bool // separate decimals with a comma, as much of Europe does
+}
+
+func newAmountFormat(raw bool, decimals int, rounding string) (amountFormat, error) {
+	if decimals < -1 {
+		return amountFormat{}, fmt.Errorf("--decimals %d must be -1 or more", decimals)
+	}
+	if rounding != "" && !slices.Contains(roundingModes, rounding) {
+		return amountFormat{}, fmt.Errorf("unknown --rounding %q, want one of %s", rounding, strings.Join(roundingModes, ", "))
+	}
+	return amountFormat{raw: raw, decimals: decimals, rounding: rounding}, nil
+}
+
+// format writes v, an integer count of 10^-scale units, as a decimal, exactly
+// unless rounded to fewer places. Trailing zeros are trimmed.
+func (af amountFormat) format(v *big.Int, scale int) string {
+	if v == nil {
+		v = new(big.Int)
+	}
+	if af.raw {
+		return v.String()
+	}
+	a := new(big.Int).Abs(v)
+	if af.decimals >= 0 && af.decimals < scale {
+		a = af.round(a, scale-af.decimals)
+		scale = af.decimals
+	}
+
+	digits := a.String()
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-scale], strings.TrimRight(digits[len(digits)-scale:], "0")
+	s := whole
+	if frac != "" {
+		if af.decimalComma {
+			s += "," + frac
+		} else {
+			s += "." + frac
+		}
+	}
+	if v.Sign() < 0 && a.Sign() != 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// round divides a, which must not be negative, by 10^places, rounding the
+// quotient by the configured mode.
+func (af amountFormat) round(a *big.Int, places int) *big.Int {
+	div := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	q, r := new(big.Int).QuoRem(a, div, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	half := new(big.Int).Lsh(r, 1).Cmp(div) // sign of r - div/2
+	var up bool
+	switch af.rounding {
+	case "down":
+	case "up":
+		up = true
+	case "half-up":
+		up = half >= 0
+	default: // half-even
+		up = half > 0 || (half == 0 && q.Bit(0) == 1)
+	}
+	if up {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
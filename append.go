@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// appendTo adds xfers to the export of the CSV format f already at path,
+// leaving out those of messages the file has in its appendKey column. The
+// file must have the columns this export would write, in the same order.
+func (f format) appendTo(path string, xfers []Transfer, opts exportOptions) error {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	header, seen, err := f.exportedMessages(existing, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	fresh := slices.DeleteFunc(slices.Clone(xfers), func(x Transfer) bool { return seen[x.MessageID] })
+	if len(fresh) == 0 {
+		slog.Info("No new transfers to append", "path", path)
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := f.write(&buf, fresh, opts); err != nil {
+		return err
+	}
+	// Header fields never span lines, so the rows follow the first
+	first, rows, _ := bytes.Cut(buf.Bytes(), []byte("\n"))
+	if want := string(bytes.TrimSuffix(first, []byte("\r"))); !slices.Equal(header, splitHeader(want, opts)) {
+		return fmt.Errorf("%s has other columns than this export, so can't be appended to", path)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		rows = append([]byte("\n"), rows...)
+	}
+	_, err = file.Write(rows)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		slog.Info("Appended transfers", "path", path, "count", len(fresh), "skipped", len(xfers)-len(fresh))
+	}
+	return err
+}
+
+// exportedMessages reads the header of a CSV export of f, and the message
+// CIDs in its appendKey column.
+func (f format) exportedMessages(data []byte, opts exportOptions) (header []string, seen map[string]bool, err error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	if opts.delimiter != 0 {
+		r.Comma = opts.delimiter
+	}
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, errors.New("no header to append under")
+	}
+	header = records[0]
+	col := slices.Index(header, f.appendKey)
+	if col < 0 {
+		return nil, nil, fmt.Errorf("no %s column to tell which transfers it has", f.appendKey)
+	}
+	seen = make(map[string]bool)
+	for _, record := range records[1:] {
+		if col < len(record) && record[col] != "" {
+			seen[record[col]] = true
+		}
+	}
+	return header, seen, nil
+}
+
+// splitHeader parses the header line of a CSV export.
+func splitHeader(line string, opts exportOptions) []string {
+	r := csv.NewReader(strings.NewReader(line))
+	if opts.delimiter != 0 {
+		r.Comma = opts.delimiter
+	}
+	header, _ := r.Read()
+	return header
+}
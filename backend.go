@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/address"
+	"github.com/mroth/filfoxy/pkg/filfox"
+	"github.com/mroth/filfoxy/pkg/httpcache"
+	"github.com/mroth/filfoxy/pkg/source"
+	"github.com/mroth/filfoxy/pkg/vcr"
+)
+
+// fetchOptions holds the command line settings that control retrieval.
+type fetchOptions struct {
+	backend          string
+	failover         string   // secondary backend, if any
+	merge            []string // further backends whose records are merged in, if any
+	breakerThreshold int
+	maxRetries       int
+	timeout          time.Duration
+	concurrency      int
+	maxPages         int
+	strict           bool
+	pageSize         int
+	types            []string // server side transfer type filter, where supported
+	heights          source.HeightRange
+	resume           bool
+	limiter          *filfox.RateLimiter // shared across every client, may be nil
+	cacheDir         string              // on-disk HTTP cache, disabled if empty
+	cacheTTL         time.Duration
+	apiKey           string
+	userAgent        string
+	headers          http.Header           // added to every request, for any backend
+	debugHTTP        string                // directory to dump raw HTTP exchanges to, if set
+	fixtures         string                // serve responses from this dump directory instead of the network, if set
+	progress         func(filfox.Progress) // reports how far fetching transfers has got, if set
+
+	// wrapTransport, if set, wraps the outermost transport, e.g. to observe
+	// responses.
+	wrapTransport func(http.RoundTripper) http.RoundTripper
+}
+
+// newSource builds the configured backend for fetching wallet, guarded by a
+// circuit breaker and failing over to the secondary backend if one is set.
+// Records of any merged backends are combined with its own.
+func newSource(wallet string, opts fetchOptions) (source.TransferSource, error) {
+	var src source.TransferSource
+	src, err := newTransferSource(opts.backend, wallet, opts)
+	if err != nil {
+		return nil, err
+	}
+	src = source.NewBreaker(src, opts.breakerThreshold)
+	if opts.failover != "" {
+		secondary, err := newTransferSource(opts.failover, wallet, opts)
+		if err != nil {
+			return nil, err
+		}
+		src = source.NewFailover(src, source.NewBreaker(secondary, opts.breakerThreshold))
+	}
+	if len(opts.merge) > 0 {
+		sources := []source.TransferSource{src}
+		for _, name := range opts.merge {
+			other, err := newTransferSource(name, wallet, opts)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, source.NewBreaker(other, opts.breakerThreshold))
+		}
+		src = source.NewMerge(sources, reportGaps)
+	}
+	return src, nil
+}
+
+// reportGaps logs the records of address that some merged backends lacked.
+func reportGaps(address string, gaps []source.Gap) {
+	if len(gaps) == 0 {
+		slog.Info("All merged backends agree on the records", "address", address)
+		return
+	}
+	slog.Warn("Records are missing from some backends", "address", address, "count", len(gaps))
+	for _, g := range gaps {
+		r := g.Record
+		slog.Warn("Record missing from backends", "message", r.Message, "type", r.Type, "from", r.From, "to", r.To,
+			"value", r.Value, "height", r.Height, "missing", strings.Join(g.Missing, ","))
+	}
+}
+
+// newHTTPClient builds the HTTP client shared by every backend, layering
+// replay, caching, recording and extra headers as configured.
+func newHTTPClient(opts fetchOptions) (*http.Client, error) {
+	hc := &http.Client{Timeout: opts.timeout}
+	if opts.fixtures != "" {
+		replay, err := vcr.NewReplayer(opts.fixtures)
+		if err != nil {
+			return nil, err
+		}
+		hc.Transport = replay
+	} else if opts.cacheDir != "" {
+		cache := httpcache.New(opts.cacheDir, nil)
+		cache.MaxAge = opts.cacheTTL
+		hc.Transport = cache
+	}
+	if opts.debugHTTP != "" {
+		rec, err := vcr.NewRecorder(opts.debugHTTP, hc.Transport)
+		if err != nil {
+			return nil, err
+		}
+		hc.Transport = rec
+	}
+	header := http.Header{"User-Agent": {opts.userAgent}}
+	for k, vs := range opts.headers {
+		header[k] = vs
+	}
+	hc.Transport = &headerTransport{header: header, next: hc.Transport}
+	if opts.wrapTransport != nil {
+		hc.Transport = opts.wrapTransport(hc.Transport)
+	}
+	return hc, nil
+}
+
+// newFilfoxClient builds a Filfox client for fetching wallet.
+func newFilfoxClient(hc *http.Client, wallet string, opts fetchOptions) *filfox.Client {
+	fopts := []filfox.Option{
+		filfox.WithHTTPClient(hc),
+		filfox.WithMaxRetries(opts.maxRetries),
+		filfox.WithConcurrency(opts.concurrency),
+		filfox.WithMaxPages(opts.maxPages),
+		filfox.WithStrictDecoding(opts.strict),
+		filfox.WithPageSize(opts.pageSize),
+		filfox.WithTypes(opts.types...),
+		filfox.WithHeightRange(opts.heights.From, opts.heights.To),
+		filfox.WithAPIKey(opts.apiKey),
+		filfox.WithUserAgent(opts.userAgent),
+	}
+	if opts.resume {
+		fopts = append(fopts, filfox.WithCheckpoint(checkpointPath(wallet), true))
+	}
+	if opts.limiter != nil {
+		fopts = append(fopts, filfox.WithRateLimiter(opts.limiter))
+	}
+	if opts.progress != nil {
+		fopts = append(fopts, filfox.WithProgress(opts.progress))
+	}
+	return filfox.NewClient(fopts...)
+}
+
+// newTransferSource builds the backend selected by name for fetching wallet.
+func newTransferSource(name, wallet string, opts fetchOptions) (source.TransferSource, error) {
+	hc, err := newHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "filfox":
+		return source.NewFilfox(newFilfoxClient(hc, wallet, opts)), nil
+	case "beryx":
+		token := opts.apiKey
+		if token == "" {
+			token = os.Getenv("BERYX_TOKEN")
+		}
+		return source.Bounded(source.NewBeryx(hc, "", token), opts.heights), nil
+	case "filscan":
+		return source.Bounded(source.NewFilscan(hc, ""), opts.heights), nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q (available: %s)", name, strings.Join(source.Names, ", "))
+	}
+}
+
+// headerTransport sets fixed headers on every outgoing request.
+type headerTransport struct {
+	header http.Header
+	next   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	req = req.Clone(req.Context())
+	for k, vs := range t.header {
+		req.Header[k] = vs
+	}
+	return next.RoundTrip(req)
+}
+
+// headerFlag collects repeated --header k=v flags.
+type headerFlag http.Header
+
+func (h headerFlag) String() string {
+	var pairs []string
+	for k, vs := range h {
+		for _, v := range vs {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h headerFlag) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("header %q must be in key=value form", s)
+	}
+	http.Header(h).Add(k, v)
+	return nil
+}
+
+// normalizeAddress converts a 0x address into the f410 (or masked f0) form
+// every backend expects, leaving Filecoin addresses as given once checked
+// to be well formed. Names of walletAliases are replaced by their address
+// first.
+func normalizeAddress(s string) (string, error) {
+	if addr, ok := walletAliases[s]; ok {
+		s = addr
+	}
+	if err := address.Validate(s); err != nil {
+		return "", err
+	}
+	if !address.IsEth(s) {
+		return s, nil
+	}
+	return address.FromEth(s, "f")
+}
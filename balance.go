@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/address"
+)
+
+// addressOverview is the balance command's output.
+type addressOverview struct {
+	Address    string    `json:"address"`
+	ID         string    `json:"id"`
+	Actor      string    `json:"actor"`
+	EthAddress string    `json:"eth_address,omitempty"` // 0x form of f410 addresses
+	Balance    string    `json:"balance"`               // attoFIL, as a string to survive JSON number precision
+	BalanceFIL string    `json:"balance_fil"`
+	Nonce      int       `json:"nonce"`
+	Created    time.Time `json:"created"`
+	CreatedAt  int       `json:"created_height"`
+}
+
+// runBalance prints the current state of an address from Filfox.
+func runBalance(ctx context.Context, w io.Writer, args []string, opts fetchOptions) error {
+	fs := flag.NewFlagSet("balance", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError{errors.New("usage: balance [--json] <address>")}
+	}
+	addr, err := normalizeAddress(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	hc, err := newHTTPClient(opts)
+	if err != nil {
+		return err
+	}
+	a, err := newFilfoxClient(hc, addr, opts).Address(ctx, addr)
+	if err != nil {
+		return err
+	}
+
+	balance, ok := new(big.Int).SetString(a.Balance, 10)
+	if !ok {
+		return fmt.Errorf("Failed to parse balance %s", a.Balance)
+	}
+	overview := addressOverview{
+		Address:    a.Address,
+		ID:         a.ID,
+		Actor:      a.Actor,
+		Balance:    balance.String(),
+		BalanceFIL: attoFILToFIL(balance).Text('f', -1),
+		Nonce:      a.Nonce,
+		Created:    time.Unix(int64(a.CreateTimestamp), 0).UTC(),
+		CreatedAt:  a.CreateHeight,
+	}
+	for _, robust := range []string{a.Address, a.Robust} {
+		if strings.HasPrefix(robust, "f410") {
+			overview.EthAddress, _ = address.ToEth(robust)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(overview)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Address:\t%s\n", overview.Address)
+	fmt.Fprintf(tw, "ID:\t%s\n", overview.ID)
+	fmt.Fprintf(tw, "Actor:\t%s\n", overview.Actor)
+	if overview.EthAddress != "" {
+		fmt.Fprintf(tw, "Eth address:\t%s\n", overview.EthAddress)
+	}
+	fmt.Fprintf(tw, "Balance:\t%s FIL\n", overview.BalanceFIL)
+	fmt.Fprintf(tw, "Nonce:\t%d\n", overview.Nonce)
+	fmt.Fprintf(tw, "Created:\t%s (height %d)\n", overview.Created.Format(time.RFC3339), overview.CreatedAt)
+	return tw.Flush()
+}
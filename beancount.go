@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerFormat("beancount", format{description: "Beancount ledger", ext: ".beancount", write: writeBeancount})
+}
+
+// writeBeancount writes xfers as a Beancount ledger: an open directive for
+// each account, then a balanced transaction per transfer and any balance
+// assertions.
+func writeBeancount(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	entries := journalEntries(xfers, opts)
+
+	// Accounts must be opened no later than their first use
+	opened := make(map[string]time.Time)
+	open := func(account string, t time.Time) {
+		if o, ok := opened[account]; !ok || t.Before(o) {
+			opened[account] = t
+		}
+	}
+	for _, e := range entries {
+		for _, p := range e.postings {
+			open(p.account, e.time)
+		}
+	}
+	for _, a := range opts.assertions {
+		open(a.account, a.time.AddDate(0, 0, -1))
+	}
+
+	const date = time.DateOnly
+	bw := bufio.NewWriter(w)
+	accounts := make([]string, 0, len(opened))
+	for account := range opened {
+		accounts = append(accounts, account)
+	}
+	slices.Sort(accounts)
+	for _, account := range accounts {
+		fmt.Fprintf(bw, "%s open %s\n", opts.localTime(opened[account]).Format(date), account)
+	}
+
+	for _, e := range entries {
+		flag := "*"
+		if e.pending {
+			flag = "!"
+		}
+		fmt.Fprintf(bw, "\n%s %s %s %s", opts.localTime(e.time).Format(date), flag, beancountString(e.payee), beancountString(e.narration))
+		for _, tag := range e.tags {
+			fmt.Fprintf(bw, " #%s", beancountTag(tag))
+		}
+		fmt.Fprintf(bw, "\n  message: %s\n", beancountString(e.message))
+		for _, p := range e.postings {
+			fmt.Fprintf(bw, "  %-40s %s %s\n", p.account, p.amount, p.commodity)
+		}
+	}
+
+	if len(opts.assertions) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, a := range opts.assertions {
+		fmt.Fprintf(bw, "%s balance %s %s FIL\n", a.time.Format(date), a.account, opts.amounts.format(a.amount, 18))
+	}
+	return bw.Flush()
+}
+
+// beancountString quotes s as a Beancount string literal.
+func beancountString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// beancountTag replaces the characters tags can't contain with dashes.
+func beancountTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("-_/.", r):
+			return r
+		}
+		return '-'
+	}, s)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+)
+
+func init() {
+	registerFormat("coinledger", format{description: "CoinLedger universal CSV", ext: ".csv", write: writeCoinLedgerCSV, csv: true, appendKey: "TxHash (Optional)"})
+}
+
+// writeCoinLedgerCSV writes xfers in CoinLedger's universal import layout.
+// Block rewards are typed Mining, other receipts Deposit and everything that
+// leaves the wallet, including fee-only messages, Withdrawal. CoinLedger reads
+// dates as UTC, so --timezone doesn't apply.
+func writeCoinLedgerCSV(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	writer := opts.csvWriter(w)
+	defer writer.Flush()
+
+	headers := []string{
+		"Date (UTC)",
+		"Platform (Optional)",
+		"Asset Sent",
+		"Amount Sent",
+		"Asset Received",
+		"Amount Received",
+		"Fee Currency (Optional)",
+		"Fee Amount (Optional)",
+		"Type",
+		"Description (Optional)",
+		"TxHash (Optional)",
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, m := range opts.movements(xfers) {
+		typ := "Withdrawal"
+		switch {
+		case m.Kind == KindReward:
+			typ = "Mining"
+		case m.received != "":
+			typ = "Deposit"
+		}
+
+		const coinLedgerDate = "01/02/2006 15:04:05"
+		record := []string{
+			opts.date(m.Timestamp.UTC(), coinLedgerDate),
+			"Filecoin",
+			m.sentCurrency,
+			m.sent,
+			m.receivedCurrency,
+			m.received,
+			m.feeCurrency,
+			m.fee,
+			typ,
+			m.description(),
+			m.MessageID,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
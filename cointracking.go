@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+func init() {
+	registerFormat("cointracking", format{description: "CoinTracking CSV import", ext: ".csv", write: writeCointrackingCSV, csv: true})
+}
+
+// writeCointrackingCSV writes xfers in Cointracking.info's CSV import layout.
+// Rewards are Mining income, penalties Lost and fee-only messages Other Fee;
+// everything else is a Deposit or Withdrawal. The Exchange column names the
+// wallet, by address when combined, and its miner sub-account, and rules'
+// categories become trade groups.
+func writeCointrackingCSV(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	writer := opts.csvWriter(w)
+	defer writer.Flush()
+
+	headers := []string{
+		"Type",
+		"Buy Amount",
+		"Buy Currency",
+		"Sell Amount",
+		"Sell Currency",
+		"Fee",
+		"Fee Currency",
+		"Exchange",
+		"Trade-Group",
+		"Comment",
+		"Date",
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, m := range opts.movements(xfers) {
+		var typ string
+		switch {
+		case m.feeOnly:
+			typ = "Other Fee"
+		case m.Kind == KindReward:
+			typ = "Mining"
+		case m.Kind == KindPenalty:
+			typ = "Lost"
+		case m.received != "":
+			typ = "Deposit"
+		default:
+			typ = "Withdrawal"
+		}
+
+		exchange := "Filecoin"
+		if opts.combined {
+			exchange += " " + m.Wallet
+		}
+		if m.Account != "" {
+			exchange += " (" + m.Account + ")"
+		}
+		comment := m.MessageID
+		if d := m.description(); d != "" {
+			comment = d + " (" + m.MessageID + ")"
+		}
+
+		record := []string{
+			typ,
+			m.received,
+			m.receivedCurrency,
+			m.sent,
+			m.sentCurrency,
+			m.fee,
+			m.feeCurrency,
+			exchange,
+			m.Category,
+			comment,
+			opts.date(opts.localTime(m.Timestamp), time.DateTime),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// command is a subcommand of filfoxy.
+type command struct {
+	name    string
+	args    string // usage of its arguments
+	summary string
+
+	// ownFlags commands parse the flags after their name themselves, rather
+	// than taking the global flags there too.
+	ownFlags bool
+}
+
+// commands are the subcommands, in the order usage lists them. export is the
+// default, so that filfoxy <wallet> keeps working.
+var commands = []command{
+	{name: "export", args: "<wallet>...", summary: "write the transfer history of wallets in --format, to a file each unless --combine'd"},
+	{name: "report", args: "<wallet>...", summary: "print a statement of totals and transfers, in Markdown unless --format or --output say otherwise"},
+	{name: "fetch", args: "<wallet>...", summary: "retrieve and print the transfers of wallets without exporting them, e.g. to fill --cache-dir"},
+	{name: "watch", args: "<wallet>...", summary: "print new transfers of wallets as they appear, checking every --interval"},
+	{name: "tui", args: "<wallet>...", summary: "browse, search, filter and tag the transfers of wallets in the terminal, then export those picked"},
+	{name: "serve", summary: "serve exports over HTTP on --listen, at /export/<wallet>?format=<format>"},
+	{name: "balance", args: "[--json] <address>", summary: "print the current balance and state of an address", ownFlags: true},
+	{name: "pending", args: "[--json] <address>", summary: "print the in-flight mempool messages of an address", ownFlags: true},
+	{name: "vesting", args: "[--json] <address>", summary: "print the vesting schedule of a multisig", ownFlags: true},
+	{name: "status", summary: "check the configured backends are up"},
+	{name: "formats", summary: "list the formats of --format"},
+	{name: "completion", args: "bash|zsh|fish", summary: "print a shell completion script, completing commands, flags, formats and config account names", ownFlags: true},
+	{name: "version", summary: "print the version, commit and build date of filfoxy"},
+}
+
+func findCommand(name string) (command, bool) {
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == name })
+	if i < 0 {
+		return command{}, false
+	}
+	return commands[i], true
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [flags] <command> [flags] [arguments]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s [flags] <wallet>...   (export)\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       (addresses may be given in f or 0x form)\n\nCommands:\n")
+	tw := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", c.name, c.args, c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nExit codes:\n")
+	tw = tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	for i, c := range exitCodes {
+		fmt.Fprintf(tw, "  %d\t%s\n", i+1, c.description)
+	}
+	tw.Flush()
+}
+
+// runFetch prints the transfers of wallets as they would be exported.
+func runFetch(ctx context.Context, w io.Writer, wallets []string, opts fetchOptions, eopts exportOptions) error {
+	xfers, _, err := collectTransfers(ctx, wallets, opts, &eopts)
+	if err != nil {
+		return err
+	}
+	if err := newTransferTable(w, eopts).write(xfers); err != nil {
+		return err
+	}
+	slog.Info("Retrieved transfers", "count", len(xfers))
+	return nil
+}
+
+// runWatch retrieves the transfers of wallets every interval until ctx ends,
+// printing those it hasn't seen before. Failures after the first retrieval
+// are logged and retried at the next interval.
+func runWatch(ctx context.Context, w io.Writer, wallets []string, opts fetchOptions, eopts exportOptions, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("--interval %s must be positive", interval)
+	}
+	seen := make(map[string]bool)
+	table := newTransferTable(w, eopts)
+	for round := 0; ; round++ {
+		e := eopts // fresh assertions and openings each round
+		xfers, _, err := collectTransfers(ctx, wallets, opts, &e)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case err != nil && round == 0:
+			return err
+		case err != nil:
+			slog.Warn("Checking for new transfers failed", "err", err)
+		}
+
+		var fresh []Transfer
+		for _, xfer := range slices.Backward(xfers) { // oldest first
+			key := xfer.watchKey()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			fresh = append(fresh, xfer)
+		}
+		if round == 0 {
+			slog.Info("Watching for new transfers", "count", len(fresh), "interval", interval)
+		} else if err := table.write(fresh); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// watchKey identifies x across retrievals, whether pending or not.
+func (x Transfer) watchKey() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s", x.Wallet, x.MessageID, x.Kind, x.From, x.To, x.Amount)
+}
+
+// runServe serves exports over HTTP on addr until ctx ends. GET
+// /export/<wallet> retrieves the wallet's transfers and responds with them in
+// the format of the format query parameter, or else --format, configured as
+// the flags say.
+func runServe(ctx context.Context, addr string, opts fetchOptions, eopts exportOptions) error {
+	// Requests for one wallet may overlap, and would contend for its checkpoint
+	opts.resume = false
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /export/{wallet}", func(w http.ResponseWriter, r *http.Request) {
+		wallet, err := normalizeAddress(r.PathValue("wallet"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		e := eopts
+		e.format = cmp.Or(r.URL.Query().Get("format"), e.format, "ledger")
+		e.compress = ""
+		if err := e.servable(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		slog.Info("Exporting", "wallet", wallet, "format", e.format, "remote", r.RemoteAddr)
+		xfers, _, err := collectTransfers(r.Context(), []string{wallet}, opts, &e)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if e.balance {
+			runningBalances(xfers)
+		}
+		var buf bytes.Buffer
+		if err := formats[e.format].write(&buf, xfers, e); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Named as the file exported with the default --output would be
+		e.output, e.outputTemplate = "", nil
+		name, _ := e.outputPath([]string{wallet}, xfers)
+		w.Header().Set("Content-Type", cmp.Or(mime.TypeByExtension(formats[e.format].ext), "application/octet-stream"))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(name)}))
+		w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("GET /formats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		runFormats(w)
+	})
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	slog.Info("Serving exports", "url", "http://"+addr+"/export/<wallet>")
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// servable checks the format of opts can be written to an HTTP response
+// with what the flags configured.
+func (opts exportOptions) servable() error {
+	f, ok := formats[opts.format]
+	switch {
+	case !ok:
+		return fmt.Errorf("unknown format %q, want one of %s", opts.format, strings.Join(formatNames(), ", "))
+	case f.writeFile != nil:
+		return fmt.Errorf("format %s manages its own file, so can't be served", opts.format)
+	case f.fiat && opts.prices == nil:
+		return fmt.Errorf("format %s is valued in fiat, so needs --countervalues", opts.format)
+	case opts.format == "custom" && opts.custom == nil:
+		return errors.New("format custom needs --template")
+	case !f.csv && (opts.delimiter != 0 || opts.amounts.decimalComma || opts.dateFormat != ""):
+		return fmt.Errorf("--delimiter, --decimal-comma and --date-format only apply to CSV formats, not %s", opts.format)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// runCompletion prints the completion script of a shell, or with accounts
+// the config account names, which the scripts complete wallets from so
+// they follow edits to the config file.
+func runCompletion(w io.Writer, args []string) error {
+	if len(args) != 1 {
+		return usageError{errors.New("usage: completion bash|zsh|fish")}
+	}
+	c := completions{
+		formats:  formatNames(),
+		backends: source.Names,
+	}
+	for _, cmd := range commands {
+		c.commands = append(c.commands, cmd.name)
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		c.flags = append(c.flags, f)
+	})
+
+	switch args[0] {
+	case "accounts":
+		for _, name := range slices.Sorted(maps.Keys(walletAliases)) {
+			fmt.Fprintln(w, name)
+		}
+		return nil
+	case "bash":
+		return c.bash(w)
+	case "zsh":
+		return c.zsh(w)
+	case "fish":
+		return c.fish(w)
+	}
+	return usageError{fmt.Errorf("no completion for shell %q, want bash, zsh or fish", args[0])}
+}
+
+// completions are the words the completion scripts offer.
+type completions struct {
+	commands []string
+	formats  []string
+	backends []string
+	flags    []*flag.Flag
+}
+
+// flagNames lists the flags as --name, those that take a value if values
+// is set, or else those that don't.
+func (c completions) flagNames(values bool) []string {
+	var names []string
+	for _, f := range c.flags {
+		if takesValue(f) == values {
+			names = append(names, "--"+f.Name)
+		}
+	}
+	return names
+}
+
+func takesValue(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !b.IsBoolFlag()
+}
+
+func (c completions) bash(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `# bash completion for filfoxy: source <(filfoxy completion bash)
+_filfoxy() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+	case $prev in
+	--format|-format)
+		COMPREPLY=($(compgen -W "%[1]s" -- "$cur"))
+		return ;;
+	--backend|-backend|--failover|-failover)
+		COMPREPLY=($(compgen -W "%[2]s" -- "$cur"))
+		return ;;
+	%[3]s)
+		COMPREPLY=($(compgen -f -- "$cur"))
+		return ;;
+	esac
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "%[4]s" -- "$cur"))
+		return
+	fi
+	COMPREPLY=($(compgen -W "%[5]s $(filfoxy completion accounts 2>/dev/null)" -- "$cur"))
+}
+complete -o default -F _filfoxy filfoxy
+`, strings.Join(c.formats, " "), strings.Join(c.backends, " "),
+		strings.Join(c.flagNames(true), "|"), strings.Join(append(c.flagNames(true), c.flagNames(false)...), " "),
+		strings.Join(c.commands, " "))
+	return err
+}
+
+func (c completions) zsh(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `#compdef filfoxy
+# zsh completion for filfoxy: source <(filfoxy completion zsh)
+_filfoxy() {
+	case ${words[CURRENT-1]} in
+	--format|-format)
+		compadd -- %[1]s
+		return ;;
+	--backend|-backend|--failover|-failover)
+		compadd -- %[2]s
+		return ;;
+	%[3]s)
+		_files
+		return ;;
+	esac
+	if [[ $PREFIX == -* ]]; then
+		compadd -- %[4]s
+		return
+	fi
+	compadd -- %[5]s ${(f)"$(filfoxy completion accounts 2>/dev/null)"}
+}
+compdef _filfoxy filfoxy
+`, strings.Join(c.formats, " "), strings.Join(c.backends, " "),
+		strings.Join(c.flagNames(true), "|"), strings.Join(append(c.flagNames(true), c.flagNames(false)...), " "),
+		strings.Join(c.commands, " "))
+	return err
+}
+
+func (c completions) fish(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# fish completion for filfoxy: filfoxy completion fish | source\n")
+	b.WriteString("complete -c filfoxy -f\n")
+	fmt.Fprintf(&b, "complete -c filfoxy -n __fish_use_subcommand -a %s\n", fishQuote(strings.Join(c.commands, " ")))
+	b.WriteString("complete -c filfoxy -a '(filfoxy completion accounts 2>/dev/null)'\n")
+	for _, f := range c.flags {
+		_, usage := flag.UnquoteUsage(f)
+		usage, _, _ = strings.Cut(usage, "\n")
+		fmt.Fprintf(&b, "complete -c filfoxy -l %s -d %s", f.Name, fishQuote(usage))
+		switch {
+		case f.Name == "format":
+			fmt.Fprintf(&b, " -x -a %s", fishQuote(strings.Join(c.formats, " ")))
+		case f.Name == "backend" || f.Name == "failover":
+			fmt.Fprintf(&b, " -x -a %s", fishQuote(strings.Join(c.backends, " ")))
+		case takesValue(f):
+			b.WriteString(" -r -F")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fishQuote single quotes s for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
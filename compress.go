@@ -0,0 +1,46 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressions are the accepted output compressions, by file name suffix.
+var compressions = map[string]string{
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+// compressionOf infers the compression of an --output path from its suffix,
+// if any.
+func compressionOf(path string) string {
+	for method, suffix := range compressions {
+		if strings.HasSuffix(path, suffix) {
+			return method
+		}
+	}
+	return ""
+}
+
+// compressWriter wraps w to compress what is written with method, one of
+// compressions or empty for none. Closing it flushes the compressor, but
+// leaves w open.
+func compressWriter(w io.Writer, method string) (io.WriteCloser, error) {
+	switch method {
+	case "":
+		return nopWriteCloser{w}, nil
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("unknown compression %q", method)
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
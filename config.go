@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// config is what the config file sets besides flags.
+type config struct {
+	wallets  []string          // exported when none are given
+	accounts map[string]string // addresses by the names they can be given as
+}
+
+// walletAliases are the accounts of the config file, resolved by
+// normalizeAddress wherever an address is accepted.
+var walletAliases map[string]string
+
+// defaultConfigPath is where the config file is looked for without --config:
+// filfoxy/config.toml in the user's config directory, such as ~/.config.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "filfoxy", "config.toml")
+}
+
+// configPath finds a --config on the command line, ahead of parsing it, as
+// the config file sets the defaults the other flags are parsed over.
+// explicit is false for the default path, which need not exist.
+func configPath(args []string) (path string, explicit bool) {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch {
+		case arg == "--":
+			return defaultConfigPath(), false
+		case !strings.HasPrefix(arg, "-") || name != "config":
+			continue
+		case hasValue:
+			return value, true
+		case i+1 < len(args):
+			return args[i+1], true
+		}
+	}
+	return defaultConfigPath(), false
+}
+
+// applyConfig sets flags from the TOML config file at path and then
+// from FILFOXY_ environment variables, before the command line overrides
+// both. Config keys and variables are named after flags, e.g.
+//
+//	backend = "beryx"
+//	format = "koinly"
+//	labels = "/home/me/filecoin/labels.csv"
+//	countervalues = "coingecko"
+//	header = ["X-Team=accounts"]
+//
+// and FILFOXY_FORMAT=koinly, with wallets = ["f1...", "f1..."] exported when
+// none are given, and an accounts table naming addresses:
+//
+//	[accounts]
+//	sp-payout = "f1..."
+func applyConfig(flags *flag.FlagSet, path string, explicit bool) (c config, err error) {
+	var config map[string]any
+	if path != "" {
+		if _, err := toml.DecodeFile(path, &config); errors.Is(err, fs.ErrNotExist) && !explicit {
+			config = nil
+		} else if err != nil {
+			return c, fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	for key, value := range config {
+		switch key {
+		case "wallets":
+			if c.wallets, err = configStrings(value); err != nil {
+				return c, fmt.Errorf("config %s: wallets: %w", path, err)
+			}
+			continue
+		case "accounts":
+			table, ok := value.(map[string]any)
+			if !ok {
+				return c, fmt.Errorf("config %s: accounts is not a table of names and addresses", path)
+			}
+			c.accounts = make(map[string]string, len(table))
+			for name, addr := range table {
+				if c.accounts[name], ok = addr.(string); !ok {
+					return c, fmt.Errorf("config %s: accounts: %s is not an address", path, name)
+				}
+			}
+			continue
+		}
+		if flags.Lookup(key) == nil || key == "config" {
+			return c, fmt.Errorf("config %s: unknown setting %q", path, key)
+		}
+		values, err := configStrings(value)
+		if err != nil {
+			return c, fmt.Errorf("config %s: %s: %w", path, key, err)
+		}
+		if key != "header" {
+			// Lists of everything but repeatable flags are comma separated
+			values = []string{strings.Join(values, ",")}
+		}
+		for _, v := range values {
+			if err := flags.Set(key, v); err != nil {
+				return c, fmt.Errorf("config %s: %s: %w", path, key, err)
+			}
+		}
+	}
+
+	flags.VisitAll(func(f *flag.Flag) {
+		env := "FILFOXY_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		v, ok := os.LookupEnv(env)
+		if ok && err == nil && f.Name != "config" {
+			if serr := flags.Set(f.Name, v); serr != nil {
+				err = fmt.Errorf("$%s: %w", env, serr)
+			}
+		}
+	})
+	return c, err
+}
+
+// configStrings turns a TOML value, or list of them, into flag values.
+func configStrings(value any) ([]string, error) {
+	list, ok := value.([]any)
+	if !ok {
+		list = []any{value}
+	}
+	values := make([]string, len(list))
+	for i, v := range list {
+		switch v := v.(type) {
+		case string, bool, int64, float64:
+			values[i] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("unsupported value %v", v)
+		}
+	}
+	return values, nil
+}
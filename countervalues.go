@@ -0,0 +1,160 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/price"
+)
+
+// newPriceSource builds the --countervalues source: coingecko, or a CSV file
+// of date,price rows.
+func newPriceSource(name string, opts fetchOptions) (price.Source, error) {
+	if name != "coingecko" {
+		return price.OpenFile(name)
+	}
+	hc, err := newHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return price.NewCoinGecko(hc, "", os.Getenv("COINGECKO_API_KEY")), nil
+}
+
+// countervalues are the FIL prices an export values its transfers at.
+type countervalues struct {
+	currency string
+	history  price.History // covering the exported transfers
+	spot     *big.Rat      // at the time of export
+}
+
+// lookupCountervalues retrieves the prices of FIL in currency from src over
+// the period of xfers, and now. It fails if the prices don't cover that
+// period, so that no transfer is valued at a price from far from its day.
+func lookupCountervalues(ctx context.Context, src price.Source, currency string, xfers []Transfer) (*countervalues, error) {
+	cv := &countervalues{currency: currency}
+	var err error
+	if len(xfers) > 0 {
+		from, to := xfers[0].Timestamp, xfers[0].Timestamp
+		for _, x := range xfers {
+			if x.Timestamp.Before(from) {
+				from = x.Timestamp
+			}
+			if x.Timestamp.After(to) {
+				to = x.Timestamp
+			}
+		}
+		slog.Info("Retrieving prices of FIL", "currency", currency, "source", src.Name())
+		if cv.history, err = src.History(ctx, currency, from, to); err != nil {
+			return nil, err
+		}
+		if err := cv.history.Covers(from, to); err != nil {
+			return nil, fmt.Errorf("%s prices in %s: %w", src.Name(), currency, err)
+		}
+	}
+	if cv.spot, err = src.Spot(ctx, currency); err != nil {
+		return nil, err
+	}
+	return cv, nil
+}
+
+// at values attoFIL at the price of t.
+func (cv *countervalues) at(attoFIL *big.Int, t time.Time) *big.Rat {
+	return cv.value(attoFIL, cv.history.At(t))
+}
+
+// now values attoFIL at the spot price.
+func (cv *countervalues) now(attoFIL *big.Int) *big.Rat {
+	return cv.value(attoFIL, cv.spot)
+}
+
+func (cv *countervalues) value(attoFIL *big.Int, price *big.Rat) *big.Rat {
+	if price == nil {
+		return nil
+	}
+	fil := new(big.Rat).SetFrac(attoFIL, new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+	return fil.Mul(fil, price)
+}
+
+// fiat formats a countervalue to the cent, or empty if there is none.
+func (opts exportOptions) fiat(v *big.Rat) string {
+	if v == nil {
+		return ""
+	}
+	s := strings.TrimPrefix(v.FloatString(2), "-")
+	if v.Sign() < 0 && strings.Trim(s, "0.") != "" {
+		s = "-" + s
+	}
+	if opts.amounts.decimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+// validateCountervalueCurrency checks --countervalue-currency is a currency
+// code, such as USD or EUR.
+func validateCountervalueCurrency(currency string) error {
+	if len(currency) < 3 || strings.ContainsFunc(currency, func(r rune) bool { return r < 'A' || r > 'Z' }) {
+		return fmt.Errorf("--countervalue-currency %q is not a currency code, such as USD", currency)
+	}
+	return nil
+}
+
+// fiatLine is a line of a bank statement of FIL kept in fiat.
+type fiatLine struct {
+	time        time.Time
+	value       *big.Rat // signed change to the balance
+	payee       string
+	description string
+	reference   string
+}
+
+// fiatLines turns xfers into bank statement lines: the change each made to
+// the FIL balance valued at the price of its day, with fees on a line of
+// their own so they can be coded to bank charges. Token and NFT transfers
+// don't move FIL and are left out, as the log reports.
+func fiatLines(xfers []Transfer, opts exportOptions) []fiatLine {
+	var lines []fiatLine
+	var skipped int
+	for _, xfer := range xfers {
+		from, to := balanceDelta(xfer)
+		total := new(big.Int).Add(from, to)
+		if total.Sign() == 0 {
+			skipped++
+			continue
+		}
+
+		description := cmp.Or(xfer.description(), string(xfer.Kind))
+		if xfer.Pending {
+			description += " (pending)"
+		}
+		fee := xfer.fee()
+		if value := new(big.Int).Add(total, fee); value.Sign() != 0 {
+			lines = append(lines, fiatLine{
+				time:        xfer.Timestamp,
+				value:       opts.countervalues.at(value, xfer.Timestamp),
+				payee:       cmp.Or(xfer.Label, xfer.counterparty()),
+				description: description,
+				reference:   xfer.MessageID,
+			})
+		}
+		if fee.Sign() != 0 {
+			lines = append(lines, fiatLine{
+				time:        xfer.Timestamp,
+				value:       opts.countervalues.at(new(big.Int).Neg(fee), xfer.Timestamp),
+				payee:       "Filecoin network",
+				description: "Gas fee",
+				reference:   xfer.MessageID,
+			})
+		}
+	}
+	if skipped > 0 {
+		slog.Info("Omitted transfers that don't change the FIL balance", "count", skipped)
+	}
+	return lines
+}
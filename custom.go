@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	registerFormat("custom", format{description: "CSV columns or any text laid out by a --template file", ext: ".csv", write: writeCustom, csv: true})
+}
+
+// A customFormat is a layout defined in a --template file, for accounting
+// systems without a built in format. A .yaml or .yml file lists CSV columns,
+// each a header and a text/template of its value for a customRow, e.g.
+//
+//	columns:
+//	  - header: Date
+//	    value: '{{.Date "02/01/2006"}}'
+//	  - header: Type
+//	    value: '{{if eq .Flow "Out"}}Withdrawal{{else}}Deposit{{end}}'
+//	  - header: Amount
+//	    value: '{{.Quantity}} {{.Ticker}}'
+//	  - header: Fee
+//	    value: '{{.Fee}}'
+//
+// Any other file is a text/template of the whole export, ranging over the
+// []customRow itself, with csv to quote a field and join for tags.
+type customFormat struct {
+	headers []string
+	columns []*template.Template
+	whole   *template.Template
+}
+
+var customFuncs = template.FuncMap{
+	"csv":  csvField,
+	"join": strings.Join,
+}
+
+// loadCustomFormat reads and parses the templates of a custom format.
+func loadCustomFormat(path string) (*customFormat, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	default:
+		whole, err := template.New(filepath.Base(path)).Funcs(customFuncs).Parse(string(b))
+		if err != nil {
+			return nil, err
+		}
+		return &customFormat{whole: whole}, nil
+	}
+
+	var raw struct {
+		Columns []struct {
+			Header string `yaml:"header"`
+			Value  string `yaml:"value"`
+		} `yaml:"columns"`
+	}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(raw.Columns) == 0 {
+		return nil, fmt.Errorf("%s: no columns", path)
+	}
+	var cf customFormat
+	for i, c := range raw.Columns {
+		if c.Header == "" {
+			c.Header = fmt.Sprintf("#%d", i+1)
+		}
+		t, err := template.New(c.Header).Funcs(customFuncs).Parse(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: column %s: %w", path, c.Header, err)
+		}
+		cf.headers = append(cf.headers, c.Header)
+		cf.columns = append(cf.columns, t)
+	}
+	return &cf, nil
+}
+
+// customRow is what custom templates see of a transfer: its fields, and
+// methods formatting them as the export is configured.
+type customRow struct {
+	Transfer
+	opts exportOptions
+}
+
+// Date is the transfer's time in the export's time zone, in layout, such as
+// 2006-01-02 15:04:05, or if empty the --date-format, RFC 3339 by default.
+func (r customRow) Date(layout string) string {
+	if layout == "" {
+		return r.opts.date(r.opts.localTime(r.Timestamp), time.RFC3339)
+	}
+	return r.opts.localTime(r.Timestamp).Format(layout)
+}
+
+// Quantity is the amount moved, unsigned, counting an NFT as one.
+func (r customRow) Quantity() string { return r.opts.quantity(r.Transfer) }
+
+// Signed is the amount moved, negative if out of the wallet.
+func (r customRow) Signed() string {
+	amount, decimals := r.moved()
+	return r.opts.amounts.format(amount, decimals)
+}
+
+// Fee is the total fee paid in FIL, or empty if none.
+func (r customRow) Fee() string {
+	fee := r.fee()
+	if fee.Sign() == 0 {
+		return ""
+	}
+	return r.opts.amounts.format(fee, 18)
+}
+
+// FIL formats an attoFIL field, such as .MinerFee or .Balance.
+func (r customRow) FIL(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return r.opts.amounts.format(v, 18)
+}
+
+// Flow is In, Out or Internal.
+func (r customRow) Flow() string { return r.flow().String() }
+
+func (r customRow) Counterparty() string { return r.counterparty() }
+
+func (r customRow) Description() string { return r.description() }
+
+// writeCustom writes xfers in the format of opts.custom.
+func writeCustom(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	cf := opts.custom
+	rows := make([]customRow, len(xfers))
+	for i, x := range xfers {
+		rows[i] = customRow{x, opts}
+	}
+	if cf.whole != nil {
+		return cf.whole.Execute(w, rows)
+	}
+
+	writer := opts.csvWriter(w)
+	defer writer.Flush()
+	if err := writer.Write(cf.headers); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, row := range rows {
+		record := make([]string, len(cf.columns))
+		for i, t := range cf.columns {
+			buf.Reset()
+			if err := t.Execute(&buf, row); err != nil {
+				return fmt.Errorf("--template column %s: %w", cf.headers[i], err)
+			}
+			record[i] = buf.String()
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// csvField quotes s as a CSV field if it needs to be.
+func csvField(s string) string {
+	if !strings.ContainsAny(s, ",\"\r\n") && strings.TrimSpace(s) == s {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
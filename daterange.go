@@ -0,0 +1,179 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// parseDateRange parses the --from and --to flags into a half-open interval
+// [from, to), in UTC. Bare dates are days in loc, and a bare date for --to
+// includes the whole of that day. Either may be empty, leaving that side
+// unbounded.
+func parseDateRange(fromFlag, toFlag string, loc *time.Location) (from, to time.Time, err error) {
+	if fromFlag != "" {
+		if from, _, err = parseDate(fromFlag, loc); err != nil {
+			return from, to, fmt.Errorf("--from: %w", err)
+		}
+	}
+	if toFlag != "" {
+		var wholeDay bool
+		if to, wholeDay, err = parseDate(toFlag, loc); err != nil {
+			return from, to, fmt.Errorf("--to: %w", err)
+		}
+		if wholeDay {
+			to = to.AddDate(0, 0, 1)
+		} else {
+			to = to.Add(time.Second) // RFC 3339 bounds are inclusive too
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, fmt.Errorf("--from %s is not before --to %s", fromFlag, toFlag)
+	}
+	return from.UTC(), to.UTC(), nil
+}
+
+// parseDate accepts a YYYY-MM-DD date, reported as wholeDay, or an RFC 3339
+// timestamp. Dates are taken as midnight in loc.
+func parseDate(s string, loc *time.Location) (t time.Time, wholeDay bool, err error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, loc); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, false, fmt.Errorf("%q is neither YYYY-MM-DD nor RFC 3339", s)
+	}
+	return t, false, nil
+}
+
+// dateFormats are the named --date-format values, with their layouts. The
+// epoch formats have none, counting seconds or milliseconds instead.
+var dateFormats = map[string]string{
+	"rfc3339":    time.RFC3339,
+	"iso8601":    "2006-01-02T15:04:05.000Z07:00",
+	"datetime":   time.DateTime,
+	"date":       time.DateOnly,
+	"dd/mm/yyyy": "02/01/2006",
+	"mm/dd/yyyy": "01/02/2006",
+	"dd.mm.yyyy": "02.01.2006",
+	"epoch":      "",
+	"epoch-ms":   "",
+}
+
+// parseDateFormat checks a --date-format is one of dateFormats or a Go time
+// layout, such as 02/01/2006 15:04.
+func parseDateFormat(s string) (string, error) {
+	if _, ok := dateFormats[strings.ToLower(s)]; ok {
+		return strings.ToLower(s), nil
+	}
+	if time.Unix(0, 0).UTC().Format(s) == s {
+		return "", fmt.Errorf("--date-format %q is neither a Go time layout nor one of %s", s, strings.Join(dateFormatNames(), ", "))
+	}
+	return s, nil
+}
+
+// dateFormatNames lists the keys of dateFormats, sorted.
+func dateFormatNames() []string {
+	names := make([]string, 0, len(dateFormats))
+	for name := range dateFormats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// date formats t, already in the zone the format writes, in the
+// --date-format if set and otherwise in layout, the format's own.
+func (opts exportOptions) date(t time.Time, layout string) string {
+	switch opts.dateFormat {
+	case "":
+	case "epoch":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "epoch-ms":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	default:
+		layout = cmp.Or(dateFormats[opts.dateFormat], opts.dateFormat)
+	}
+	return t.Format(layout)
+}
+
+// intersectHeights returns the epochs within both a and b, treating zero
+// bounds as open.
+func intersectHeights(a, b source.HeightRange) source.HeightRange {
+	r := a
+	if b.From != 0 && b.From > r.From {
+		r.From = b.From
+	}
+	if b.To != 0 && (r.To == 0 || b.To < r.To) {
+		r.To = b.To
+	}
+	return r
+}
+
+// exportMetadata describes the bounds of an export, so it can be matched to
+// the period or audit it was produced for.
+type exportMetadata struct {
+	Wallet     string     `json:"wallet"`
+	Wallets    []string   `json:"wallets,omitempty"` // all of them, when combined
+	Backend    string     `json:"backend"`
+	FromHeight int        `json:"from_height,omitempty"`
+	ToHeight   int        `json:"to_height,omitempty"`
+	FromDate   *time.Time `json:"from_date,omitempty"`
+	ToDate     *time.Time `json:"to_date,omitempty"` // exclusive
+	Transfers  int        `json:"transfers"`
+	Generated  time.Time  `json:"generated"`
+	Version    string     `json:"version"`
+}
+
+// writeExportMetadata records the range an export covered alongside it,
+// replacing an earlier record only where the export itself may be replaced
+// or added to.
+func writeExportMetadata(path string, wallets []string, backend string, eopts exportOptions, count int) error {
+	meta := exportMetadata{
+		Wallet:     wallets[0],
+		Backend:    backend,
+		FromHeight: eopts.heights.From,
+		ToHeight:   eopts.heights.To,
+		Transfers:  count,
+		Generated:  time.Now().UTC().Truncate(time.Second),
+		Version:    readBuildInfo().version,
+	}
+	if len(wallets) > 1 {
+		meta.Wallets = wallets
+	}
+	if !eopts.from.IsZero() {
+		meta.FromDate = &eopts.from
+	}
+	if !eopts.to.IsZero() {
+		meta.ToDate = &eopts.to
+	}
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if eopts.overwrite || eopts.appending {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return existsError(path)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(b, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteExportMetadataExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.csv.meta.json")
+	if err := os.WriteFile(path, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	eopts := exportOptions{from: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	if err := writeExportMetadata(path, []string{testWallet}, "filfox", eopts, 1); err == nil {
+		t.Error("replaced an existing record without --force")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "{}\n" {
+		t.Errorf("existing record changed to %q", b)
+	}
+
+	eopts.overwrite = true
+	if err := writeExportMetadata(path, []string{testWallet}, "filfox", eopts, 1); err != nil {
+		t.Errorf("with --force: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// enrichMessageDetails looks up each transfer's message to fill in its gas
+// and exit code fields, using up to workers lookups in parallel. A message
+// is looked up once for all the transfers it made.
+func enrichMessageDetails(ctx context.Context, ms source.MessageSource, xfers []Transfer, workers int) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var ids []string
+	legs := make(map[string][]int) // indices of xfers, by message
+	for i, x := range xfers {
+		if _, seen := legs[x.MessageID]; !seen {
+			ids = append(ids, x.MessageID)
+		}
+		legs[x.MessageID] = append(legs[x.MessageID], i)
+	}
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				details, err := ms.MessageDetails(ctx, id)
+				for _, i := range legs[id] {
+					if err == nil {
+						err = xfers[i].applyDetails(details)
+					}
+				}
+				if err != nil {
+					cancel(err)
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, id := range ids {
+		select {
+		case work <- id:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	return context.Cause(ctx)
+}
+
+// applyMethods sets the Method of each transfer from the matching message.
+// Transfers caused by messages the wallet neither sent nor received, such as
+// internal sends from a multisig, are left blank.
+func applyMethods(xfers []Transfer, msgs []source.Message) {
+	methods := make(map[string]string, len(msgs))
+	for _, m := range msgs {
+		methods[m.Cid] = m.Method
+	}
+	for i := range xfers {
+		xfers[i].Method = methods[xfers[i].MessageID]
+	}
+}
+
+func (t *Transfer) applyDetails(d source.MessageDetails) error {
+	var err error
+	parse := func(s string) *big.Int {
+		if s == "" || err != nil {
+			return nil
+		}
+		v, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			err = fmt.Errorf("Failed to parse amount %s in message %s", s, t.MessageID)
+		}
+		return v
+	}
+
+	t.GasLimit = d.GasLimit
+	t.GasFeeCap = parse(d.GasFeeCap)
+	t.GasPremium = parse(d.GasPremium)
+	t.BaseFeeBurn = parse(d.BaseFeeBurn)
+	t.ExitCode = &d.ExitCode
+	t.OverEstimationBurn = parse(d.OverEstimationBurn)
+	t.MinerTip = parse(d.MinerTip)
+	return err
+}
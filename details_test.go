@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// countingMessages serves the same details for every message, counting the
+// lookups of each.
+type countingMessages struct {
+	mu      sync.Mutex
+	lookups map[string]int
+}
+
+func (c *countingMessages) MessageDetails(ctx context.Context, cid string) (source.MessageDetails, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lookups[cid]++
+	return source.MessageDetails{GasLimit: 1000, GasFeeCap: "100", ExitCode: 0}, nil
+}
+
+func TestEnrichMessageDetailsOncePerMessage(t *testing.T) {
+	leg := func(msg string) Transfer {
+		return Transfer{Wallet: testWallet, Kind: KindTransfer, MessageID: msg, From: testWallet, To: testOther, Amount: big.NewInt(-1)}
+	}
+	xfers := []Transfer{leg("bafybatch"), leg("bafybatch"), leg("bafybatch"), leg("bafyother")}
+	ms := &countingMessages{lookups: make(map[string]int)}
+
+	if err := enrichMessageDetails(context.Background(), ms, xfers, 4); err != nil {
+		t.Fatal(err)
+	}
+	for cid, n := range ms.lookups {
+		if n != 1 {
+			t.Errorf("looked up %s %d times, want once", cid, n)
+		}
+	}
+	if len(ms.lookups) != 2 {
+		t.Errorf("looked up %d messages, want 2", len(ms.lookups))
+	}
+	for i, x := range xfers {
+		if x.GasLimit != 1000 || x.GasFeeCap == nil || x.GasFeeCap.Int64() != 100 || x.ExitCode == nil {
+			t.Errorf("transfer %d lacks its message's details: %+v", i, x)
+		}
+	}
+	if xfers[0].GasFeeCap == xfers[1].GasFeeCap {
+		t.Error("legs of a message share their amounts")
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import "math/big"
+
+// outgoing reports whether x took value out of wallet, judged by which side of
+// it wallet is on. Only when that's ambiguous, such as a self-transfer or an
+// ID address standing in for wallet, does it fall back on Amount's sign.
+func (x Transfer) outgoing(wallet string) bool {
+	switch {
+	case x.From == wallet && x.To != wallet:
+		return true
+	case x.To == wallet && x.From != wallet:
+		return false
+	}
+	return x.Amount.Sign() < 0
+}
+
+// counterparty is the address on the other side of x from its wallet.
+func (x Transfer) counterparty() string {
+	if x.outgoing(x.Wallet) {
+		return x.To
+	}
+	return x.From
+}
+
+// normalizeSigns makes the amounts of wallet's transfers negative when they
+// leave it and positive when they arrive, and its fees always negative,
+// whatever convention the backend used.
+func normalizeSigns(xfers []Transfer, wallet string) {
+	neg := func(v *big.Int) {
+		if v != nil {
+			v.Neg(v.Abs(v))
+		}
+	}
+	for i := range xfers {
+		x := &xfers[i]
+		switch x.Kind {
+		case KindTransfer, KindInternal, KindOther:
+		default:
+			continue
+		}
+		if x.outgoing(wallet) {
+			neg(x.Amount)
+		} else {
+			x.Amount.Abs(x.Amount)
+		}
+		neg(x.MinerFee)
+		neg(x.BurnFee)
+	}
+}
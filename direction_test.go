@@ -0,0 +1,55 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestNormalizeSigns(t *testing.T) {
+	const counterparty = testOther
+	tests := []struct {
+		name         string
+		from, to     string
+		amount, fee  int64
+		wantAmount   int64
+		wantFee      int64
+		wantOutgoing bool
+	}{
+		{name: "signed send", from: testWallet, to: counterparty, amount: -10, fee: -1, wantAmount: -10, wantFee: -1, wantOutgoing: true},
+		{name: "unsigned send", from: testWallet, to: counterparty, amount: 10, fee: 1, wantAmount: -10, wantFee: -1, wantOutgoing: true},
+		{name: "receive", from: counterparty, to: testWallet, amount: 10, wantAmount: 10},
+		{name: "receive reported negative", from: counterparty, to: testWallet, amount: -10, wantAmount: 10},
+		// A self-send is on both sides of the wallet, so only the backend's
+		// sign says which leg it is; its fee leaves the wallet either way.
+		{name: "signed self-send", from: testWallet, to: testWallet, amount: -10, fee: -1, wantAmount: -10, wantFee: -1, wantOutgoing: true},
+		{name: "unsigned self-send", from: testWallet, to: testWallet, amount: 10, fee: 1, wantAmount: 10, wantFee: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := Transfer{Wallet: testWallet, Kind: KindTransfer, From: tt.from, To: tt.to, Amount: big.NewInt(tt.amount)}
+			if tt.fee != 0 {
+				x.MinerFee = big.NewInt(tt.fee)
+			}
+			xfers := []Transfer{x}
+			normalizeSigns(xfers, testWallet)
+			got := xfers[0]
+			if got.Amount.Int64() != tt.wantAmount {
+				t.Errorf("amount = %v, want %d", got.Amount, tt.wantAmount)
+			}
+			if tt.fee != 0 && got.MinerFee.Int64() != tt.wantFee {
+				t.Errorf("fee = %v, want %d", got.MinerFee, tt.wantFee)
+			}
+			if o := got.outgoing(testWallet); o != tt.wantOutgoing {
+				t.Errorf("outgoing = %v, want %v", o, tt.wantOutgoing)
+			}
+		})
+	}
+}
+
+func TestNormalizeSignsSkipsOtherKinds(t *testing.T) {
+	xfers := []Transfer{{Wallet: testWallet, Kind: KindPenalty, From: testWallet, To: testOther, Amount: big.NewInt(-3)}}
+	normalizeSigns(xfers, testWallet)
+	if xfers[0].Amount.Int64() != -3 {
+		t.Errorf("penalty amount = %v, want -3", xfers[0].Amount)
+	}
+}
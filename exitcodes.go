@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/mroth/filfoxy/pkg/address"
+	"github.com/mroth/filfoxy/pkg/filfox"
+)
+
+// Exit codes, so that scripts and cron jobs can tell failures apart.
+const (
+	exitFailure = iota + 1
+	exitUsage
+	exitNotFound
+	exitRateLimited
+	exitPartial
+	exitMismatch
+)
+
+// exitCodes describe the exit codes, by code less one, for usage and
+// --error-json.
+var exitCodes = []struct{ kind, description string }{
+	{"failure", "any failure not listed below"},
+	{"usage", "bad flags or arguments, including malformed addresses"},
+	{"not_found", "the backend has never seen an address"},
+	{"rate_limited", "the backend kept refusing requests as too many"},
+	{"partial", "some wallets were exported, but others failed"},
+	{"mismatch", "exported, but balances don't reconcile with the chain"},
+}
+
+// errBalanceMismatch is returned once an export is written if its balances
+// don't reconcile with the on-chain ones.
+var errBalanceMismatch = errors.New("computed balances don't reconcile with the on-chain balances")
+
+// usageError is a mistake in the flags or arguments given.
+type usageError struct{ error }
+
+func (e usageError) Unwrap() error { return e.error }
+
+// partialError is the failure of some of the wallets exported one by one.
+type partialError struct {
+	exported int
+	failed   []error // of each wallet that failed
+}
+
+func (e *partialError) Error() string {
+	msgs := make([]string, len(e.failed))
+	for i, err := range e.failed {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("exported %d of %d wallets: %s", e.exported, e.exported+len(e.failed), strings.Join(msgs, "; "))
+}
+
+func (e *partialError) Unwrap() []error { return e.failed }
+
+// exitCode is the exit code for err.
+func exitCode(err error) int {
+	var partial *partialError
+	var usage usageError
+	switch {
+	case errors.As(err, &partial):
+		return exitPartial
+	case errors.As(err, &usage), errors.Is(err, address.ErrInvalid):
+		return exitUsage
+	case errors.Is(err, filfox.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, filfox.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, errBalanceMismatch):
+		return exitMismatch
+	}
+	return exitFailure
+}
+
+// errorJSON reports failures as JSON on stderr, set by --error-json.
+var errorJSON bool
+
+// fatal reports err, as a log line or with --error-json as JSON, and exits
+// with its exit code.
+func fatal(err error) {
+	code := exitCode(err)
+	if errorJSON {
+		writeErrorJSON(os.Stderr, err, code)
+	} else {
+		slog.Error(err.Error())
+	}
+	os.Exit(code)
+}
+
+// writeErrorJSON writes err to w as a JSON object of its message, exit code
+// and the kind of failure that is.
+func writeErrorJSON(w io.Writer, err error, code int) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(struct {
+		Error string `json:"error"`
+		Code  int    `json:"code"`
+		Kind  string `json:"kind"`
+	}{err.Error(), code, exitCodes[code-1].kind})
+}
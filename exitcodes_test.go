@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mroth/filfoxy/pkg/address"
+	"github.com/mroth/filfoxy/pkg/filfox"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), exitFailure},
+		{usageError{errors.New("usage: balance [--json] <address>")}, exitUsage},
+		{fmt.Errorf("label: %w", address.ErrInvalid), exitUsage},
+		{fmt.Errorf("Wallet f01 %w on filfox", filfox.ErrNotFound), exitNotFound},
+		{fmt.Errorf("page 3: %w", filfox.ErrRateLimited), exitRateLimited},
+		{&partialError{exported: 1, failed: []error{filfox.ErrNotFound}}, exitPartial},
+		{fmt.Errorf("%w: 1 of 1 wallets", errBalanceMismatch), exitMismatch},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+)
+
+// parseFIL parses a decimal FIL amount into attoFIL.
+func parseFIL(s string) (*big.Int, error) {
+	f, ok := new(big.Float).SetPrec(128).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a FIL amount", s)
+	}
+	atto, _ := f.Mul(f, new(big.Float).SetInt(attoFIL)).Int(nil)
+	return atto, nil
+}
+
+// dropDust removes incoming FIL transfers smaller than minAmount attoFIL.
+// Outgoing transfers are always kept, since their fees were really paid.
+func dropDust(xfers []Transfer, minAmount *big.Int) []Transfer {
+	before := len(xfers)
+	xfers = slices.DeleteFunc(xfers, func(x Transfer) bool {
+		switch x.Kind {
+		case KindTransfer, KindInternal, KindOther:
+		default:
+			return false
+		}
+		return x.Amount.Sign() > 0 && x.Amount.Cmp(minAmount) < 0
+	})
+	if n := before - len(xfers); n > 0 {
+		slog.Info("Omitted dust transfers", "count", n)
+	}
+	return xfers
+}
+
+// spamFilter recognises unsolicited incoming transfers: zero-value token
+// airdrops, and anything from a known spam sender.
+type spamFilter struct {
+	senders map[string]bool
+}
+
+// newSpamFilter loads known spam senders, one address per line, from path if
+// it's set. Blank lines and lines starting with # are ignored.
+func newSpamFilter(path string) (*spamFilter, error) {
+	sf := &spamFilter{senders: make(map[string]bool)}
+	if path == "" {
+		return sf, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addr, err := normalizeAddress(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		sf.senders[addr] = true
+	}
+	return sf, scanner.Err()
+}
+
+func (sf *spamFilter) isSpam(x Transfer) bool {
+	if x.outgoing(x.Wallet) || x.FeeOnly {
+		return false
+	}
+	if sf.senders[x.From] {
+		return true
+	}
+	return x.Kind == KindToken && x.Amount.Sign() == 0
+}
+
+// drop removes the spam among xfers.
+func (sf *spamFilter) drop(xfers []Transfer) []Transfer {
+	before := len(xfers)
+	xfers = slices.DeleteFunc(xfers, sf.isSpam)
+	if n := before - len(xfers); n > 0 {
+		slog.Info("Omitted spam transfers", "count", n)
+	}
+	return xfers
+}
+
+// transferTypes are the values accepted by --types: the backend record types
+// a Transfer is made of, plus the kinds that have no such record.
+var transferTypes = []string{"send", "receive", "miner-fee", "burn-fee", "fee-only",
+	string(KindReward), string(KindPenalty), string(KindPledge), string(KindToken),
+	string(KindNFT), string(KindInternal), string(KindOther)}
+
+// types returns the --types that x matches.
+func (x Transfer) types() []string {
+	switch x.Kind {
+	case KindTransfer:
+	case KindOther:
+		return []string{string(KindOther), x.RecordType}
+	default:
+		return []string{string(x.Kind)}
+	}
+	var types []string
+	switch {
+	case x.FeeOnly:
+		types = append(types, "fee-only")
+	case x.Amount.Sign() < 0:
+		types = append(types, "send")
+	default:
+		types = append(types, "receive")
+	}
+	if x.MinerFee != nil {
+		types = append(types, "miner-fee")
+	}
+	if x.BurnFee != nil {
+		types = append(types, "burn-fee")
+	}
+	return types
+}
+
+// typeFilter keeps the transfers in a direction and of any of a set of types.
+type typeFilter struct {
+	direction string // "in", "out", or "" for both
+	types     []string
+}
+
+func newTypeFilter(direction, types string) (*typeFilter, error) {
+	tf := &typeFilter{direction: direction}
+	switch direction {
+	case "", "in", "out":
+	default:
+		return nil, fmt.Errorf("--direction must be in or out, not %q", direction)
+	}
+	for _, t := range strings.Split(types, ",") {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		if !slices.Contains(transferTypes, t) {
+			return nil, fmt.Errorf("unknown --types %q, expected some of %s", t, strings.Join(transferTypes, ","))
+		}
+		tf.types = append(tf.types, t)
+	}
+	return tf, nil
+}
+
+func (tf *typeFilter) keep(x Transfer) bool {
+	if tf.direction != "" && x.outgoing(x.Wallet) != (tf.direction == "out") {
+		return false
+	}
+	if len(tf.types) == 0 {
+		return true
+	}
+	for _, t := range x.types() {
+		if slices.Contains(tf.types, t) {
+			return true
+		}
+	}
+	return false
+}
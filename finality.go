@@ -0,0 +1,26 @@
+package main
+
+import "log/slog"
+
+// finality is the number of epochs after which a tipset can no longer be
+// reverted.
+const finality = 900
+
+// markPending flags the transfers that are within finality of the chain head
+// at headHeight, or drops them instead if confirmedOnly is set.
+func markPending(xfers []Transfer, headHeight int, confirmedOnly bool) []Transfer {
+	kept := xfers[:0]
+	dropped := 0
+	for _, xfer := range xfers {
+		xfer.Pending = xfer.Height > headHeight-finality
+		if xfer.Pending && confirmedOnly {
+			dropped++
+			continue
+		}
+		kept = append(kept, xfer)
+	}
+	if dropped > 0 {
+		slog.Info("Omitted transfers not yet final", "count", dropped)
+	}
+	return kept
+}
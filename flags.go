@@ -0,0 +1,432 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// cliFlags are the values of the command line flags, which register binds to
+// a FlagSet.
+type cliFlags struct {
+	backend               string
+	failover              string
+	merge                 string
+	breakerThreshold      int
+	maxRetries            int
+	timeout               time.Duration
+	concurrency           int
+	maxPages              int
+	pageSize              int
+	apiTypes              string
+	rps                   float64
+	cacheDir              string
+	cacheTTL              time.Duration
+	apiKey                string
+	headers               headerFlag
+	userAgent             string
+	debugHTTP             string
+	offline               bool
+	fixtures              string
+	strict                bool
+	messages              bool
+	messageDetails        bool
+	feeBreakdown          bool
+	rewards               bool
+	pledges               bool
+	vesting               bool
+	tokens                bool
+	nfts                  bool
+	internal              bool
+	resolveIDs            bool
+	minerAccounts         bool
+	confirmedOnly         bool
+	skipFailed            bool
+	dropReplaced          bool
+	walletsFile           string
+	own                   string
+	fromDate              string
+	toDate                string
+	timezone              string
+	format                string
+	output                string
+	force                 bool
+	appendOutput          bool
+	gzipOutput            bool
+	zstdOutput            bool
+	combine               bool
+	outputTemplate        string
+	customTemplate        string
+	accountAssets         string
+	accountFees           string
+	accountIncome         string
+	accountPenalties      string
+	accountCounterparties string
+	balanceAssertions     bool
+	decimals              int
+	rounding              string
+	dateFormat            string
+	delimiter             string
+	decimalComma          bool
+	countervalueSource    string
+	countervalueCurrency  string
+	rawAmounts            bool
+	fromHeight            int
+	toHeight              int
+	minAmount             string
+	skipSpam              bool
+	spamSenders           string
+	direction             string
+	types                 string
+	labels                string
+	rules                 string
+	runningBalance        bool
+	progress              bool
+	logLevel              string
+	logFormat             string
+	columns               string
+	noColor               bool
+	plain                 bool
+	errorJSON             bool
+	prompt                bool
+	resume                bool
+	interval              time.Duration
+	listen                string
+}
+
+// register defines the flags on fs, storing their values in flags.
+func (flags *cliFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&flags.backend, "backend", "filfox", "explorer API to retrieve transfers from: "+strings.Join(source.Names, ", "))
+	fs.StringVar(&flags.failover, "failover", "", "secondary backend to use when the primary fails")
+	fs.StringVar(&flags.merge, "merge", "", "comma separated further backends to merge records from, reporting any a backend lacks")
+	fs.IntVar(&flags.breakerThreshold, "breaker-threshold", 3, "stop calling a backend after this many consecutive failures")
+	fs.IntVar(&flags.maxRetries, "max-retries", filfox.DefaultMaxRetries, "maximum number of retries for transient API failures")
+	fs.DurationVar(&flags.timeout, "timeout", filfox.DefaultTimeout, "time limit for each API request (0 for none)")
+	fs.IntVar(&flags.concurrency, "concurrency", filfox.DefaultConcurrency, "number of pages to fetch in parallel")
+	fs.IntVar(&flags.maxPages, "max-pages", filfox.DefaultMaxPages, "safety cap on the number of pages fetched per wallet")
+	fs.IntVar(&flags.pageSize, "page-size", filfox.DefaultPageSize, "number of records requested per API page")
+	fs.StringVar(&flags.apiTypes, "api-types", "", "comma separated transfer types to request from the API, e.g. send,receive (default all)")
+	fs.Float64Var(&flags.rps, "rps", 0, "maximum API requests per second (0 for unlimited)")
+	fs.StringVar(&flags.cacheDir, "cache-dir", "", "directory for caching API responses between runs (disabled if empty)")
+	fs.DurationVar(&flags.cacheTTL, "cache-ttl", 0, "serve cached responses this fresh without revalidating them")
+	fs.StringVar(&flags.apiKey, "api-key", "", "API key sent with every request (default $FILFOXY_API_KEY)")
+	flags.headers = make(headerFlag)
+	fs.Var(flags.headers, "header", "extra `key=value` HTTP header for every request (repeatable)")
+	fs.StringVar(&flags.userAgent, "user-agent", defaultUserAgent(), "User-Agent header sent with every request")
+	fs.StringVar(&flags.debugHTTP, "debug-http", "", "write every raw API request and response to this `dir`")
+	fs.BoolVar(&flags.offline, "offline", false, "serve API responses from --fixtures instead of the network")
+	fs.StringVar(&flags.fixtures, "fixtures", "", "`dir` of responses previously written by --debug-http")
+	fs.BoolVar(&flags.strict, "strict", false, "fail on unknown API response fields or transfer types instead of warning")
+	fs.BoolVar(&flags.messages, "messages", false, "retrieve the wallet's messages to decode each transfer's method, adding a Method column")
+	fs.BoolVar(&flags.messageDetails, "message-details", false, "look up each message for its gas breakdown and exit code, adding them as extra columns")
+	fs.BoolVar(&flags.feeBreakdown, "fee-breakdown", false, "look up each message to split its fees into base fee burn, overestimation burn and miner tip columns")
+	fs.BoolVar(&flags.rewards, "rewards", false, "include block rewards when exporting a miner (f0/f2) address")
+	fs.BoolVar(&flags.pledges, "pledges", false, "include pledge collateral locks and releases when exporting a miner (f0/f2) address")
+	fs.BoolVar(&flags.vesting, "vesting", false, "annotate withdrawals of vested funds from a vesting multisig, adding a Note column")
+	fs.BoolVar(&flags.tokens, "tokens", true, "include FRC-20/ERC-20 token transfers when exporting an f410 address")
+	fs.BoolVar(&flags.nfts, "nfts", false, "include ERC-721 transfers when exporting an f410 address, adding Token Contract and Token ID columns")
+	fs.BoolVar(&flags.internal, "internal", false, "include FIL sent or received by FEVM contract internal calls")
+	fs.BoolVar(&flags.resolveIDs, "resolve-ids", false, "replace f0 ID counterparty addresses with their robust f1/f2/f3/f410 form")
+	fs.BoolVar(&flags.minerAccounts, "miner-accounts", false, "include the owner, worker and beneficiary addresses when exporting a miner, as labelled sub-accounts")
+	fs.BoolVar(&flags.confirmedOnly, "confirmed-only", false, "omit transfers that haven't reached finality, rather than marking them Pending")
+	fs.BoolVar(&flags.skipFailed, "skip-failed", false, "omit messages that only paid fees, such as failed sends")
+	fs.BoolVar(&flags.dropReplaced, "drop-replaced", false, "retrieve the wallet's messages to drop any replaced by a resend with the same nonce")
+	fs.StringVar(&flags.walletsFile, "wallets-file", "", "`file` of further wallets to export, whitespace separated, with # comments; a wallet argument of - reads them from stdin")
+	fs.StringVar(&flags.own, "own", "", "comma separated further addresses of yours; transfers between them and the wallet are exported as TRANSFER")
+	fs.StringVar(&flags.fromDate, "from", "", "only export transfers on or after this `date` (YYYY-MM-DD or RFC 3339)")
+	fs.StringVar(&flags.toDate, "to", "", "only export transfers on or before this `date` (YYYY-MM-DD, inclusive, or RFC 3339)")
+	fs.StringVar(&flags.timezone, "timezone", "UTC", "IANA time `zone` of exported dates and of bare --from/--to dates, e.g. Europe/Berlin or Local")
+	fs.StringVar(&flags.format, "format", "ledger", "layout of the exported file: "+strings.Join(formatNames(), ", "))
+	fs.StringVar(&flags.output, "output", "", "`path` to write the export to, or - for stdout (default the wallet's first 9 characters, the format and its extension)")
+	fs.StringVar(&flags.output, "o", "", "shorthand for --output")
+	fs.BoolVar(&flags.force, "force", false, "replace the --output file if it already exists")
+	fs.BoolVar(&flags.appendOutput, "append", false, "add to the --output file if it already exists, skipping the messages it has; for CSV formats with a message column and sqlite")
+	fs.BoolVar(&flags.gzipOutput, "gzip", false, "gzip the export, adding .gz to its name (implied by an --output ending in .gz)")
+	fs.BoolVar(&flags.zstdOutput, "zstd", false, "compress the export with zstd, adding .zst to its name (implied by an --output ending in .zst)")
+	fs.BoolVar(&flags.combine, "combine", false, "export several wallets into one file, keeping each in its own account, rather than a file each")
+	fs.StringVar(&flags.outputTemplate, "output-template", "", "Go `template` naming the output file from .Wallet, .Format, .Ext, .From and .To dates, .FromHeight and .ToHeight, e.g. {{.Wallet}}-{{.From}}-{{.To}}{{.Ext}}")
+	fs.StringVar(&flags.customTemplate, "template", "", "`file` defining --format custom: YAML of CSV columns as Go templates, or a Go template of the whole file")
+	fs.StringVar(&flags.accountAssets, "account-assets", defaultJournalAccounts.assets, "double-entry `account` of the exported wallets")
+	fs.StringVar(&flags.accountFees, "account-fees", defaultJournalAccounts.fees, "double-entry `account` of gas fees")
+	fs.StringVar(&flags.accountIncome, "account-income", defaultJournalAccounts.income, "double-entry `account` of block rewards")
+	fs.StringVar(&flags.accountPenalties, "account-penalties", defaultJournalAccounts.penalties, "double-entry `account` of miner penalties")
+	fs.StringVar(&flags.accountCounterparties, "account-counterparties", defaultJournalAccounts.counterparties, "double-entry `account` under which labelled counterparties get their own")
+	fs.BoolVar(&flags.balanceAssertions, "balance-assertions", false, "add monthly balance assertions from the on-chain balance history to double-entry formats")
+	fs.IntVar(&flags.decimals, "decimals", -1, "round exported amounts to at most this many decimal places (-1 for full precision)")
+	fs.StringVar(&flags.rounding, "rounding", "half-even", "rounding `mode` for --decimals: "+strings.Join(roundingModes, ", "))
+	fs.StringVar(&flags.dateFormat, "date-format", "", "`layout` of CSV format dates: a Go time layout such as 02/01/2006 15:04, or one of "+strings.Join(dateFormatNames(), ", ")+" (default each format's own)")
+	fs.StringVar(&flags.delimiter, "delimiter", "", "`character` separating the fields of CSV formats, e.g. ';' or tab (default a comma, or ';' with --decimal-comma)")
+	fs.BoolVar(&flags.decimalComma, "decimal-comma", false, "write CSV format amounts with a decimal comma, e.g. 1234,5, as European spreadsheets and tax tools expect")
+	fs.StringVar(&flags.countervalueSource, "countervalues", "", "value transfers in fiat, for the ledger format's countervalue columns and the xero format, at prices from `source`: coingecko (a key in $COINGECKO_API_KEY if any), or a CSV file of date,price rows")
+	fs.StringVar(&flags.countervalueCurrency, "countervalue-currency", "USD", "fiat currency of --countervalues")
+	fs.BoolVar(&flags.rawAmounts, "raw-amounts", false, "export amounts as integers in the smallest unit, e.g. attoFIL, ignoring --decimals")
+	fs.IntVar(&flags.fromHeight, "from-height", 0, "only export transfers at or after this epoch")
+	fs.IntVar(&flags.toHeight, "to-height", 0, "only export transfers at or before this epoch")
+	fs.StringVar(&flags.minAmount, "min-amount", "", "omit incoming FIL transfers below this many FIL, e.g. 0.001")
+	fs.BoolVar(&flags.skipSpam, "skip-spam", false, "omit zero-value token airdrops and transfers from --spam-senders")
+	fs.StringVar(&flags.spamSenders, "spam-senders", "", "`file` of known spam sender addresses, one per line, for --skip-spam")
+	fs.StringVar(&flags.direction, "direction", "", "only export transfers in this direction: in or out")
+	fs.StringVar(&flags.types, "types", "", "only export transfers of these comma separated types: "+strings.Join(transferTypes, ","))
+	fs.StringVar(&flags.labels, "labels", "", "address book `file` (CSV of address,label or YAML map) naming counterparties in a Label column")
+	fs.StringVar(&flags.rules, "rules", "", "rules `file` (YAML or JSON) assigning categories and tags to transfers, added as columns")
+	fs.BoolVar(&flags.runningBalance, "balance", false, "add a running Balance column, and report how its final figure reconciles with the on-chain balance")
+	fs.BoolVar(&flags.progress, "progress", true, "show how far retrieving transfers has got on stderr: a live line on a terminal, otherwise a log line every few seconds")
+	fs.StringVar(&flags.logLevel, "log-level", "info", "least severe messages to log to stderr: debug, info, warn or error")
+	fs.StringVar(&flags.logFormat, "log-format", "text", "format of the logs on stderr: text or json")
+	fs.StringVar(&flags.columns, "columns", defaultTableColumns, "comma separated columns of the transfers printed to the terminal, in order: "+strings.Join(tableColumnNames(), ","))
+	fs.BoolVar(&flags.noColor, "no-color", false, "don't color the transfers printed to the terminal, as also when $NO_COLOR is set")
+	fs.BoolVar(&flags.plain, "plain", false, "print transfers to the terminal tab separated and uncolored, for scripts")
+	fs.BoolVar(&flags.errorJSON, "error-json", false, "report a failure on stderr as a JSON object of its error, exit code and kind, rather than a log line")
+	fs.BoolVar(&flags.prompt, "prompt", true, "when run from a terminal without wallets, ask for one and a format rather than printing usage")
+	fs.BoolVar(&flags.resume, "resume", false, "save fetch progress to a checkpoint, and resume an interrupted fetch from it")
+	fs.DurationVar(&flags.interval, "interval", time.Minute, "how often watch checks for new transfers")
+	fs.StringVar(&flags.listen, "listen", "localhost:8080", "`address` serve listens on")
+	fs.String("config", defaultConfigPath(), "TOML `file` of flag defaults, keyed by flag name, and of the wallets to export if none are given")
+}
+
+// fetchOptions configures retrieving transfers from the flags.
+func (flags *cliFlags) fetchOptions() (fetchOptions, error) {
+	opts := fetchOptions{
+		backend:          flags.backend,
+		failover:         flags.failover,
+		breakerThreshold: flags.breakerThreshold,
+		maxRetries:       flags.maxRetries,
+		timeout:          flags.timeout,
+		concurrency:      flags.concurrency,
+		maxPages:         flags.maxPages,
+		strict:           flags.strict,
+		pageSize:         flags.pageSize,
+		resume:           flags.resume,
+		cacheDir:         flags.cacheDir,
+		cacheTTL:         flags.cacheTTL,
+		apiKey:           flags.apiKey,
+		userAgent:        flags.userAgent,
+		headers:          http.Header(flags.headers),
+		debugHTTP:        flags.debugHTTP,
+		fixtures:         flags.fixtures,
+	}
+	_, from, to, err := flags.dateRange()
+	if err != nil {
+		return opts, err
+	}
+	// Only fetch the epochs that can fall within the dates
+	dateHeights, err := source.HeightsBetween(from, to)
+	if err != nil {
+		return opts, usageError{fmt.Errorf("--to %s: %w", flags.toDate, err)}
+	}
+	opts.heights = intersectHeights(dateHeights, source.HeightRange{From: flags.fromHeight, To: flags.toHeight})
+	if opts.heights.To != 0 && opts.heights.From > opts.heights.To {
+		return opts, usageError{fmt.Errorf("the height range %d-%d is empty", opts.heights.From, opts.heights.To)}
+	}
+	if flags.apiTypes != "" {
+		opts.types = strings.Split(flags.apiTypes, ",")
+	}
+	if flags.merge != "" {
+		opts.merge = strings.Split(flags.merge, ",")
+	}
+	if flags.rps > 0 {
+		// Shared by every request this process makes, regardless of wallet
+		opts.limiter = filfox.NewRateLimiter(flags.rps, 1)
+	}
+	return opts, nil
+}
+
+// dateRange reads --timezone, and --from and --to in it.
+func (flags *cliFlags) dateRange() (location *time.Location, from, to time.Time, err error) {
+	if location, err = time.LoadLocation(flags.timezone); err != nil {
+		return nil, from, to, usageError{fmt.Errorf("--timezone: %w", err)}
+	}
+	if from, to, err = parseDateRange(flags.fromDate, flags.toDate, location); err != nil {
+		return nil, from, to, usageError{err}
+	}
+	return location, from, to, nil
+}
+
+// exportOptionsFor configures an export of wallets from flags. names are the
+// config account names of wallets, and opts how transfers are retrieved.
+func exportOptionsFor(flags *cliFlags, wallets []string, names map[string]string, opts fetchOptions) (exportOptions, error) {
+	// The wallets exported together all belong to the user
+	ownAddresses := slices.Clone(wallets)
+	for _, addr := range strings.Split(flags.own, ",") {
+		if addr = strings.TrimSpace(addr); addr == "" {
+			continue
+		}
+		addr, err := normalizeAddress(addr)
+		if err != nil {
+			return exportOptions{}, err
+		}
+		ownAddresses = append(ownAddresses, addr)
+	}
+	location, from, to, err := flags.dateRange()
+	if err != nil {
+		return exportOptions{}, err
+	}
+	eopts := exportOptions{
+		methods:    flags.messages,
+		gasColumns: flags.messageDetails,
+		feeColumns: flags.feeBreakdown,
+		rewards:    flags.rewards,
+		pledges:    flags.pledges,
+		vesting:    flags.vesting,
+		tokens:     flags.tokens,
+		nfts:       flags.nfts,
+		internal:   flags.internal,
+		resolveIDs: flags.resolveIDs,
+
+		minerAccounts: flags.minerAccounts,
+		confirmedOnly: flags.confirmedOnly,
+		skipFailed:    flags.skipFailed,
+		dropReplaced:  flags.dropReplaced,
+		own:           ownAddresses,
+		format:        flags.format,
+		output:        flags.output,
+		overwrite:     flags.force,
+		appending:     flags.appendOutput,
+		combined:      flags.combine,
+		names:         names,
+		accounts: journalAccounts{
+			assets:         flags.accountAssets,
+			fees:           flags.accountFees,
+			income:         flags.accountIncome,
+			penalties:      flags.accountPenalties,
+			counterparties: flags.accountCounterparties,
+			perWallet:      flags.combine,
+		},
+		balanceAssertions: flags.balanceAssertions,
+		location:          location,
+		table:             newTableStyle(os.Stdout, flags.noColor, flags.plain),
+		from:              from,
+		to:                to,
+		heights:           source.HeightRange{From: flags.fromHeight, To: flags.toHeight},
+		balance:           flags.runningBalance,
+	}
+	if _, ok := formats[flags.format]; !ok {
+		return eopts, fmt.Errorf("unknown --format %q, want one of %s", flags.format, strings.Join(formatNames(), ", "))
+	}
+	if flags.outputTemplate != "" {
+		if flags.output != "" {
+			return eopts, errors.New("--output and --output-template are exclusive")
+		}
+		if eopts.outputTemplate, err = parseOutputTemplate(flags.outputTemplate); err != nil {
+			return eopts, err
+		}
+	}
+	if len(wallets) > 1 && !flags.combine && flags.output != "" {
+		return eopts, errors.New("--output names one file, so several wallets need --combine or --output-template")
+	}
+	switch {
+	case flags.gzipOutput && flags.zstdOutput:
+		return eopts, errors.New("--gzip and --zstd are exclusive")
+	case flags.gzipOutput:
+		eopts.compress = "gzip"
+	case flags.zstdOutput:
+		eopts.compress = "zstd"
+	default:
+		eopts.compress = compressionOf(flags.output)
+	}
+	if flags.appendOutput {
+		f := formats[flags.format]
+		switch {
+		case flags.force:
+			return eopts, errors.New("--force and --append are exclusive")
+		case f.appendKey == "" && f.writeFile == nil:
+			return eopts, fmt.Errorf("--format %s can't be appended to", flags.format)
+		case eopts.compress != "":
+			return eopts, errors.New("--append can't add to compressed files")
+		case flags.output == "-":
+			return eopts, errors.New("--append needs a file to add to, not stdout")
+		}
+	}
+	if eopts.compress != "" && formats[flags.format].writeFile != nil {
+		return eopts, fmt.Errorf("--format %s manages its own file, so can't be compressed", flags.format)
+	}
+	if flags.output == "-" && formats[flags.format].writeFile != nil {
+		return eopts, fmt.Errorf("--format %s manages its own file, so can't be written to stdout", flags.format)
+	}
+	if (flags.format == "custom") != (flags.customTemplate != "") {
+		return eopts, errors.New("--format custom and --template go together")
+	}
+	if flags.customTemplate != "" {
+		if eopts.custom, err = loadCustomFormat(flags.customTemplate); err != nil {
+			return eopts, err
+		}
+	}
+	if eopts.table.columns, err = parseTableColumns(flags.columns); err != nil {
+		return eopts, err
+	}
+	if eopts.amounts, err = newAmountFormat(flags.rawAmounts, flags.decimals, flags.rounding); err != nil {
+		return eopts, err
+	}
+	if (flags.delimiter != "" || flags.decimalComma) && !formats[flags.format].csv {
+		return eopts, fmt.Errorf("--delimiter and --decimal-comma only apply to CSV formats, not %s", flags.format)
+	}
+	if flags.dateFormat != "" {
+		if !formats[flags.format].csv {
+			return eopts, fmt.Errorf("--date-format only applies to CSV formats, not %s", flags.format)
+		}
+		if eopts.dateFormat, err = parseDateFormat(flags.dateFormat); err != nil {
+			return eopts, err
+		}
+	}
+	if flags.delimiter != "" {
+		if eopts.delimiter, err = parseDelimiter(flags.delimiter); err != nil {
+			return eopts, err
+		}
+	}
+	if flags.decimalComma {
+		eopts.amounts.decimalComma = true
+		if eopts.delimiter == 0 {
+			// Commas would then need quoting in every amount
+			eopts.delimiter = ';'
+		}
+	}
+	if formats[flags.format].fiat && flags.countervalueSource == "" {
+		return eopts, fmt.Errorf("--format %s is valued in fiat, so needs --countervalues", flags.format)
+	}
+	if flags.countervalueSource != "" {
+		if !formats[flags.format].countervalues && !formats[flags.format].fiat {
+			return eopts, fmt.Errorf("--format %s has no countervalues to fill in", flags.format)
+		}
+		eopts.countervalueCurrency = strings.ToUpper(flags.countervalueCurrency)
+		if err = validateCountervalueCurrency(eopts.countervalueCurrency); err != nil {
+			return eopts, err
+		}
+		if eopts.prices, err = newPriceSource(flags.countervalueSource, opts); err != nil {
+			return eopts, err
+		}
+	}
+	if flags.minAmount != "" {
+		if eopts.minAmount, err = parseFIL(flags.minAmount); err != nil {
+			return eopts, err
+		}
+	}
+	if flags.skipSpam {
+		if eopts.spam, err = newSpamFilter(flags.spamSenders); err != nil {
+			return eopts, err
+		}
+	}
+	if flags.labels != "" {
+		if eopts.labels, err = loadAddressBook(flags.labels); err != nil {
+			return eopts, err
+		}
+	}
+	if flags.rules != "" {
+		if eopts.rules, err = loadRules(flags.rules, eopts.methods); err != nil {
+			return eopts, err
+		}
+	}
+	if flags.direction != "" || flags.types != "" {
+		if eopts.filter, err = newTypeFilter(flags.direction, flags.types); err != nil {
+			return eopts, err
+		}
+	}
+	return eopts, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+// parseFlags parses args as the command line would be.
+func parseFlags(t *testing.T, args ...string) *cliFlags {
+	t.Helper()
+	var flags cliFlags
+	fs := flag.NewFlagSet("filfoxy", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return &flags
+}
+
+func TestExportOptionsFor(t *testing.T) {
+	flags := parseFlags(t, "--format", "koinly", "--decimal-comma", "--from", "2024-01-01", "--own", testOther)
+	opts, err := flags.fetchOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eopts, err := exportOptionsFor(flags, []string{testWallet}, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eopts.format != "koinly" || eopts.delimiter != ';' || !eopts.amounts.decimalComma {
+		t.Errorf("got format %s, delimiter %q and decimal comma %v", eopts.format, eopts.delimiter, eopts.amounts.decimalComma)
+	}
+	if len(eopts.own) != 2 || eopts.own[1] != testOther {
+		t.Errorf("own = %v, want the wallet and %s", eopts.own, testOther)
+	}
+	if eopts.from.IsZero() || opts.heights.From == 0 {
+		t.Errorf("--from set neither the date %v nor the heights %+v", eopts.from, opts.heights)
+	}
+}
+
+func TestExportOptionsForConflicts(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--format", "nope"}, "unknown --format"},
+		{[]string{"--gzip", "--zstd"}, "exclusive"},
+		{[]string{"--force", "--append", "--format", "koinly"}, "exclusive"},
+		{[]string{"--format", "sqlite", "--gzip"}, "can't be compressed"},
+		{[]string{"--format", "json", "--delimiter", ";"}, "only apply to CSV formats"},
+		{[]string{"--format", "custom"}, "go together"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			flags := parseFlags(t, tt.args...)
+			_, err := exportOptionsFor(flags, []string{testWallet}, nil, fetchOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mroth/filfoxy/pkg/export"
+)
+
+// format is a file layout transfers can be exported in.
+type format struct {
+	description string // a line for the formats command
+	ext         string // file name extension, including the dot
+	write       func(w io.Writer, xfers []Transfer, opts exportOptions) error
+
+	// writeFile, if set, replaces write for formats that manage their file
+	// themselves, such as databases that are appended to.
+	writeFile func(path string, xfers []Transfer, opts exportOptions) error
+
+	// openings has the balance of each wallet at the start of the export
+	// looked up into opts.openings, for statements.
+	openings bool
+
+	// csv formats are delimited text, written with opts.csvWriter, so
+	// --delimiter and --decimal-comma apply.
+	csv bool
+
+	// countervalues formats can value transfers with --countervalues, and
+	// fiat formats only write values, so need them.
+	countervalues, fiat bool
+
+	// appendKey is the header of the column of CSV formats holding the
+	// message CID, by which --append skips transfers already in the file.
+	// Formats without one can't be appended to.
+	appendKey string
+}
+
+// writeTo writes xfers to the file at path, or stdout if path is -,
+// compressed as configured. An existing file is only replaced with --force,
+// or added to with --append.
+func (f format) writeTo(path string, xfers []Transfer, opts exportOptions) error {
+	if f.writeFile != nil {
+		if err := checkOverwrite(path, opts); err != nil {
+			return err
+		}
+		if opts.overwrite {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		return f.writeFile(path, xfers, opts)
+	}
+	out := io.Writer(os.Stdout)
+	var file *os.File
+	if path != "-" {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if opts.overwrite {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+		var err error
+		file, err = os.OpenFile(path, flags, 0o666)
+		switch {
+		case errors.Is(err, fs.ErrExist) && opts.appending:
+			return f.appendTo(path, xfers, opts)
+		case errors.Is(err, fs.ErrExist):
+			return existsError(path)
+		case err != nil:
+			return err
+		}
+		out = file
+	}
+	w, err := compressWriter(out, opts.compress)
+	if err == nil {
+		err = f.write(w, xfers, opts)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if file != nil {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// existsError refuses to replace the file at path without --force or
+// --append.
+func existsError(path string) error {
+	return fmt.Errorf("%s already exists; give --force to replace it, or --append to add to it", path)
+}
+
+// checkOverwrite fails with existsError if the file at path exists and opts
+// neither replace nor add to it.
+func checkOverwrite(path string, opts exportOptions) error {
+	if opts.overwrite || opts.appending {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return existsError(path)
+	}
+	return nil
+}
+
+// formats are the layouts selectable with --format, by name.
+var formats = make(map[string]format)
+
+// registerFormat makes f selectable as name. Each built in format registers
+// itself from an init function of its own file; formats contributed from
+// outside go through package export instead.
+func registerFormat(name string, f format) {
+	if _, ok := formats[name]; ok {
+		panic("format " + name + " registered twice")
+	}
+	if (f.write == nil) == (f.writeFile == nil) {
+		panic("format " + name + " needs exactly one of write and writeFile")
+	}
+	formats[name] = f
+}
+
+// registerExportFormats makes the formats contributed through package export
+// selectable too. main calls it before anything lists formatNames.
+func registerExportFormats() {
+	for _, name := range export.Names() {
+		f, _ := export.Lookup(name)
+		registerFormat(name, format{description: f.Description, ext: f.Ext, csv: f.CSV,
+			write: func(w io.Writer, xfers []Transfer, opts exportOptions) error {
+				exported := make([]export.Transfer, len(xfers))
+				for i, x := range xfers {
+					exported[i] = x.exported()
+				}
+				return f.Exporter.Export(w, exported, opts.exported())
+			}})
+	}
+}
+
+// exported is x as package export presents it to contributed formats.
+func (x Transfer) exported() export.Transfer {
+	amount, decimals := x.moved()
+	return export.Transfer{
+		Wallet:       x.Wallet,
+		Kind:         string(x.Kind),
+		Flow:         x.flow().String(),
+		Height:       x.Height,
+		Timestamp:    x.Timestamp,
+		MessageID:    x.MessageID,
+		From:         x.From,
+		To:           x.To,
+		Counterparty: x.counterparty(),
+		Amount:       amount,
+		Currency:     x.Ticker(),
+		Decimals:     decimals,
+		Fee:          x.fee(),
+		FeeOnly:      x.FeeOnly,
+		Self:         x.Self,
+		Pending:      x.Pending,
+		Method:       x.Method,
+		Label:        x.Label,
+		Category:     x.Category,
+		Tags:         x.Tags,
+		Account:      x.Account,
+		Note:         x.Note,
+	}
+}
+
+// exported is opts as package export presents them to contributed formats.
+func (opts exportOptions) exported() export.Options {
+	return export.Options{
+		Location:     cmp.Or(opts.location, time.UTC),
+		Combined:     opts.combined,
+		FormatAmount: opts.amounts.format,
+		FormatDate:   opts.date,
+		Delimiter:    opts.delimiter,
+	}
+}
+
+// formatNames lists the keys of formats, sorted.
+func formatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// fee is the total of the fees x paid, as a positive attoFIL amount.
+func (x Transfer) fee() *big.Int {
+	fee := new(big.Int)
+	for _, f := range []*big.Int{x.MinerFee, x.BurnFee} {
+		if f != nil {
+			fee.Add(fee, new(big.Int).Abs(f))
+		}
+	}
+	return fee
+}
+
+// description joins whatever x has been annotated with, for formats with a
+// single free text column.
+func (x Transfer) description() string {
+	var parts []string
+	for _, s := range []string{x.Method, x.Label, x.Category, x.Note} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// csvWriter writes CSV records to w separated by the export's delimiter.
+func (opts exportOptions) csvWriter(w io.Writer) *csv.Writer {
+	writer := csv.NewWriter(w)
+	if opts.delimiter != 0 {
+		writer.Comma = opts.delimiter
+	}
+	return writer
+}
+
+// parseDelimiter reads a --delimiter: a single character, or tab.
+func parseDelimiter(s string) (rune, error) {
+	if s == "tab" || s == `\t` {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("--delimiter %q must be a single character other than a quote or newline, or tab", s)
+	}
+	return r, nil
+}
+
+// localTime is t in the export's time zone.
+func (opts exportOptions) localTime(t time.Time) time.Time {
+	return t.In(cmp.Or(opts.location, time.UTC))
+}
+
+// flow is how a transfer changes the exported wallet's holdings, for formats
+// that only know deposits and withdrawals.
+type flow int
+
+const (
+	flowNone flow = iota // stays within the wallet, so only its fees count
+	flowIn
+	flowOut
+)
+
+func (x Transfer) flow() flow {
+	switch {
+	case x.Kind == KindPledge:
+		// Collateral stays in the miner's balance
+		return flowNone
+	case x.Kind == KindReward:
+		return flowIn
+	case x.Kind == KindPenalty:
+		return flowOut
+	case x.FeeOnly, x.Amount.Sign() == 0 && x.Kind != KindNFT:
+		return flowNone
+	case x.Self && (x.From == x.To || x.ToWallet != ""):
+		// Both sides are in this export
+		return flowNone
+	case x.outgoing(x.Wallet):
+		// Self transfers to another of the user's wallets are left to the
+		// tax tool to match against that wallet's deposit
+		return flowOut
+	default:
+		return flowIn
+	}
+}
+
+// quantity formats the amount x moved, unsigned, counting an NFT as one.
+func (opts exportOptions) quantity(x Transfer) string {
+	if x.Kind == KindNFT {
+		return "1"
+	}
+	return opts.amounts.format(new(big.Int).Abs(x.Amount), x.decimals())
+}
+
+// A movement is a transfer as tax tools that only know deposits and
+// withdrawals see it: an amount sent or received, and a fee apart from it.
+type movement struct {
+	Transfer
+	sent, sentCurrency         string
+	received, receivedCurrency string
+	fee, feeCurrency           string
+	feeOnly                    bool // only the fee left the wallet, so it is what was sent
+}
+
+// movements decomposes xfers into movements, dropping moves within the
+// wallet, such as pledges or paired miner sub-account transfers, that don't
+// change its holdings once their fees are counted.
+func (opts exportOptions) movements(xfers []Transfer) []movement {
+	var ms []movement
+	var skipped int
+	for _, xfer := range xfers {
+		m := movement{Transfer: xfer}
+		switch xfer.flow() {
+		case flowIn:
+			m.received, m.receivedCurrency = opts.quantity(xfer), xfer.Ticker()
+		case flowOut:
+			m.sent, m.sentCurrency = opts.quantity(xfer), xfer.Ticker()
+		}
+		if f := xfer.fee(); f.Sign() != 0 {
+			m.fee, m.feeCurrency = opts.amounts.format(f, 18), "FIL"
+			if m.sent == "" && m.received == "" {
+				m.sent, m.sentCurrency, m.feeOnly = m.fee, m.feeCurrency, true
+				m.fee, m.feeCurrency = "", ""
+			}
+		}
+		if m.sent == "" && m.received == "" {
+			skipped++
+			continue
+		}
+		ms = append(ms, m)
+	}
+	if skipped > 0 {
+		slog.Info("Omitted transfers within the wallet that don't change its holdings", "count", skipped)
+	}
+	return ms
+}
+
+func (f flow) String() string {
+	switch f {
+	case flowIn:
+		return "In"
+	case flowOut:
+		return "Out"
+	}
+	return "Internal"
+}
+
+// moved is the signed amount x moved in units of 10^-decimals, counting an
+// NFT as one.
+func (x Transfer) moved() (amount *big.Int, decimals int) {
+	if x.Kind != KindNFT {
+		return new(big.Int).Set(x.Amount), x.decimals()
+	}
+	amount = big.NewInt(1)
+	if x.outgoing(x.Wallet) {
+		amount.Neg(amount)
+	}
+	return amount, 0
+}
+
+// runFormats lists the formats --format accepts.
+func runFormats(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FORMAT\tEXTENSION\tDESCRIPTION")
+	for _, name := range formatNames() {
+		f := formats[name]
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, f.ext, f.description)
+	}
+	return tw.Flush()
+}
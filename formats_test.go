@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/export"
+	"github.com/mroth/filfoxy/pkg/filfox"
+	"github.com/mroth/filfoxy/pkg/filfox/filfoxtest"
+	"github.com/mroth/filfoxy/pkg/price"
+	"github.com/mroth/filfoxy/pkg/source"
+	"github.com/parquet-go/parquet-go"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the format tests")
+
+// testExport fetches a small history of testWallet from a mock filfox, with a
+// send paying both fees, two receives, a failed message that only paid fees,
+// and a block reward, labelled and categorised as an export would be.
+func testExport(t *testing.T) ([]Transfer, exportOptions) {
+	t.Helper()
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	at := func(height int) int { return 1714521600 + (height-100)*30 } // 2024-05-01
+	srv.SetTransfers(testWallet, []filfox.Transfer{
+		{Height: 400, Timestamp: at(400), Message: "bafysend", From: testWallet, To: testOther, Value: "-1250000000000000000", Type: "send"},
+		{Height: 400, Timestamp: at(400), Message: "bafysend", From: testWallet, To: "f05", Value: "-1000000000000", Type: "miner-fee"},
+		{Height: 400, Timestamp: at(400), Message: "bafysend", From: testWallet, To: "f099", Value: "-2000000000000", Type: "burn-fee"},
+		{Height: 300, Timestamp: at(300), Message: "bafyfailed", From: testWallet, To: "f099", Value: "-3000000000000", Type: "burn-fee"},
+		{Height: 200, Timestamp: at(200), Message: "bafypayout", From: testOther, To: testWallet, Value: "10000000000000000000", Type: "receive"},
+		{Height: 100, Timestamp: at(100), Message: "bafyfirst", From: "f1abc", To: testWallet, Value: "500000000000000000", Type: "receive"},
+	})
+	xfers, err := fetchTransfers(context.Background(), source.NewFilfox(srv.Client()), testWallet, fetchOptions{backend: "filfox"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reward := Transfer{Wallet: testWallet, Kind: KindReward, Height: 350, Timestamp: time.Unix(int64(at(350)), 0), MessageID: "reward-350",
+		From: "f02", To: testWallet, Amount: big.NewInt(2e18)}
+	xfers = append(xfers, reward)
+	slices.SortStableFunc(xfers, func(a, b Transfer) int { return b.Timestamp.Compare(a.Timestamp) })
+	for i := range xfers {
+		if xfers[i].counterparty() == testOther {
+			xfers[i].Label = "Acme Storage"
+		}
+		if xfers[i].MessageID == "bafypayout" {
+			xfers[i].Category, xfers[i].Tags = "income", []string{"payout"}
+		}
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	return xfers, exportOptions{
+		format:   "ledger",
+		amounts:  amountFormat{decimals: -1},
+		accounts: defaultJournalAccounts,
+		from:     day(1),
+		to:       day(3),
+		openings: map[string]*big.Int{testWallet: big.NewInt(1e18)},
+		countervalues: &countervalues{
+			currency: "USD",
+			history:  price.History{{Time: day(1), Price: big.NewRat(5, 1)}},
+			spot:     big.NewRat(6, 1),
+		},
+	}
+}
+
+// volatile matches the parts of exports that vary from run to run.
+var volatile = regexp.MustCompile(`<DTSERVER>[^<]*`)
+
+func stable(b []byte) []byte {
+	return volatile.ReplaceAll(b, []byte("<DTSERVER>now"))
+}
+
+func TestFormatsGolden(t *testing.T) {
+	xfers, opts := testExport(t)
+	custom, err := loadCustomFormat(writeTestFile(t, "custom.yaml", `
+columns:
+  - header: Date
+    value: '{{.Date "02/01/2006"}}'
+  - header: Type
+    value: '{{if eq .Flow "Out"}}Withdrawal{{else}}Deposit{{end}}'
+  - header: Amount
+    value: '{{.Quantity}} {{.Ticker}}'
+  - header: Fee
+    value: '{{.Fee}}'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		"koinly", "coinledger", "cointracking", "turbotax",
+		"beancount", "hledger", "ledger-cli", "gnucash",
+		"ofx", "qif", "xero", "quickbooks", "quickbooks-4col",
+		"markdown", "html", "custom",
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts := opts
+			opts.format, opts.custom = name, custom
+			var buf bytes.Buffer
+			if err := formats[name].write(&buf, xfers, opts); err != nil {
+				t.Fatal(err)
+			}
+			got := stable(buf.Bytes())
+
+			path := filepath.Join("testdata", "formats", name+formats[name].ext)
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from %s (run go test -update if intended):\n%s", name, path, got)
+			}
+		})
+	}
+}
+
+func TestSQLiteRows(t *testing.T) {
+	xfers, opts := testExport(t)
+	path := filepath.Join(t.TempDir(), "transfers.db")
+	if err := writeSQLite(path, xfers, opts); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT message_id, kind, amount, category FROM transfers ORDER BY timestamp DESC`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id, kind, amount string
+		var category sql.NullString
+		if err := rows.Scan(&id, &kind, &amount, &category); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, strings.Join([]string{id, kind, amount, category.String}, " "))
+	}
+	want := []string{
+		"bafysend transfer -1250000000000000000 ",
+		"reward-350 reward 2000000000000000000 ",
+		"bafyfailed transfer 0 ",
+		"bafypayout transfer 10000000000000000000 income",
+		"bafyfirst transfer 500000000000000000 ",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got rows\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParquetRows(t *testing.T) {
+	xfers, opts := testExport(t)
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, xfers, opts); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := parquet.Read[parquetTransfer](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(xfers) {
+		t.Fatalf("read back %d rows, want %d", len(rows), len(xfers))
+	}
+	for i, r := range rows {
+		x := xfers[i]
+		want, err := parquetDecimal(x.Amount, x.decimals())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.MessageID != x.MessageID || r.Kind != string(x.Kind) || r.Amount != want || r.Category != x.Category {
+			t.Errorf("row %d = %s %s %s, want %s %s %s", i, r.MessageID, r.Kind, r.Category, x.MessageID, x.Kind, x.Category)
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	xfers, opts := testExport(t)
+	var buf bytes.Buffer
+	if err := writePDF(&buf, xfers, opts); err != nil {
+		t.Fatal(err)
+	}
+	b := bytes.TrimSpace(buf.Bytes())
+	if !bytes.HasPrefix(b, []byte("%PDF-")) || !bytes.HasSuffix(b, []byte("%%EOF")) {
+		t.Errorf("not a complete PDF: %q...", b[:min(len(b), 16)])
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	export.Register("test-contributed", export.Format{Description: "test", Ext: ".txt", Exporter: export.ExporterFunc(
+		func(w io.Writer, xfers []export.Transfer, opts export.Options) error {
+			for _, x := range xfers {
+				fmt.Fprintf(w, "%s %s %s %s %s\n", opts.FormatDate(x.Timestamp.In(opts.Location), time.DateOnly),
+					x.Flow, opts.FormatAmount(x.Amount, x.Decimals), x.Currency, opts.FormatAmount(x.Fee, 18))
+			}
+			return nil
+		})})
+	registerExportFormats()
+
+	xfers, opts := testExport(t)
+	f, ok := formats["test-contributed"]
+	if !ok {
+		t.Fatalf("contributed format missing from %v", formatNames())
+	}
+	var buf bytes.Buffer
+	if err := f.write(&buf, xfers[:2], opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "2024-05-01 Out -1.25 FIL 0.000003\n2024-05-01 In 2 FIL 0\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"time"
+)
+
+func init() {
+	registerFormat("gnucash", format{description: "GnuCash multi-split transaction CSV", ext: ".csv", write: writeGnuCashCSV, csv: true})
+}
+
+// writeGnuCashCSV writes xfers for GnuCash's transaction importer in
+// multi-split mode: one row per split of the journal postings, with the
+// transaction's fields on its first row only. Splits name the commodity
+// they're in, which must exist in GnuCash as a security for the FIL and
+// token accounts.
+func writeGnuCashCSV(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	writer := opts.csvWriter(w)
+	defer writer.Flush()
+
+	headers := []string{
+		"Date",
+		"Transaction ID",
+		"Description",
+		"Notes",
+		"Commodity/Currency",
+		"Memo",
+		"Full Account Name",
+		"Amount Num.",
+		"Reconcile",
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	ids := make(map[string]int) // entries seen per message
+	for _, e := range journalEntries(xfers, opts) {
+		id := e.message
+		if n := ids[e.message]; n > 0 {
+			id += "-" + strconv.Itoa(n)
+		}
+		ids[e.message]++
+
+		reconcile := "c"
+		if e.pending {
+			reconcile = "n"
+		}
+		for i, p := range e.postings {
+			var date, description, notes string
+			if i == 0 {
+				date = opts.date(opts.localTime(e.time), time.DateOnly)
+				description, notes = e.payee, e.narration
+			}
+			record := []string{
+				date,
+				id,
+				description,
+				notes,
+				p.commodity,
+				e.message,
+				p.account,
+				p.amount,
+				reconcile,
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
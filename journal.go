@@ -0,0 +1,222 @@
+package main
+
+import (
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// journalAccounts names the accounts double-entry formats post to.
+type journalAccounts struct {
+	assets         string // the exported wallets, with sub-accounts below it
+	fees           string // gas fees paid
+	income         string // block rewards
+	penalties      string // miner penalties
+	counterparties string // labelled counterparties, each below it, or Unknown
+
+	perWallet bool // give each wallet its own account below assets
+}
+
+var defaultJournalAccounts = journalAccounts{
+	assets:         "Assets:Filecoin",
+	fees:           "Expenses:Filecoin:Fees",
+	income:         "Income:Filecoin:Mining",
+	penalties:      "Expenses:Filecoin:Penalties",
+	counterparties: "Equity:Filecoin:Counterparties",
+}
+
+// journalEntry is a balanced transaction of a double-entry format.
+type journalEntry struct {
+	time      time.Time
+	pending   bool
+	payee     string
+	narration string
+	message   string
+	tags      []string
+	postings  []posting
+}
+
+// posting moves amount, a signed decimal, of commodity into account.
+type posting struct {
+	account   string
+	amount    string
+	commodity string
+}
+
+// journalEntries turns each transfer into balanced postings between the
+// asset account of its wallet, the fee expense account and its
+// counterparty's account, oldest first. Pledges, which lock FIL without
+// moving it, are left out.
+func journalEntries(xfers []Transfer, opts exportOptions) []journalEntry {
+	accts := opts.accounts
+	var entries []journalEntry
+	for _, xfer := range xfers {
+		if xfer.Kind == KindPledge {
+			continue
+		}
+		e := journalEntry{
+			time:      xfer.Timestamp,
+			pending:   xfer.Pending,
+			payee:     xfer.Label,
+			narration: xfer.description(),
+			message:   xfer.MessageID,
+			tags:      xfer.Tags,
+		}
+		if e.payee == "" {
+			e.payee = xfer.counterparty()
+		}
+		asset := accts.assetAccount(xfer.Wallet, xfer.Account)
+
+		value := new(big.Int).Set(xfer.Amount)
+		if xfer.Kind == KindNFT {
+			value.SetInt64(1)
+			if xfer.outgoing(xfer.Wallet) {
+				value.Neg(value)
+			}
+		}
+		decimals := xfer.decimals()
+		if xfer.Kind == KindNFT {
+			decimals = 0
+		}
+		if value.Sign() != 0 && !(xfer.Self && xfer.From == xfer.To) {
+			var other string
+			switch {
+			case xfer.Kind == KindReward:
+				other = accts.income
+			case xfer.Kind == KindPenalty:
+				other = accts.penalties
+			case xfer.ToWallet != "":
+				other = accts.assetAccount(xfer.ToWallet, xfer.ToAccount)
+			case xfer.Self:
+				other = accts.assets + ":" + accountComponent(xfer.counterparty())
+			case xfer.Category != "":
+				other = categoryAccount(xfer)
+			case xfer.Label != "":
+				other = accts.counterparties + ":" + accountComponent(xfer.Label)
+			default:
+				other = accts.counterparties + ":Unknown"
+			}
+			commodity := commodityName(xfer.Ticker())
+			e.postings = append(e.postings,
+				posting{asset, opts.amounts.format(value, decimals), commodity},
+				posting{other, opts.amounts.format(new(big.Int).Neg(value), decimals), commodity})
+		}
+		if fee := xfer.fee(); fee.Sign() != 0 {
+			e.postings = append(e.postings,
+				posting{accts.fees, opts.amounts.format(fee, 18), "FIL"},
+				posting{asset, opts.amounts.format(new(big.Int).Neg(fee), 18), "FIL"})
+		}
+		if len(e.postings) > 0 {
+			entries = append(entries, e)
+		}
+	}
+	slices.Reverse(entries)
+	return entries
+}
+
+// balanceAssertion states the FIL balance of account at the start of a day.
+type balanceAssertion struct {
+	time    time.Time
+	account string
+	amount  *big.Int
+}
+
+// balanceAssertions derives the FIL balance of wallet at the start of each
+// month in loc covered by its on-chain samples.
+func balanceAssertions(samples []source.BalanceSample, xfers []Transfer, wallet string, opts exportOptions) ([]balanceAssertion, error) {
+	if len(samples) == 0 {
+		return nil, nil
+	}
+	account := opts.accounts.assetAccount(wallet, "")
+	for _, x := range xfers {
+		if x.Wallet == wallet {
+			account = opts.accounts.assetAccount(wallet, x.Account)
+			break
+		}
+	}
+
+	var assertions []balanceAssertion
+	first := opts.localTime(time.Unix(samples[0].Timestamp, 0))
+	month := time.Date(first.Year(), first.Month()+1, 1, 0, 0, 0, 0, first.Location())
+	last := time.Unix(samples[len(samples)-1].Timestamp, 0)
+	for ; !month.After(last); month = month.AddDate(0, 1, 0) {
+		amount, err := balanceAt(samples, xfers, wallet, month)
+		if err != nil {
+			return nil, err
+		}
+		assertions = append(assertions, balanceAssertion{month, account, amount})
+	}
+	return assertions, nil
+}
+
+// assetAccount is the account of a wallet, or of a miner's sub-account.
+func (accts journalAccounts) assetAccount(wallet, sub string) string {
+	account := accts.assets
+	if accts.perWallet {
+		account += ":" + accountComponent(wallet)
+	}
+	if sub != "" {
+		account += ":" + accountComponent(sub)
+	}
+	return account
+}
+
+// categoryAccount names the account of a categorised transfer: the category
+// itself if it names a full account, such as Income:Consulting, or else the
+// category below Income or Expenses by the way the transfer flows.
+func categoryAccount(x Transfer) string {
+	parts := strings.Split(x.Category, ":")
+	if len(parts) > 1 && slices.Contains(accountRoots, parts[0]) {
+		for i := 1; i < len(parts); i++ {
+			parts[i] = accountComponent(parts[i])
+		}
+		return strings.Join(parts, ":")
+	}
+	root := "Income"
+	if x.outgoing(x.Wallet) {
+		root = "Expenses"
+	}
+	return root + ":" + accountComponent(x.Category)
+}
+
+// accountRoots are the top-level accounts of double-entry formats.
+var accountRoots = []string{"Assets", "Liabilities", "Equity", "Income", "Expenses"}
+
+// accountComponent turns s, such as a label or address, into a single
+// account name component: the capitalised words of s joined with dashes.
+func accountComponent(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	if len(words) == 0 {
+		return "Unknown"
+	}
+	return strings.Join(words, "-")
+}
+
+// commodityName turns a ticker into a commodity name: upper case letters,
+// digits and a few punctuation marks, starting with a letter.
+func commodityName(ticker string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return unicode.ToUpper(r)
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-', r == '\'':
+			return r
+		}
+		return -1
+	}, ticker)
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		name = "T" + name
+	}
+	return name[:min(len(name), 24)]
+}
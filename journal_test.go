@@ -0,0 +1,41 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestJournalCounterpartyAccounts(t *testing.T) {
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	xfer := func(amount int64, category, label string) Transfer {
+		x := Transfer{Wallet: testWallet, Kind: KindTransfer, Timestamp: at, MessageID: "bafy", From: testOther, To: testWallet,
+			Amount: big.NewInt(amount), Category: category, Label: label}
+		if amount < 0 {
+			x.From, x.To = testWallet, testOther
+		}
+		return x
+	}
+	tests := []struct {
+		name string
+		xfer Transfer
+		want string
+	}{
+		{"income category", xfer(5, "storage deals", "Acme"), "Income:Storage-Deals"},
+		{"expense category", xfer(-5, "hosting", ""), "Expenses:Hosting"},
+		{"full account category", xfer(5, "Income:consulting work", ""), "Income:Consulting-Work"},
+		{"label", xfer(5, "", "Acme"), "Equity:Filecoin:Counterparties:Acme"},
+		{"neither", xfer(5, "", ""), "Equity:Filecoin:Counterparties:Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := journalEntries([]Transfer{tt.xfer}, exportOptions{accounts: defaultJournalAccounts, amounts: amountFormat{decimals: -1}})
+			if len(entries) != 1 || len(entries[0].postings) != 2 {
+				t.Fatalf("got entries %+v, want one of two postings", entries)
+			}
+			if got := entries[0].postings[1].account; got != tt.want {
+				t.Errorf("counterparty account = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
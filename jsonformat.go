@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math/big"
+)
+
+func init() {
+	registerFormat("json", format{description: "JSON array of transfers, amounts as exact strings", ext: ".json", write: writeJSON})
+	registerFormat("jsonl", format{description: "one JSON transfer per line, for streaming tools", ext: ".jsonl", write: writeJSONL})
+}
+
+// jsonTransfer is a Transfer as written by the JSON formats, with its amounts
+// as strings of integers so no consumer rounds them through a float.
+type jsonTransfer struct {
+	Transfer
+	Amount             *string `json:"amount"`
+	MinerFee           *string `json:"miner_fee"`
+	BurnFee            *string `json:"burn_fee"`
+	Balance            *string `json:"balance,omitempty"`
+	GasFeeCap          *string `json:"gas_fee_cap,omitempty"`
+	GasPremium         *string `json:"gas_premium,omitempty"`
+	BaseFeeBurn        *string `json:"base_fee_burn,omitempty"`
+	OverEstimationBurn *string `json:"over_estimation_burn,omitempty"`
+	MinerTip           *string `json:"miner_tip,omitempty"`
+}
+
+func newJSONTransfer(x Transfer) jsonTransfer {
+	str := func(v *big.Int) *string {
+		if v == nil {
+			return nil
+		}
+		s := v.String()
+		return &s
+	}
+	return jsonTransfer{
+		Transfer:           x,
+		Amount:             str(x.Amount),
+		MinerFee:           str(x.MinerFee),
+		BurnFee:            str(x.BurnFee),
+		Balance:            str(x.Balance),
+		GasFeeCap:          str(x.GasFeeCap),
+		GasPremium:         str(x.GasPremium),
+		BaseFeeBurn:        str(x.BaseFeeBurn),
+		OverEstimationBurn: str(x.OverEstimationBurn),
+		MinerTip:           str(x.MinerTip),
+	}
+}
+
+// writeJSON writes xfers as an indented JSON array. Amounts are in attoFIL,
+// or a token's smallest unit, and timestamps in UTC regardless of the export
+// options.
+func writeJSON(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	out := make([]jsonTransfer, len(xfers))
+	for i, x := range xfers {
+		out[i] = newJSONTransfer(x)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeJSONL writes xfers as JSON Lines, one transfer per line, in the shape
+// of writeJSON.
+func writeJSONL(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	enc := json.NewEncoder(w)
+	for _, x := range xfers {
+		if err := enc.Encode(newJSONTransfer(x)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
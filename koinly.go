@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerFormat("koinly", format{description: "Koinly universal CSV", ext: ".csv", write: writeKoinlyCSV, csv: true, appendKey: "TxHash"})
+}
+
+// writeKoinlyCSV writes xfers in Koinly's universal CSV layout. Each row is a
+// deposit (Received), a withdrawal (Sent) or both; fees are always listed
+// apart from the amount sent. Moves within the exported wallet, such as
+// pledges or paired miner sub-account transfers, only contribute their fees.
+// A category or tag naming one of Koinly's labels labels the row.
+func writeKoinlyCSV(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	writer := opts.csvWriter(w)
+	defer writer.Flush()
+
+	headers := []string{
+		"Date",
+		"Sent Amount",
+		"Sent Currency",
+		"Received Amount",
+		"Received Currency",
+		"Fee Amount",
+		"Fee Currency",
+		"Net Worth Amount", // Omitted, cost basis is imported from another source
+		"Net Worth Currency",
+		"Label",
+		"Description",
+		"TxHash",
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, m := range opts.movements(xfers) {
+		label := koinlyLabel(m.Transfer)
+		if m.feeOnly {
+			label = "cost"
+		}
+		record := []string{
+			opts.date(opts.localTime(m.Timestamp), time.RFC3339),
+			m.sent,
+			m.sentCurrency,
+			m.received,
+			m.receivedCurrency,
+			m.fee,
+			m.feeCurrency,
+			"",
+			"",
+			label,
+			m.description(),
+			m.MessageID,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// koinlyLabels are the labels Koinly understands on deposits and withdrawals.
+var koinlyLabels = map[flow][]string{
+	flowIn:  {"airdrop", "fork", "mining", "reward", "income", "other income", "lending interest", "cashback", "salary", "staking"},
+	flowOut: {"gift", "lost", "cost", "donation", "interest payment", "margin fee", "realized gain"},
+}
+
+// koinlyLabel labels xfer by the first of its category and tags that is one of
+// koinlyLabels for its flow, otherwise by its kind.
+func koinlyLabel(xfer Transfer) string {
+	for _, s := range append([]string{xfer.Category}, xfer.Tags...) {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != "" && slices.Contains(koinlyLabels[xfer.flow()], s) {
+			return s
+		}
+	}
+	switch xfer.Kind {
+	case KindReward:
+		return "mining"
+	case KindPenalty:
+		return "cost"
+	}
+	return ""
+}
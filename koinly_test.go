@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestKoinlyLabelsFromRules(t *testing.T) {
+	rs, err := loadRules(writeTestFile(t, "rules.yaml", `
+rules:
+  - name: payouts
+    match:
+      direction: in
+      counterparty: `+testOther+`
+    category: Income
+  - name: charity
+    match:
+      direction: out
+    category: donations
+    tags: [Donation]
+  - name: consulting
+    match:
+      direction: in
+    category: consulting
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	xfers := []Transfer{
+		{Wallet: testWallet, Kind: KindTransfer, Timestamp: at, MessageID: "bafy1", From: testOther, To: testWallet, Amount: big.NewInt(1e18)},
+		{Wallet: testWallet, Kind: KindTransfer, Timestamp: at, MessageID: "bafy2", From: testWallet, To: testOther, Amount: big.NewInt(-1e18)},
+		{Wallet: testWallet, Kind: KindTransfer, Timestamp: at, MessageID: "bafy3", From: "f1someoneelse", To: testWallet, Amount: big.NewInt(1e18)},
+		{Wallet: testWallet, Kind: KindReward, Timestamp: at, MessageID: "bafy4", From: "f02", To: testWallet, Amount: big.NewInt(1e18)},
+	}
+	rs.apply(xfers)
+
+	var buf bytes.Buffer
+	if err := writeKoinlyCSV(&buf, xfers, exportOptions{amounts: amountFormat{decimals: -1}}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"income", "donation", "", "mining"}
+	if len(rows) != len(want)+1 {
+		t.Fatalf("got %d rows, want %d: %q", len(rows), len(want)+1, rows)
+	}
+	for i, w := range want {
+		if got := rows[i+1][9]; got != w {
+			t.Errorf("row %d label = %q, want %q", i+1, got, w)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// addressBook maps addresses to human readable names.
+type addressBook map[string]string
+
+// loadAddressBook reads labels from path. A .yaml or .yml file maps addresses
+// to labels; anything else is read as CSV rows of address,label, with an
+// optional header.
+func loadAddressBook(path string) (addressBook, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]string)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		r := csv.NewReader(strings.NewReader(string(b)))
+		r.FieldsPerRecord = 2
+		r.Comment = '#'
+		rows, err := r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for i, row := range rows {
+			if i == 0 && strings.EqualFold(row[0], "address") {
+				continue
+			}
+			raw[row[0]] = row[1]
+		}
+	}
+
+	book := make(addressBook, len(raw))
+	for addr, label := range raw {
+		addr, err := normalizeAddress(strings.TrimSpace(addr))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		book[addr] = strings.TrimSpace(label)
+	}
+	return book, nil
+}
+
+// apply labels each transfer with the name of its counterparty.
+func (book addressBook) apply(xfers []Transfer) {
+	for i := range xfers {
+		xfers[i].Label = book[xfers[i].counterparty()]
+	}
+}
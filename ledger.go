@@ -0,0 +1,212 @@
+package main
+
+import (
+	"io"
+	"math/big"
+	"strings"
+)
+
+func init() {
+	registerFormat("ledger", format{description: "Ledger Live CSV, the default", ext: ".csv", write: writeLedgerCSV, csv: true, appendKey: "Operation Hash", countervalues: true})
+}
+
+// writeLedgerCSV writes xfers in the CSV layout Ledger Live exports and
+// imports.
+func writeLedgerCSV(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	writer := opts.csvWriter(w)
+	defer writer.Flush()
+
+	// Write CSV header
+	headers := []string{
+		"Operation Date",      // Field 1: "Operation Date", as 2024-09-12T16:19:30.000Z format
+		"Status",              // Field 2: "Status" --> "Confirmed", or "Pending" until the transfer reaches finality
+		"Currency Ticker",     // Field 3: "Currency Ticker" --> "FIL", or the symbol of a token transfer
+		"Operation Type",      // Field 4: "Operation Type" --> ["IN" or "OUT"] based on which side of the transfer the wallet is, or "REWARD"/"PENALTY"/"FREEZE"/"UNFREEZE" for miner income, penalties and pledges, "FEES" for fee-only messages, or "TRANSFER" between own addresses
+		"Operation Amount",    // Field 5: "Operation Amount" --> FIL amount transferred, absolute value
+		"Operation Fees",      // Field 6: "Operation Fees" --> miner fee + burn fees, if any
+		"Operation Hash",      // Field 7: "Opearation Hash" --> the message ID
+		"Account Name",        // Field 8: "Account Name" --> the wallet's config account name, or "Filfox API" followed by the wallet when combined, and suffixed with the sub-account of a miner
+		"Account xpub",        // Field 9: "Account xpub" --> sender or receiver address
+		"Countervalue Ticker", // Field 10: "Countervalue Ticker" --> "USD", or the --countervalue-currency
+		// Field 11: "Countervalue at Operation Date" -> Omitted unless --countervalues, as most users import cost basis from another source rather than rely on a spot exchange rate
+		// Field 12: "Countervalue at CSV Export" -> Omitted alongside Field 11
+	}
+	if opts.countervalues != nil {
+		headers = append(headers, "Countervalue at Operation Date", "Countervalue at CSV Export")
+	}
+	if opts.methods {
+		headers = append(headers, "Method")
+	}
+	if opts.labels != nil {
+		headers = append(headers, "Label")
+	}
+	if opts.rules != nil {
+		headers = append(headers, "Category", "Tags")
+	}
+	if opts.vesting {
+		headers = append(headers, "Note")
+	}
+	if opts.nfts {
+		headers = append(headers, "Token Contract", "Token ID")
+	}
+	if opts.balance {
+		headers = append(headers, "Balance")
+	}
+	if opts.gasColumns {
+		headers = append(headers, "Gas Limit", "Gas Fee Cap", "Gas Premium", "Base Fee Burn", "Exit Code")
+	}
+	if opts.feeColumns {
+		headers = append(headers, "Fee Base Burn", "Fee Overestimation Burn", "Fee Miner Tip")
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	// Write CSV records
+	for _, xfer := range xfers {
+		// Field 1: Operation Date
+		const iso8601WithMillis = "2006-01-02T15:04:05.000Z07:00"
+		operationDate := opts.date(opts.localTime(xfer.Timestamp), iso8601WithMillis)
+
+		// Field 2: Status
+		status := "Confirmed"
+		if xfer.Pending {
+			status = "Pending"
+		}
+
+		// Field 3: Currency Type
+		currencyType := xfer.Ticker()
+
+		// Field 4: Operation Type and Field 9: Account xpub
+		var operationType, accountXpub string
+		switch {
+		case xfer.Kind == KindReward:
+			operationType = "REWARD"
+			accountXpub = xfer.To
+		case xfer.Kind == KindPenalty:
+			operationType = "PENALTY"
+			accountXpub = xfer.From
+		case xfer.Kind == KindPledge && xfer.Amount.Sign() < 0:
+			operationType = "FREEZE"
+			accountXpub = xfer.From
+		case xfer.Kind == KindPledge:
+			operationType = "UNFREEZE"
+			accountXpub = xfer.To
+		case xfer.FeeOnly:
+			operationType = "FEES"
+			accountXpub = xfer.From
+		case xfer.Self:
+			operationType = "TRANSFER"
+			accountXpub = xfer.From
+		case xfer.outgoing(xfer.Wallet):
+			operationType = "OUT"
+			accountXpub = xfer.From
+		default:
+			operationType = "IN"
+			accountXpub = xfer.To
+		}
+		// Field 5: Operation Amount
+		// Needs to be converted to abs value, as Filfox API returns negative values for OUT transactions
+		// On OUT and FEES transactions, Ledger add the totalFee to the amount, so we need to calculate the totalFee first
+		totalFee := new(big.Int)
+		if xfer.MinerFee != nil {
+			totalFee.Add(totalFee, xfer.MinerFee)
+		}
+		if xfer.BurnFee != nil {
+			totalFee.Add(totalFee, xfer.BurnFee)
+		}
+
+		var amount *big.Int
+		if operationType == "OUT" || operationType == "FEES" {
+			amount = new(big.Int).Add(new(big.Int).Abs(xfer.Amount), new(big.Int).Abs(totalFee))
+		} else {
+			amount = new(big.Int).Abs(xfer.Amount)
+		}
+		operationAmount := opts.amounts.format(amount, xfer.decimals())
+
+		// Field 6: Operation Fee
+		// Calculated in previous field
+		operationFee := opts.amounts.format(new(big.Int).Abs(totalFee), 18)
+
+		// Field 7: Operation Hash
+		operationHash := xfer.MessageID
+
+		// Field 8: Account Name
+		accountName := "Filfox API"
+		if name := opts.names[xfer.Wallet]; name != "" {
+			accountName = name
+		} else if opts.combined {
+			// Ledger Live keeps each wallet of a combined export apart by name
+			accountName += " " + xfer.Wallet
+		}
+		if xfer.ToAccount != "" {
+			accountName += " (" + xfer.Account + " -> " + xfer.ToAccount + ")"
+		} else if xfer.Account != "" {
+			accountName += " (" + xfer.Account + ")"
+		}
+
+		// Field 9: Account xpub
+		// Determined alongside the operation type above
+
+		// Field 10: Countervalue Ticker
+		counterValueTicker := "USD"
+		if opts.countervalues != nil {
+			counterValueTicker = opts.countervalues.currency
+		}
+
+		record := []string{
+			operationDate,
+			status,
+			currencyType,
+			operationType,
+			operationAmount,
+			operationFee,
+			operationHash,
+			accountName,
+			accountXpub,
+			counterValueTicker,
+		}
+		if cv := opts.countervalues; cv != nil {
+			// Fields 11 and 12: Countervalues, of FIL only
+			var atDate, atExport string
+			if currencyType == "FIL" {
+				atDate, atExport = opts.fiat(cv.at(amount, xfer.Timestamp)), opts.fiat(cv.now(amount))
+			}
+			record = append(record, atDate, atExport)
+		}
+		if opts.methods {
+			record = append(record, xfer.Method)
+		}
+		if opts.labels != nil {
+			record = append(record, xfer.Label)
+		}
+		if opts.rules != nil {
+			record = append(record, xfer.Category, strings.Join(xfer.Tags, ";"))
+		}
+		if opts.vesting {
+			record = append(record, xfer.Note)
+		}
+		if opts.nfts {
+			var contract, id string
+			if xfer.Token != nil {
+				contract, id = xfer.Token.Contract, xfer.Token.ID
+			}
+			record = append(record, contract, id)
+		}
+		if opts.balance {
+			record = append(record, opts.amounts.format(xfer.Balance, 18))
+		}
+		if opts.gasColumns {
+			record = append(record, gasColumns(xfer)...)
+		}
+		if opts.feeColumns {
+			record = append(record, feeColumns(xfer, opts.amounts)...)
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,58 +1,140 @@
 package main
 
 import (
-	"encoding/csv"
-	"encoding/json"
+	"cmp"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"log"
 	"log/slog"
 	"maps"
 	"math/big"
-	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"slices"
+	"strconv"
+	"syscall"
+	"text/template"
 	"time"
-)
 
-var (
-	ApiEndpoint = "https://filfox.info/api/v1"
+	"github.com/mroth/filfoxy/pkg/filfox"
+	"github.com/mroth/filfoxy/pkg/price"
+	"github.com/mroth/filfoxy/pkg/source"
 )
 
 var (
 	attoFIL = big.NewInt(1e18)
 )
 
-type APITransactionsResponse struct {
-	TotalCount int                 `json:"totalCount"`
-	Transfers  []APITransferRecord `json:"transfers"`
-	Types      []string            `json:"types"`
-}
+// Kind classifies what a Transfer represents, beyond its direction.
+type Kind string
+
+const (
+	KindTransfer Kind = "transfer" // an ordinary send or receive
+	KindReward   Kind = "reward"   // block reward income for a miner
+	KindPenalty  Kind = "penalty"  // fault, termination or consensus fees burnt from a miner
+	KindPledge   Kind = "pledge"   // collateral locked or released within a miner's own balance
+	KindToken    Kind = "token"    // an FEVM token transfer, with Token set
+	KindNFT      Kind = "nft"      // a zero-value ERC-721 transfer, with Token and its ID set
+	KindInternal Kind = "internal" // FIL moved by a contract's internal call
+	KindOther    Kind = "other"    // a record of a type filfoxy doesn't know, with RecordType set
+)
 
-type APITransferRecord struct {
-	Height    int    `json:"height"`
-	Timestamp int    `json:"timestamp"`
-	Message   string `json:"message"`
-	From      string `json:"from"`
-	To        string `json:"to"`
-	Value     string `json:"value"` // in attoFIL as a string
-	Type      string `json:"type"`  // [send, receive, miner-fee, burn-fee]
+// Token identifies the FEVM token moved by a Transfer of KindToken or KindNFT.
+type Token struct {
+	Contract string `json:"contract"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+	ID       string `json:"id,omitempty"` // the NFT's token ID
 }
 
 type Transfer struct {
-	Height    int       `json:"height"`
-	Timestamp time.Time `json:"timestamp"`
-	MessageID string    `json:"message_id"`
-	From      string    `json:"from"`
-	To        string    `json:"to"`
-	Amount    *big.Int  `json:"amount"`
-	MinerFee  *big.Int  `json:"miner_fee"`
-	BurnFee   *big.Int  `json:"burn_fee"`
+	Wallet     string    `json:"wallet"` // the exported address whose history Transfer is from
+	Kind       Kind      `json:"kind"`
+	Height     int       `json:"height"`
+	Timestamp  time.Time `json:"timestamp"`
+	MessageID  string    `json:"message_id"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	Amount     *big.Int  `json:"amount"` // in attoFIL, or the smallest unit of Token
+	Token      *Token    `json:"token,omitempty"`
+	RecordType string    `json:"record_type,omitempty"` // the backend's type, for KindOther
+	FeeOnly    bool      `json:"fee_only,omitempty"`    // a message that paid fees but moved no value, often because it failed
+	Self       bool      `json:"self,omitempty"`        // moved value between the user's own addresses
+	MinerFee   *big.Int  `json:"miner_fee"`
+	BurnFee    *big.Int  `json:"burn_fee"`
+
+	// Populated only when messages are listed
+	Method string `json:"method,omitempty"`
+
+	// The counterparty's name from the address book, if any
+	Label string `json:"label,omitempty"`
+
+	// Assigned by the rules file, if any
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+
+	// Set when Transfer is within finality of the chain head, and could
+	// still be reverted by a reorg
+	Pending bool `json:"pending,omitempty"`
+
+	// The sub-account Transfer belongs to, when exporting a miner's accounts,
+	// and for a self-transfer the one it was paired with arriving in
+	Account   string `json:"account,omitempty"`
+	ToAccount string `json:"to_account,omitempty"`
+	ToWallet  string `json:"to_wallet,omitempty"`
+
+	// Wallet's balance after Transfer, when running balances are computed
+	Balance *big.Int `json:"balance,omitempty"`
+
+	// Populated only when vesting unlocks are annotated
+	Note string `json:"note,omitempty"`
+
+	// Populated only when message details are fetched
+	GasLimit    int64    `json:"gas_limit,omitempty"`
+	GasFeeCap   *big.Int `json:"gas_fee_cap,omitempty"`
+	GasPremium  *big.Int `json:"gas_premium,omitempty"`
+	BaseFeeBurn *big.Int `json:"base_fee_burn,omitempty"`
+	ExitCode    *int     `json:"exit_code,omitempty"`
+
+	OverEstimationBurn *big.Int `json:"over_estimation_burn,omitempty"`
+	MinerTip           *big.Int `json:"miner_tip,omitempty"`
+}
+
+// Ticker is the currency t's Amount is denominated in.
+func (t Transfer) Ticker() string {
+	if t.Token != nil {
+		return t.Token.Symbol
+	}
+	return "FIL"
+}
+
+// units converts an amount in t's smallest unit into whole units of its
+// currency.
+func (t Transfer) units(v *big.Int) *big.Float {
+	if t.Token != nil {
+		return scaleAmount(v, t.Token.Decimals)
+	}
+	return attoFILToFIL(v)
+}
+
+// decimals is the number of decimal places of t's amounts' unit.
+func (t Transfer) decimals() int {
+	if t.Token != nil {
+		return t.Token.Decimals
+	}
+	return 18
 }
 
-func (t Transfer) String() string {
-	return fmt.Sprintf("[%s] %s: 📤 %.6s… -> %.6s…, 💸: %9.2f\t| ⛏️: %6v\t| 🔥: %6v",
-		t.Timestamp, t.MessageID, t.From, t.To, attoFILToFIL(t.Amount), t.MinerFee, t.BurnFee)
+// scaleAmount divides v by 10^decimals.
+func scaleAmount(v *big.Int, decimals int) *big.Float {
+	if v == nil {
+		return big.NewFloat(0)
+	}
+	f := new(big.Float).SetInt(v)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return f.Quo(f, new(big.Float).SetInt(scale))
 }
 
 func attoFILToFIL(atto *big.Int) *big.Float {
@@ -65,69 +147,24 @@ func attoFILToFIL(atto *big.Int) *big.Float {
 	return fil
 }
 
-func retrieveTransfers(wallet string) ([]APITransferRecord, error) {
-	var allTransfers []APITransferRecord
-	pageSize := 100
-	page := 0
-
-	for {
-		req, err := http.NewRequest("GET", ApiEndpoint+"/address/"+wallet+"/transfers", nil)
-		if err != nil {
-			return nil, err
-		}
-
-		q := req.URL.Query()
-		q.Add("pageSize", fmt.Sprintf("%d", pageSize))
-		q.Add("page", fmt.Sprintf("%d", page))
-		req.URL.RawQuery = q.Encode()
-
-		slog.Debug("API call", "url", req.URL.String())
-		client := http.DefaultClient
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("API call returned non-success code: %s", resp.Status)
-		}
-
-		decoder := json.NewDecoder(resp.Body)
-		var apiResponse APITransactionsResponse
-		err = decoder.Decode(&apiResponse)
-		if err != nil {
-			return nil, err
-		}
-
-		allTransfers = append(allTransfers, apiResponse.Transfers...)
+// mungeTransferRecords groups records by message into Transfers, one per
+// value leg of the message. Records of
+// unknown type abort when strict, and are otherwise kept apart as Transfers of
+// KindOther with a warning.
+func mungeTransferRecords(records []source.Record, strict bool) ([]Transfer, error) {
+	transferSet := make(map[string]Transfer, 0)
+	legs := make(map[string]int) // further value legs seen per message
 
-		// Check if we have retrieved all records
-		if len(allTransfers) >= apiResponse.TotalCount {
-			break
+	// entry returns the Transfer collecting records under key, creating it
+	// from record if this is the first of them
+	entry := func(key string, kind Kind, record source.Record) Transfer {
+		if transfer, found := transferSet[key]; found {
+			return transfer
 		}
-
-		page++
+		return newTransfer(kind, record)
 	}
 
-	return allTransfers, nil
-}
-
-func mungeTransferRecords(records []APITransferRecord) ([]Transfer, error) {
-	transferSet := make(map[string]Transfer, 0)
-
 	for _, record := range records {
-		// If first time we've seen this message, create a new Transfer
-		transfer, found := transferSet[record.Message]
-		if !found {
-			transfer.Height = record.Height
-			transfer.Timestamp = time.Unix(int64(record.Timestamp), 0).UTC()
-			transfer.MessageID = record.Message
-			transfer.From = record.From
-			transfer.To = record.To
-			transferSet[record.Message] = transfer
-		}
-
 		// Parse the amount and assign it to the Transfer
 		value, ok := new(big.Int).SetString(record.Value, 10)
 		if !ok {
@@ -136,179 +173,817 @@ func mungeTransferRecords(records []APITransferRecord) ([]Transfer, error) {
 
 		switch record.Type {
 		case "send", "receive":
+			transfer := entry(record.Message, KindTransfer, record)
+			if transfer.Amount != nil {
+				// Batch payouts and multisig executions can move value in
+				// several legs. Keep each further leg as its own Transfer,
+				// leaving the fees with the first.
+				legs[record.Message]++
+				key := fmt.Sprintf("%s#%d", record.Message, legs[record.Message])
+				transfer = newTransfer(KindTransfer, record)
+				transfer.Amount = value
+				transferSet[key] = transfer
+				continue
+			}
+			// The message's fee records may have come first; the leg's
+			// counterparties are the ones that matter
+			transfer.From, transfer.To = record.From, record.To
 			transfer.Amount = value
 			transferSet[record.Message] = transfer
 		case "burn-fee":
+			transfer := entry(record.Message, KindTransfer, record)
 			transfer.BurnFee = value
 			transferSet[record.Message] = transfer
 		case "miner-fee":
+			transfer := entry(record.Message, KindTransfer, record)
 			transfer.MinerFee = value
 			transferSet[record.Message] = transfer
+		case "burn", "penalty":
+			// Penalties are kept apart from the message's own transfer, so
+			// they aren't mistaken for its amount or ordinary burn fee
+			key := record.Message + "/penalty"
+			penalty := entry(key, KindPenalty, record)
+			if penalty.Amount == nil {
+				penalty.Amount = new(big.Int)
+			}
+			penalty.Amount.Sub(penalty.Amount, value.Abs(value))
+			transferSet[key] = penalty
 		default:
-			return nil, fmt.Errorf("Unknown transfer type: %s", record.Type)
+			if strict {
+				return nil, fmt.Errorf("Unknown transfer type: %s", record.Type)
+			}
+			slog.Warn("Keeping record of unknown transfer type as other", "type", record.Type, "message", record.Message)
+			key := record.Message + "/" + record.Type
+			other := entry(key, KindOther, record)
+			if other.Amount == nil {
+				other.RecordType = record.Type
+				other.Amount = new(big.Int)
+			}
+			other.Amount.Add(other.Amount, value)
+			transferSet[key] = other
 		}
 	}
 
-	// verification -- make sure all transfers have amount field set
-	for _, transfer := range transferSet {
+	// Messages that failed, or moved no value, only paid fees. Record them as
+	// zero-amount transfers rather than losing the fees.
+	for key, transfer := range transferSet {
 		if transfer.Amount == nil {
-			return nil, fmt.Errorf("Transfer %s is missing amount fields", transfer.MessageID)
+			if transfer.MinerFee == nil && transfer.BurnFee == nil {
+				return nil, fmt.Errorf("Transfer %s is missing amount fields", transfer.MessageID)
+			}
+			transfer.Amount = new(big.Int)
+			transfer.FeeOnly = true
+			transferSet[key] = transfer
 		}
 	}
 
-	xfers := slices.Collect(maps.Values(transferSet))
-	slices.SortFunc(xfers, func(a, b Transfer) int {
+	// Collect in key order first, so a message's legs stay in a stable order
+	xfers := make([]Transfer, 0, len(transferSet))
+	for _, key := range slices.Sorted(maps.Keys(transferSet)) {
+		xfers = append(xfers, transferSet[key])
+	}
+	slices.SortStableFunc(xfers, func(a, b Transfer) int {
 		return b.Timestamp.Compare(a.Timestamp)
 	})
 	return xfers, nil
 }
 
-// Write a Ledger style CSV file
-func writeLedgerCSV(w io.Writer, xfers []Transfer) error {
-	writer := csv.NewWriter(w)
-	defer writer.Flush()
-
-	// Write CSV header
-	headers := []string{
-		"Operation Date",      // Field 1: "Operation Date", as 2024-09-12T16:19:30.000Z format
-		"Status",              // Field 2: "Status" --> hard code to "Confirmed" (for now, can check height later, but not necessary for my use case)
-		"Currency Ticker",     // Field 3: "Currency Ticker" --> hard code to "FIL"
-		"Operation Type",      // Field 4: "Operation Type" --> ["IN" or "OUT"] based on transfer direction
-		"Operation Amount",    // Field 5: "Operation Amount" --> FIL amount transferred, absolute value
-		"Operation Fees",      // Field 6: "Operation Fees" --> miner fee + burn fees, if any
-		"Operation Hash",      // Field 7: "Opearation Hash" --> the message ID
-		"Account Name",        // Field 8: "Account Name" --> hard code to "Filfox API"
-		"Account xpub",        // Field 9: "Account xpub" --> sender or receiver address
-		"Countervalue Ticker", // Field 10: "Countervalue Ticker" --> hard code to "USD"
-		// Field 11: "Countervalue at Operation Date" -> Omitted, we want to import cost basis from another source rather than rely on Filfox's spot exchange rate
-		// Field 12: "Countervalue at CSV Export" -> Omitted, not valuable for this use case
-	}
-	if err := writer.Write(headers); err != nil {
-		return err
+// newTransfer starts a Transfer of kind from the fields shared by all of a
+// message's records.
+func newTransfer(kind Kind, record source.Record) Transfer {
+	return Transfer{
+		Kind:      kind,
+		Height:    record.Height,
+		Timestamp: time.Unix(record.Timestamp, 0).UTC(),
+		MessageID: record.Message,
+		From:      record.From,
+		To:        record.To,
 	}
+}
 
-	// Write CSV records
-	for _, xfer := range xfers {
-		// Field 1: Operation Date
-		const iso8601WithMillis = "2006-01-02T15:04:05.000Z"
-		operationDate := xfer.Timestamp.Format(iso8601WithMillis)
+// exportOptions controls optional parts of the exported file.
+type exportOptions struct {
+	methods    bool // retrieve messages to decode methods, appending a Method column
+	gasColumns bool // append gas breakdown columns from message details
+	feeColumns bool // append the fee split into burns and miner tip from message details
+	rewards    bool // include block rewards earned by a miner address
+	pledges    bool // include collateral locks and releases of a miner address
+	vesting    bool // annotate vested unlocks of a multisig, appending a Note column
+	tokens     bool // include FEVM token transfers of f410 addresses
+	nfts       bool // include NFT transfers of f410 addresses, appending contract and token ID columns
+	internal   bool // include FIL moved by contract internal calls
+	resolveIDs bool // replace f0 ID addresses with their robust form
+
+	minerAccounts bool // include the owner, worker and beneficiary of a miner address
+	confirmedOnly bool // drop transfers that haven't reached finality
+	skipFailed    bool // drop fee-only transfers of failed or valueless messages
+	dropReplaced  bool // drop messages replaced by another with the same nonce
+
+	own []string // further addresses of the user's, besides wallet
+
+	from, to time.Time          // only export transfers in [from, to), if set
+	heights  source.HeightRange // only export transfers at these epochs, if set
+
+	minAmount *big.Int    // drop incoming FIL transfers below this many attoFIL, if set
+	spam      *spamFilter // drop spam transfers, if set
+	filter    *typeFilter // only keep transfers of these directions and types, if set
+	selected  bool        // only the transfers picked in the tui are exported
+
+	labels addressBook // label counterparties, appending a Label column, if set
+	rules  *ruleSet    // categorise and tag transfers, appending Category and Tags columns, if set
+
+	balance bool // append a running Balance column and reconcile it against the chain
+
+	format         string             // key of formats to write; ledger if empty
+	output         string             // path to write to, - for stdout; named after the wallet if empty
+	outputTemplate *template.Template // names the path to write to instead, if set
+	overwrite      bool               // replace an existing output file
+	appending      bool               // add the transfers an existing output file lacks to it
+	combined       bool               // several wallets are exported into one file, each its own account
+	names          map[string]string  // config account names of wallets, by address
+	compress       string             // key of compressions to compress the output with, if set
+	delimiter      rune               // separates the fields of CSV formats; a comma if zero
+	dateFormat     string             // key of dateFormats or layout of CSV format dates; each format's own if empty
+	custom         *customFormat      // layout of the custom format, if set
+	location       *time.Location     // zone of exported dates; UTC if nil
+	amounts        amountFormat       // precision of exported amounts
+	table          tableStyle         // how transfers are printed to the terminal
+
+	accounts          journalAccounts    // account names of double-entry formats
+	balanceAssertions bool               // look up on-chain balances to assert in double-entry formats
+	assertions        []balanceAssertion // set from balanceAssertions while exporting
+
+	openings map[string]*big.Int // FIL balance of each wallet at from, set while exporting formats that want it
+
+	prices               price.Source   // values transfers in countervalueCurrency, if set
+	countervalueCurrency string         // such as USD
+	countervalues        *countervalues // set from prices while exporting
+}
 
-		// Field 2: Status
-		status := "Confirmed"
+// fetchTransfers retrieves the transfer history of wallet from src and munges
+// it into Transfers.
+func fetchTransfers(ctx context.Context, src source.TransferSource, wallet string, opts fetchOptions) ([]Transfer, error) {
+	slog.Info("Retrieving transactions", "wallet", wallet, "backend", src.Name())
+	xferRecs, err := src.Transfers(ctx, wallet)
+	if err != nil {
+		if errors.Is(err, filfox.ErrNotFound) {
+			return nil, fmt.Errorf("Wallet %s %w on %s: check the address for typos", wallet, filfox.ErrNotFound, src.Name())
+		}
+		if opts.backend == "filfox" && opts.resume {
+			return nil, fmt.Errorf("%w (rerun to continue from the last completed page)", err)
+		}
+		return nil, err
+	}
 
-		// Field 3: Currency Type
-		currencyType := "FIL"
+	slog.Info("Received transactions, munging", "count", len(xferRecs))
+	xfers, err := mungeTransferRecords(xferRecs, opts.strict)
+	if err != nil {
+		return nil, err
+	}
 
-		// Field 4: Operation Type and Field 9: Account xpub
-		var operationType, accountXpub string
-		if xfer.Amount.Cmp(big.NewInt(0)) > 0 {
-			operationType = "IN"
-			accountXpub = xfer.To
-		} else {
-			operationType = "OUT"
-			accountXpub = xfer.From
+	slog.Info("Munged into transfers", "count", len(xfers))
+	normalizeSigns(xfers, wallet)
+	for i := range xfers {
+		xfers[i].Wallet = wallet
+	}
+	return xfers, nil
+}
+
+// bridgeTransfers retrieves the transfers of wallet after a balance sample at
+// height and before the epochs of opts.heights, which fetching only those
+// left out, so that an opening balance can be derived from the sample.
+func bridgeTransfers(ctx context.Context, wallet string, height int, opts fetchOptions) ([]Transfer, error) {
+	if opts.heights.From <= height+1 {
+		return nil, nil
+	}
+	opts.heights = source.HeightRange{From: height + 1, To: opts.heights.From - 1}
+	opts.resume = false
+	src, err := newSource(wallet, opts)
+	if err != nil {
+		return nil, err
+	}
+	return fetchTransfers(ctx, src, wallet, opts)
+}
+
+// checkpointPath is where fetch progress for wallet is saved between runs.
+func checkpointPath(wallet string) string {
+	return filepath.Join(os.TempDir(), "filfoxy-"+wallet+".checkpoint")
+}
+
+// gasColumns formats the optional message detail fields of xfer, leaving them
+// blank when they weren't fetched.
+func gasColumns(xfer Transfer) []string {
+	str := func(v *big.Int) string {
+		if v == nil {
+			return ""
 		}
-		// Field 5: Operation Amount
-		// Needs to be converted to abs value, as Filfox API returns negative values for OUT transactions
-		// On OUT transactions, Ledger add the totalFee to the amount, so we need to calculate the totalFee first
-		totalFee := new(big.Int)
-		if xfer.MinerFee != nil {
-			totalFee.Add(totalFee, xfer.MinerFee)
+		return v.String()
+	}
+	var gasLimit, exitCode string
+	if xfer.GasLimit != 0 {
+		gasLimit = strconv.FormatInt(xfer.GasLimit, 10)
+	}
+	if xfer.ExitCode != nil {
+		exitCode = strconv.Itoa(*xfer.ExitCode)
+	}
+	return []string{gasLimit, str(xfer.GasFeeCap), str(xfer.GasPremium), str(xfer.BaseFeeBurn), exitCode}
+}
+
+// feeColumns formats the fees xfer paid split into the base fee and
+// overestimation burns and the miner tip, in FIL. They are blank for
+// transfers that paid no fees, such as receipts.
+func feeColumns(xfer Transfer, af amountFormat) []string {
+	if xfer.MinerFee == nil && xfer.BurnFee == nil {
+		return []string{"", "", ""}
+	}
+	str := func(v *big.Int) string {
+		if v == nil {
+			return ""
+		}
+		return af.format(v, 18)
+	}
+	return []string{str(xfer.BaseFeeBurn), str(xfer.OverEstimationBurn), str(xfer.MinerTip)}
+}
+
+func main() {
+	registerExportFormats()
+	var flags cliFlags
+	flags.register(flag.CommandLine)
+	flag.Usage = usage
+	configFile, explicit := configPath(os.Args[1:])
+	cfg, err := applyConfig(flag.CommandLine, configFile, explicit)
+	if err != nil {
+		fatal(err)
+	}
+	walletNames := make(map[string]string) // the reverse of walletAliases
+	for _, name := range slices.Sorted(maps.Keys(cfg.accounts)) {
+		addr, err := normalizeAddress(cfg.accounts[name])
+		if err != nil {
+			fatal(fmt.Errorf("config account %s: %w", name, err))
 		}
-		if xfer.BurnFee != nil {
-			totalFee.Add(totalFee, xfer.BurnFee)
+		cfg.accounts[name] = addr
+		if _, ok := walletNames[addr]; !ok {
+			walletNames[addr] = name
+		}
+	}
+	walletAliases = cfg.accounts
+	flag.Parse()
+
+	// Flags may follow the command too, as in filfoxy export --format koinly <wallet>
+	command, args := "export", flag.Args()
+	if flag.NArg() < 1 && len(cfg.wallets) == 0 && flags.walletsFile == "" {
+		if !flags.prompt || !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+			flag.Usage()
+			os.Exit(exitUsage)
+		}
+		wallet, chosen, err := promptExport(os.Stdin, os.Stderr, walletNames, flags.format)
+		if err != nil {
+			fatal(err)
 		}
+		args, flags.format = []string{wallet}, chosen
+	}
+	if c, ok := findCommand(flag.Arg(0)); ok {
+		command, args = c.name, args[1:]
+		if !c.ownFlags {
+			flag.CommandLine.Parse(args)
+			args = flag.Args()
+		}
+	}
+	errorJSON = flags.errorJSON
+	if command == "report" {
+		set := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["format"] {
+			flags.format = "markdown"
+		}
+		if !set["output"] && !set["o"] && !set["output-template"] {
+			flags.output = "-"
+		}
+	}
+	if flags.offline && flags.fixtures == "" {
+		fatal(usageError{errors.New("--offline requires --fixtures")})
+	}
+	if !flags.offline {
+		flags.fixtures = ""
+	}
 
-		var amount *big.Int
-		if operationType == "OUT" {
-			amount = new(big.Int).Add(new(big.Int).Abs(xfer.Amount), new(big.Int).Abs(totalFee))
-		} else {
-			amount = new(big.Int).Abs(xfer.Amount)
+	logger, err := newLogger(os.Stderr, flags.logLevel, flags.logFormat)
+	if err != nil {
+		fatal(usageError{err})
+	}
+	slog.SetDefault(logger)
+
+	// Abort in-flight API calls cleanly on Ctrl-C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts, err := flags.fetchOptions()
+	if err != nil {
+		fatal(err)
+	}
+	if flags.progress {
+		opts.progress = newProgressPrinter(os.Stderr).update
+	}
+
+	switch command {
+	case "formats":
+		err = runFormats(os.Stdout)
+	case "completion":
+		err = runCompletion(os.Stdout, args)
+	case "version":
+		err = runVersion(os.Stdout)
+	case "status":
+		err = runStatus(ctx, os.Stdout, opts)
+	case "balance":
+		err = runBalance(ctx, os.Stdout, args, opts)
+	case "pending":
+		err = runPending(ctx, os.Stdout, args, opts)
+	case "vesting":
+		err = runVesting(ctx, os.Stdout, args, opts)
+	case "serve":
+		var eopts exportOptions
+		if eopts, err = exportOptionsFor(&flags, nil, walletNames, opts); err != nil {
+			err = usageError{err}
+			break
+		}
+		err = runServe(ctx, flags.listen, opts, eopts)
+	default: // export, report, fetch, watch and tui
+		if len(args) == 0 && flags.walletsFile == "" {
+			args = cfg.wallets
+		}
+		if len(args) == 0 && flags.walletsFile == "" {
+			c, _ := findCommand(command)
+			err = usageError{fmt.Errorf("usage: %s [flags] %s", c.name, c.args)}
+			break
+		}
+		var wallets []string
+		if wallets, err = walletArgs(args, flags.walletsFile, os.Stdin); err != nil {
+			break
 		}
-		// For formatting float64 to here, only use enough precision as necessary, but allow up to 18 digits of precision
-		_amount := attoFILToFIL(amount)
-		operationAmount := _amount.Text('f', -1)
+		var eopts exportOptions
+		if eopts, err = exportOptionsFor(&flags, wallets, walletNames, opts); err != nil {
+			err = usageError{err}
+			break
+		}
+		switch {
+		case command == "fetch":
+			err = runFetch(ctx, os.Stdout, wallets, opts, eopts)
+		case command == "watch":
+			err = runWatch(ctx, os.Stdout, wallets, opts, eopts, flags.interval)
+		case command == "tui" && len(wallets) > 1 && !flags.combine:
+			err = usageError{errors.New("tui browses several wallets together, so needs --combine")}
+		case command == "tui":
+			err = runTUI(ctx, wallets, opts, eopts)
+		case flags.combine || len(wallets) == 1:
+			err = runExport(ctx, wallets, opts, eopts)
+		default:
+			err = runExports(ctx, wallets, opts, eopts)
+		}
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
 
-		// Field 6: Operation Fee
-		// Calculated in previous field
-		_fee := attoFILToFIL(new(big.Int).Abs(totalFee))
-		operationFee := _fee.Text('f', -1)
+// collectTransfers gathers the transfers of wallets, newest first, with the
+// balance assertions and countervalues eopts asks for. src is the first
+// wallet's, for checking balances.
+func collectTransfers(ctx context.Context, wallets []string, opts fetchOptions, eopts *exportOptions) (xfers []Transfer, src source.TransferSource, err error) {
+	for _, wallet := range wallets {
+		wxfers, wsrc, err := gatherTransfers(ctx, wallet, opts, eopts)
+		if err != nil {
+			return nil, nil, err
+		}
+		xfers = append(xfers, wxfers...)
+		if src == nil {
+			src = wsrc
+		}
+	}
 
-		// Field 7: Operation Hash
-		operationHash := xfer.MessageID
+	if len(wallets) > 1 {
+		// Each side of a transfer between the wallets is in its own history
+		xfers = pairInternalTransfers(xfers)
+	}
 
-		// Field 8: Account Name
-		accountName := "Filfox API"
+	// Interleave any rewards, pledges, token and internal transfers, and
+	// the wallets, by time
+	slices.SortStableFunc(xfers, func(a, b Transfer) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+	slices.SortStableFunc(eopts.assertions, func(a, b balanceAssertion) int {
+		return a.time.Compare(b.time)
+	})
 
-		// Field 9: Account xpub
-		if xfer.Amount.Cmp(big.NewInt(0)) > 0 {
-			accountXpub = xfer.To
-		} else {
-			accountXpub = xfer.From
+	if eopts.prices != nil {
+		if eopts.countervalues, err = lookupCountervalues(ctx, eopts.prices, eopts.countervalueCurrency, xfers); err != nil {
+			return nil, nil, err
 		}
+	}
+	return xfers, src, nil
+}
 
-		// Field 10: Countervalue Ticker
-		counterValueTicker := "USD"
+// runExport retrieves the transfer histories of wallets and writes them
+// together in the selected format to the --output file or stdout.
+func runExport(ctx context.Context, wallets []string, opts fetchOptions, eopts exportOptions) error {
+	xfers, src, err := collectTransfers(ctx, wallets, opts, &eopts)
+	if err != nil {
+		return err
+	}
+	return writeExport(ctx, wallets, xfers, src, eopts)
+}
+
+// writeExport writes the transfers collected from wallets, reconciling them
+// and recording the range exported as eopts asks.
+func writeExport(ctx context.Context, wallets []string, xfers []Transfer, src source.TransferSource, eopts exportOptions) error {
+	var totals map[string]*big.Int
+	if eopts.balance {
+		totals = runningBalances(xfers)
+	}
 
-		record := []string{
-			operationDate,
-			status,
-			currencyType,
-			operationType,
-			operationAmount,
-			operationFee,
-			operationHash,
-			accountName,
-			accountXpub,
-			counterValueTicker,
+	name := cmp.Or(eopts.format, "ledger")
+	outputFileName, err := eopts.outputPath(wallets, xfers)
+	if err != nil {
+		return err
+	}
+	// The range is recorded beside the file, which a pipe doesn't have. An
+	// existing record is checked for first, so as not to write an export
+	// whose range then can't be.
+	var metaFileName string
+	if (!eopts.from.IsZero() || !eopts.to.IsZero() || !eopts.heights.IsZero()) && outputFileName != "-" {
+		metaFileName = outputFileName + ".meta.json"
+		if err := checkOverwrite(metaFileName, eopts); err != nil {
+			return err
 		}
+	}
 
-		if err := writer.Write(record); err != nil {
+	if outputFileName == "-" {
+		if err := formats[name].writeTo(outputFileName, xfers, eopts); err != nil {
 			return err
 		}
+		slog.Info("Transfers written", "output", "stdout")
+	} else {
+		if err := newTransferTable(os.Stdout, eopts).write(xfers); err != nil {
+			return err
+		}
+		if dir := filepath.Dir(outputFileName); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+		if err := formats[name].writeTo(outputFileName, xfers, eopts); err != nil {
+			return err
+		}
+		slog.Info("Transfers written", "output", outputFileName)
 	}
 
-	return nil
+	var mismatch error
+	if eopts.balance {
+		if bs, ok := source.Find[source.BalanceSource](src); ok {
+			partial := !eopts.from.IsZero() || !eopts.to.IsZero() || !eopts.heights.IsZero() ||
+				eopts.minAmount != nil || eopts.spam != nil || eopts.filter != nil ||
+				eopts.skipFailed || eopts.confirmedOnly || eopts.selected
+			err := reconcile(ctx, os.Stderr, bs, totals, exportedWallets(wallets, xfers), partial)
+			if errors.Is(err, errBalanceMismatch) {
+				mismatch = err // once the range is recorded
+			} else if err != nil {
+				return err
+			}
+		} else {
+			slog.Warn("Backend does not report balances, skipping reconciliation", "backend", src.Name())
+		}
+	}
+
+	if metaFileName != "" {
+		if err := writeExportMetadata(metaFileName, wallets, src.Name(), eopts, len(xfers)); err != nil {
+			return err
+		}
+		slog.Info("Export range recorded", "path", metaFileName)
+	}
+	return mismatch
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <wallet>", os.Args[0])
-		os.Exit(1)
+// runExports exports each of wallets to a file of its own. A wallet that
+// fails doesn't stop the rest being exported, but makes a partialError.
+func runExports(ctx context.Context, wallets []string, opts fetchOptions, eopts exportOptions) error {
+	var failed []error
+	var mismatch error
+	for _, wallet := range wallets {
+		err := runExport(ctx, []string{wallet}, opts, eopts)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, errBalanceMismatch):
+			mismatch = fmt.Errorf("%s: %w", wallet, err)
+		case err != nil:
+			slog.Error("Export failed", "wallet", wallet, "err", err)
+			failed = append(failed, fmt.Errorf("%s: %w", wallet, err))
+		}
 	}
-	wallet := os.Args[1]
+	switch {
+	case len(failed) == len(wallets):
+		return errors.Join(failed...)
+	case len(failed) > 0:
+		return &partialError{exported: len(wallets) - len(failed), failed: failed}
+	}
+	return mismatch
+}
 
-	slog.SetLogLoggerLevel(slog.LevelDebug)
+// gatherTransfers retrieves the transfer history of wallet from its backend,
+// with everything eopts asks to add to it, and filters it down to what is
+// exported. Opening balances and balance assertions are added to eopts.
+func gatherTransfers(ctx context.Context, wallet string, opts fetchOptions, eopts *exportOptions) ([]Transfer, source.TransferSource, error) {
+	if (eopts.rewards || eopts.pledges || eopts.minerAccounts) && !isMinerAddress(wallet) {
+		return nil, nil, errors.New("--rewards, --pledges and --miner-accounts require a miner (f0/f2) address")
+	}
 
-	log.Printf("Retrieving transactions for wallet %s", wallet)
-	xferRecs, err := retrieveTransfers(wallet)
+	src, err := newSource(wallet, opts)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
-
-	log.Printf("Received %d transactions, munging...", len(xferRecs))
-	xfers, err := mungeTransferRecords(xferRecs)
+	xfers, err := fetchTransfers(ctx, src, wallet, opts)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
 
-	log.Printf("Munged into %d transfers", len(xfers))
+	// Addresses that belong to the exporting user, for spotting self-transfers
+	own := append([]string{wallet}, eopts.own...)
 
-	for _, xfer := range xfers {
-		fmt.Println(xfer)
+	if eopts.minerAccounts {
+		accounts, addrs, err := expandMinerAccounts(ctx, src, wallet, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		xfers = append(xfers, accounts...)
+		own = append(own, addrs...)
 	}
 
-	outputFileName := fmt.Sprintf("%s.csv", wallet[:9])
-	file, err := os.Create(outputFileName)
-	if err != nil {
-		log.Fatal(err)
+	var msgs []source.Message
+	if eopts.methods || eopts.dropReplaced {
+		ml, ok := source.Find[source.MessageLister](src)
+		if !ok {
+			return nil, nil, fmt.Errorf("backend %s does not support listing messages", src.Name())
+		}
+		slog.Info("Retrieving messages", "wallet", wallet)
+		if msgs, err = ml.Messages(ctx, wallet); err != nil {
+			return nil, nil, err
+		}
+	}
+	if eopts.dropReplaced {
+		xfers = dropReplaced(xfers, msgs)
 	}
-	defer file.Close()
 
-	err = writeLedgerCSV(file, xfers)
-	if err != nil {
-		log.Fatal(err)
+	if eopts.skipFailed {
+		xfers = slices.DeleteFunc(xfers, func(x Transfer) bool { return x.FeeOnly })
+	}
+
+	if eopts.methods {
+		applyMethods(xfers, msgs)
+	}
+
+	if eopts.gasColumns || eopts.feeColumns {
+		ms, ok := source.Find[source.MessageSource](src)
+		if !ok {
+			return nil, nil, fmt.Errorf("backend %s does not support message details", src.Name())
+		}
+		slog.Info("Retrieving message details", "count", len(xfers))
+		if err := enrichMessageDetails(ctx, ms, xfers, opts.concurrency); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if eopts.vesting {
+		slog.Info("Retrieving vesting schedule", "wallet", wallet)
+		vesting, err := lookupVesting(ctx, wallet, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		annotateVestedUnlocks(xfers, wallet, vesting)
+	}
+
+	if eopts.rewards {
+		rs, ok := source.Find[source.RewardSource](src)
+		if !ok {
+			return nil, nil, fmt.Errorf("backend %s does not support block rewards", src.Name())
+		}
+		slog.Info("Retrieving block rewards", "miner", wallet)
+		rewards, err := rs.BlockRewards(ctx, wallet)
+		if err != nil {
+			return nil, nil, err
+		}
+		rxfers, err := rewardTransfers(wallet, rewards)
+		if err != nil {
+			return nil, nil, err
+		}
+		slog.Info("Received block rewards", "count", len(rxfers))
+		xfers = append(xfers, rxfers...)
+	}
+
+	if eopts.pledges {
+		ps, ok := source.Find[source.PledgeSource](src)
+		if !ok {
+			return nil, nil, fmt.Errorf("backend %s does not support pledge history", src.Name())
+		}
+		slog.Info("Retrieving pledge history", "miner", wallet)
+		samples, err := ps.PledgeHistory(ctx, wallet, eopts.from)
+		if err != nil {
+			return nil, nil, err
+		}
+		pxfers, err := pledgeTransfers(wallet, samples)
+		if err != nil {
+			return nil, nil, err
+		}
+		slog.Info("Derived pledge movements", "count", len(pxfers))
+		xfers = append(xfers, pxfers...)
 	}
 
-	log.Printf("Transfers written to %s", outputFileName)
+	if eopts.tokens && isEthAccount(wallet) {
+		if ts, ok := source.Find[source.TokenSource](src); ok {
+			slog.Info("Retrieving token transfers", "wallet", wallet)
+			recs, err := ts.TokenTransfers(ctx, wallet)
+			if err != nil {
+				return nil, nil, err
+			}
+			txfers, err := tokenTransfers(wallet, recs)
+			if err != nil {
+				return nil, nil, err
+			}
+			slog.Info("Received token transfers", "count", len(txfers))
+			xfers = append(xfers, txfers...)
+		} else {
+			slog.Warn("Backend does not support token transfers, omitting them", "backend", src.Name())
+		}
+	}
+
+	if eopts.nfts && isEthAccount(wallet) {
+		ns, ok := source.Find[source.NFTSource](src)
+		if !ok {
+			return nil, nil, fmt.Errorf("backend %s does not support NFT transfers", src.Name())
+		}
+		slog.Info("Retrieving NFT transfers", "wallet", wallet)
+		recs, err := ns.NFTTransfers(ctx, wallet)
+		if err != nil {
+			return nil, nil, err
+		}
+		nxfers := nftTransfers(recs)
+		slog.Info("Received NFT transfers", "count", len(nxfers))
+		xfers = append(xfers, nxfers...)
+	}
+
+	if eopts.internal {
+		is, ok := source.Find[source.InternalSource](src)
+		if !ok {
+			return nil, nil, fmt.Errorf("backend %s does not support internal transfers", src.Name())
+		}
+		slog.Info("Retrieving internal transfers", "wallet", wallet)
+		recs, err := is.InternalTransfers(ctx, wallet)
+		if err != nil {
+			return nil, nil, err
+		}
+		ixfers, err := internalTransfers(recs, xfers)
+		if err != nil {
+			return nil, nil, err
+		}
+		slog.Info("Received internal transfers", "count", len(ixfers))
+		xfers = append(xfers, ixfers...)
+	}
+
+	// Everything retrieved beyond the transfers themselves is for wallet
+	for i := range xfers {
+		if xfers[i].Wallet == "" {
+			xfers[i].Wallet = wallet
+		}
+	}
+
+	if eopts.resolveIDs {
+		ar, ok := source.Find[source.AddressResolver](src)
+		if !ok {
+			return nil, nil, fmt.Errorf("backend %s does not support resolving ID addresses", src.Name())
+		}
+		resolver, err := newIDResolver(ar, opts.cacheDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		slog.Info("Resolving ID addresses")
+		if err := resolver.resolveAll(ctx, xfers); err != nil {
+			return nil, nil, err
+		}
+		for _, addr := range own {
+			robust, err := resolver.resolve(ctx, addr)
+			if err != nil {
+				return nil, nil, err
+			}
+			if robust != "" && robust != addr {
+				own = append(own, robust)
+			}
+		}
+		if err := resolver.save(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if eopts.minerAccounts {
+		for i := range xfers {
+			if xfers[i].Account == "" {
+				xfers[i].Account = "miner"
+			}
+		}
+	}
+
+	xfers = markSelfTransfers(xfers, own)
+	xfers = pairInternalTransfers(xfers)
+
+	if hs, ok := source.Find[source.HeadSource](src); ok {
+		head, err := hs.ChainHead(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		xfers = markPending(xfers, head.Height, eopts.confirmedOnly)
+	} else {
+		slog.Warn("Backend does not report the chain head, assuming all transfers are final", "backend", src.Name())
+	}
+
+	if formats[cmp.Or(eopts.format, "ledger")].openings && !eopts.from.IsZero() {
+		// Looked up before the range filter drops the transfers leading to it
+		bh, ok := source.Find[source.BalanceHistorySource](src)
+		if !ok {
+			return nil, nil, fmt.Errorf("backend %s does not report balance history for opening balances", src.Name())
+		}
+		if eopts.openings == nil {
+			eopts.openings = make(map[string]*big.Int)
+		}
+		for _, w := range exportedWallets([]string{wallet}, xfers) {
+			slog.Info("Retrieving balance history", "wallet", w)
+			samples, err := bh.BalanceHistory(ctx, w, eopts.from)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(samples) == 0 {
+				return nil, nil, fmt.Errorf("no balance history of %s for its opening balance", w)
+			}
+			// The fetch began at --from, short of the transfers since the
+			// sample before it
+			bridge, err := bridgeTransfers(ctx, w, sampleBefore(samples, eopts.from).Height, opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			if eopts.openings[w], err = balanceAt(samples, append(slices.Clip(xfers), bridge...), w, eopts.from); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if !eopts.from.IsZero() || !eopts.to.IsZero() || !eopts.heights.IsZero() {
+		xfers = slices.DeleteFunc(xfers, func(x Transfer) bool {
+			return x.Timestamp.Before(eopts.from) || (!eopts.to.IsZero() && !x.Timestamp.Before(eopts.to)) ||
+				!eopts.heights.Contains(x.Height)
+		})
+	}
+
+	if eopts.minAmount != nil {
+		xfers = dropDust(xfers, eopts.minAmount)
+	}
+	if eopts.spam != nil {
+		xfers = eopts.spam.drop(xfers)
+	}
+	if eopts.filter != nil {
+		xfers = slices.DeleteFunc(xfers, func(x Transfer) bool { return !eopts.filter.keep(x) })
+	}
+
+	if eopts.labels != nil {
+		eopts.labels.apply(xfers)
+	}
+	if eopts.rules != nil {
+		eopts.rules.apply(xfers)
+	}
+
+	if eopts.balanceAssertions {
+		bh, ok := source.Find[source.BalanceHistorySource](src)
+		if !ok {
+			return nil, nil, fmt.Errorf("backend %s does not report balance history", src.Name())
+		}
+		for _, w := range exportedWallets([]string{wallet}, xfers) {
+			slog.Info("Retrieving balance history", "wallet", w)
+			samples, err := bh.BalanceHistory(ctx, w, eopts.from)
+			if err != nil {
+				return nil, nil, err
+			}
+			assertions, err := balanceAssertions(samples, xfers, w, *eopts)
+			if err != nil {
+				return nil, nil, err
+			}
+			eopts.assertions = append(eopts.assertions, assertions...)
+		}
+	}
+	return xfers, src, nil
+}
+
+// exportedWallets lists wallets and the other wallets xfers are from.
+func exportedWallets(wallets []string, xfers []Transfer) []string {
+	wallets = slices.Clone(wallets)
+	for _, x := range xfers {
+		if !slices.Contains(wallets, x.Wallet) {
+			wallets = append(wallets, x.Wallet)
+		}
+	}
+	return wallets
 }
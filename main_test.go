@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+func TestMungeTransferRecords(t *testing.T) {
+	const (
+		payer = "f1payer"
+		alice = "f1alice"
+		bob   = "f1bob"
+	)
+	record := func(typ, from, to, value string) source.Record {
+		return source.Record{Height: 100, Timestamp: 1700000000, Message: "bafy1", From: from, To: to, Value: value, Type: typ}
+	}
+	type want struct {
+		kind     Kind
+		from, to string
+		amount   int64
+		minerFee *int64
+		burnFee  *int64
+		feeOnly  bool
+	}
+	fee := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name    string
+		records []source.Record
+		want    []want
+	}{
+		{
+			name: "multi-leg send",
+			records: []source.Record{
+				record("miner-fee", payer, "f0miner", "-1"),
+				record("burn-fee", payer, "f099", "-2"),
+				record("send", payer, alice, "-10"),
+				record("send", payer, bob, "-20"),
+				record("send", payer, alice, "-10"),
+			},
+			want: []want{
+				{kind: KindTransfer, from: payer, to: alice, amount: -10, minerFee: fee(-1), burnFee: fee(-2)},
+				{kind: KindTransfer, from: payer, to: bob, amount: -20},
+				{kind: KindTransfer, from: payer, to: alice, amount: -10},
+			},
+		},
+		{
+			name: "multi-leg receive",
+			records: []source.Record{
+				record("receive", payer, alice, "5"),
+				record("receive", payer, alice, "7"),
+			},
+			want: []want{
+				{kind: KindTransfer, from: payer, to: alice, amount: 5},
+				{kind: KindTransfer, from: payer, to: alice, amount: 7},
+			},
+		},
+		{
+			name: "burn fee only",
+			records: []source.Record{
+				record("burn-fee", payer, "f099", "-2"),
+			},
+			want: []want{
+				{kind: KindTransfer, from: payer, to: "f099", amount: 0, burnFee: fee(-2), feeOnly: true},
+			},
+		},
+		{
+			name: "miner fee only",
+			records: []source.Record{
+				record("miner-fee", payer, "f0miner", "-1"),
+			},
+			want: []want{
+				{kind: KindTransfer, from: payer, to: "f0miner", amount: 0, minerFee: fee(-1), feeOnly: true},
+			},
+		},
+		{
+			name: "burn only",
+			records: []source.Record{
+				record("burn", "f0miner", "f099", "-3"),
+			},
+			want: []want{
+				{kind: KindPenalty, from: "f0miner", to: "f099", amount: -3},
+			},
+		},
+	}
+
+	cmpFee := func(got *big.Int, want *int64) bool {
+		if want == nil {
+			return got == nil
+		}
+		return got != nil && got.Int64() == *want
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xfers, err := mungeTransferRecords(tt.records, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(xfers) != len(tt.want) {
+				t.Fatalf("got %d transfers, want %d: %+v", len(xfers), len(tt.want), xfers)
+			}
+			for i, w := range tt.want {
+				x := xfers[i]
+				if x.Kind != w.kind || x.From != w.from || x.To != w.to || x.Amount.Int64() != w.amount || x.FeeOnly != w.feeOnly {
+					t.Errorf("transfer %d = %s %s->%s %v fee only %v, want %s %s->%s %d fee only %v",
+						i, x.Kind, x.From, x.To, x.Amount, x.FeeOnly, w.kind, w.from, w.to, w.amount, w.feeOnly)
+				}
+				if !cmpFee(x.MinerFee, w.minerFee) || !cmpFee(x.BurnFee, w.burnFee) {
+					t.Errorf("transfer %d fees = %v, %v, want %v, %v", i, x.MinerFee, x.BurnFee, w.minerFee, w.burnFee)
+				}
+			}
+		})
+	}
+}
+
+func TestMungeTransferRecordsUnknownType(t *testing.T) {
+	records := []source.Record{{Message: "bafy1", Value: "1", Type: "mystery"}}
+	if _, err := mungeTransferRecords(records, true); err == nil {
+		t.Error("strict munging accepted an unknown type")
+	}
+	xfers, err := mungeTransferRecords(records, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xfers) != 1 || xfers[0].Kind != KindOther || xfers[0].RecordType != "mystery" {
+		t.Errorf("got %+v, want one KindOther transfer", xfers)
+	}
+}
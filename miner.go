@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+const (
+	rewardActor     = "f02"  // the builtin actor that pays out block rewards
+	burntFundsActor = "f099" // where penalties are sent to be burnt
+)
+
+// isMinerAddress reports whether address could belong to a miner actor, which
+// are only ever addressed by ID (f0) or actor (f2) addresses.
+func isMinerAddress(address string) bool {
+	if len(address) < 2 {
+		return false
+	}
+	switch address[:2] {
+	case "f0", "f2", "t0", "t2":
+		return true
+	}
+	return false
+}
+
+// rewardTransfers represents each block mined by miner as an incoming reward
+// Transfer, keyed by the block CID in place of a message. Any penalty charged
+// against the block is added as a separate outgoing penalty Transfer.
+func rewardTransfers(miner string, rewards []source.BlockReward) ([]Transfer, error) {
+	xfers := make([]Transfer, 0, len(rewards))
+	for _, r := range rewards {
+		amount, ok := new(big.Int).SetString(r.Reward, 10)
+		if !ok {
+			return nil, fmt.Errorf("Failed to parse reward %s for block %s", r.Reward, r.Block)
+		}
+		xfers = append(xfers, Transfer{
+			Kind:      KindReward,
+			Height:    r.Height,
+			Timestamp: time.Unix(r.Timestamp, 0).UTC(),
+			MessageID: r.Block,
+			From:      rewardActor,
+			To:        miner,
+			Amount:    amount,
+		})
+
+		if r.Penalty == "" {
+			continue
+		}
+		penalty, ok := new(big.Int).SetString(r.Penalty, 10)
+		if !ok {
+			return nil, fmt.Errorf("Failed to parse penalty %s for block %s", r.Penalty, r.Block)
+		}
+		if penalty.Sign() == 0 {
+			continue
+		}
+		xfers = append(xfers, Transfer{
+			Kind:      KindPenalty,
+			Height:    r.Height,
+			Timestamp: time.Unix(r.Timestamp, 0).UTC(),
+			MessageID: r.Block,
+			From:      miner,
+			To:        burntFundsActor,
+			Amount:    penalty.Neg(penalty.Abs(penalty)),
+		})
+	}
+	return xfers, nil
+}
+
+// pledgeTransfers turns the changes in miner's locked collateral between
+// consecutive samples into internal pledge Transfers. These move funds
+// between the miner's available and locked balance rather than in or out of
+// it, so the exported ledger reconciles with available balance. Movements
+// within one sample interval are netted together, and the first sample's
+// collateral is locked at it. Having no message, each is identified by its
+// height instead.
+func pledgeTransfers(miner string, samples []source.PledgeSample) ([]Transfer, error) {
+	var xfers []Transfer
+	prev := new(big.Int)
+	for _, s := range samples {
+		locked, ok := new(big.Int).SetString(s.Locked, 10)
+		if !ok {
+			return nil, fmt.Errorf("Failed to parse locked balance %s at height %d", s.Locked, s.Height)
+		}
+		if locked.Cmp(prev) != 0 {
+			// Negative when collateral is locked, as it leaves the available balance
+			xfers = append(xfers, Transfer{
+				Kind:      KindPledge,
+				Height:    s.Height,
+				Timestamp: time.Unix(s.Timestamp, 0).UTC(),
+				MessageID: fmt.Sprintf("pledge-%d", s.Height),
+				From:      miner,
+				To:        miner,
+				Amount:    new(big.Int).Sub(prev, locked),
+			})
+		}
+		prev = locked
+	}
+	return xfers, nil
+}
+
+// expandMinerAccounts retrieves the transfers of miner's owner, worker and
+// beneficiary addresses, labelling each with the roles of its address. It
+// also returns the addresses themselves.
+func expandMinerAccounts(ctx context.Context, src source.TransferSource, miner string, opts fetchOptions) ([]Transfer, []string, error) {
+	mi, ok := source.Find[source.MinerInfoSource](src)
+	if !ok {
+		return nil, nil, fmt.Errorf("backend %s does not support miner info", src.Name())
+	}
+	accounts, err := mi.MinerAccounts(ctx, miner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The same address often fills several roles, e.g. owner and beneficiary
+	var addrs []string
+	roles := make(map[string][]string)
+	for _, a := range []struct{ role, addr string }{
+		{"owner", accounts.Owner},
+		{"worker", accounts.Worker},
+		{"beneficiary", accounts.Beneficiary},
+	} {
+		if a.addr == "" || a.addr == miner {
+			continue
+		}
+		if _, seen := roles[a.addr]; !seen {
+			addrs = append(addrs, a.addr)
+		}
+		roles[a.addr] = append(roles[a.addr], a.role)
+	}
+
+	var xfers []Transfer
+	for _, addr := range addrs {
+		// Each address gets its own source, so checkpoints aren't shared
+		asrc, err := newSource(addr, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		axfers, err := fetchTransfers(ctx, asrc, addr, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		label := strings.Join(roles[addr], "/")
+		for i := range axfers {
+			axfers[i].Account = label
+		}
+		xfers = append(xfers, axfers...)
+	}
+	return xfers, addrs, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+func TestPledgeTransfers(t *testing.T) {
+	samples := []source.PledgeSample{
+		{Height: 100, Timestamp: 1000, Locked: "50"},
+		{Height: 200, Timestamp: 2000, Locked: "50"},
+		{Height: 300, Timestamp: 3000, Locked: "80"},
+		{Height: 400, Timestamp: 4000, Locked: "20"},
+	}
+	xfers, err := pledgeTransfers("f01234", samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id     string
+		amount int64
+	}{
+		{"pledge-100", -50}, // locked before the first sample
+		{"pledge-300", -30},
+		{"pledge-400", 60},
+	}
+	if len(xfers) != len(want) {
+		t.Fatalf("got %d pledge transfers, want %d: %+v", len(xfers), len(want), xfers)
+	}
+	for i, w := range want {
+		x := xfers[i]
+		if x.Kind != KindPledge || x.MessageID != w.id || x.Amount.Int64() != w.amount {
+			t.Errorf("transfer %d = %s %s %v, want pledge %s %d", i, x.Kind, x.MessageID, x.Amount, w.id, w.amount)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+func init() {
+	registerFormat("ofx", format{description: "OFX 2.2 bank statement", ext: ".ofx", write: writeOFX})
+}
+
+// ofxDocument is the subset of an OFX 2.2 bank statement download filfoxy
+// produces.
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	Signon  struct {
+		Status   ofxStatus `xml:"SONRS>STATUS"`
+		DTServer string    `xml:"SONRS>DTSERVER"`
+		Language string    `xml:"SONRS>LANGUAGE"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Statement struct {
+		TrnUID string    `xml:"TRNUID"`
+		Status ofxStatus `xml:"STATUS"`
+		CurDef string    `xml:"STMTRS>CURDEF"`
+		BankID string    `xml:"STMTRS>BANKACCTFROM>BANKID"`
+		AcctID string    `xml:"STMTRS>BANKACCTFROM>ACCTID"`
+		Type   string    `xml:"STMTRS>BANKACCTFROM>ACCTTYPE"`
+		Start  string    `xml:"STMTRS>BANKTRANLIST>DTSTART"`
+		End    string    `xml:"STMTRS>BANKTRANLIST>DTEND"`
+		Trans  []ofxTran `xml:"STMTRS>BANKTRANLIST>STMTTRN"`
+		Bal    string    `xml:"STMTRS>LEDGERBAL>BALAMT"`
+		BalAt  string    `xml:"STMTRS>LEDGERBAL>DTASOF"`
+	} `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxTran struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	FITID  string `xml:"FITID"`
+	Name   string `xml:"NAME"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+// writeOFX writes xfers as an OFX bank statement of the exported wallet, so
+// tools that only import bank accounts can take its FIL history. OFX
+// requires an ISO 4217 currency, so amounts are in FIL under XXX, the code
+// for no currency. Transfers that don't change the FIL balance, such as
+// tokens, are left out, and the ledger balance is their sum, which only
+// matches the chain when the whole history was exported.
+func writeOFX(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+	var doc ofxDocument
+	doc.Signon.Status = ok
+	doc.Signon.DTServer = opts.ofxTime(time.Now())
+	doc.Signon.Language = "ENG"
+	doc.Statement.TrnUID = "0"
+	doc.Statement.Status = ok
+	doc.Statement.CurDef = "XXX"
+	doc.Statement.BankID = "filecoin"
+	doc.Statement.Type = "CHECKING"
+
+	balance := new(big.Int)
+	ids := make(map[string]int) // transfers seen per message
+	var skipped int
+	var first, last time.Time
+	for _, xfer := range xfers {
+		from, to := balanceDelta(xfer)
+		total := new(big.Int).Add(from, to)
+		if total.Sign() == 0 {
+			skipped++
+			continue
+		}
+		balance.Add(balance, total)
+		if doc.Statement.AcctID == "" {
+			doc.Statement.AcctID = xfer.Wallet
+		}
+		if first.IsZero() || xfer.Timestamp.Before(first) {
+			first = xfer.Timestamp
+		}
+		if xfer.Timestamp.After(last) {
+			last = xfer.Timestamp
+		}
+
+		typ := "CREDIT"
+		switch {
+		case xfer.FeeOnly || xfer.flow() == flowNone:
+			typ = "FEE"
+		case xfer.Kind == KindPenalty:
+			typ = "SRVCHG"
+		case xfer.Self:
+			typ = "XFER"
+		case total.Sign() < 0:
+			typ = "DEBIT"
+		}
+		fitid := xfer.MessageID
+		if n := ids[xfer.MessageID]; n > 0 {
+			fitid += "-" + strconv.Itoa(n)
+		}
+		ids[xfer.MessageID]++
+
+		name := cmp.Or(xfer.Label, xfer.counterparty())
+		if r := []rune(name); len(r) > 32 {
+			name = string(r[:32]) // the NAME element's limit, in characters
+		}
+		doc.Statement.Trans = append(doc.Statement.Trans, ofxTran{
+			Type:   typ,
+			Posted: opts.ofxTime(xfer.Timestamp),
+			Amount: opts.amounts.format(total, 18),
+			FITID:  fitid,
+			Name:   name,
+			Memo:   xfer.MessageID,
+		})
+	}
+	if skipped > 0 {
+		slog.Info("Omitted transfers that don't change the FIL balance", "count", skipped)
+	}
+	doc.Statement.Start = opts.ofxTime(first)
+	doc.Statement.End = opts.ofxTime(last)
+	doc.Statement.Bal = opts.amounts.format(balance, 18)
+	doc.Statement.BalAt = opts.ofxTime(last)
+
+	if _, err := io.WriteString(w, xml.Header+
+		`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ofxTime formats t in the export's time zone as an OFX datetime, e.g.
+// 20240131120000.000[+1:CET].
+func (opts exportOptions) ofxTime(t time.Time) string {
+	t = opts.localTime(t)
+	name, offset := t.Zone()
+	hours := strconv.FormatFloat(float64(offset)/3600, 'f', -1, 64)
+	if offset >= 0 {
+		hours = "+" + hours
+	}
+	return fmt.Sprintf("%s[%s:%s]", t.Format("20060102150405.000"), hours, name)
+}
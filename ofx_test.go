@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestOFXTruncatesNamesByCharacter(t *testing.T) {
+	label := strings.Repeat("é", 40)
+	xfers := []Transfer{{Wallet: testWallet, Kind: KindTransfer, Timestamp: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		MessageID: "bafy", From: testOther, To: testWallet, Amount: big.NewInt(1e18), Label: label}}
+	var buf bytes.Buffer
+	if err := writeOFX(&buf, xfers, exportOptions{amounts: amountFormat{decimals: -1}}); err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.Valid(buf.Bytes()) {
+		t.Fatal("OFX is not valid UTF-8")
+	}
+	dec := xml.NewDecoder(&buf)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			t.Fatalf("no NAME in the statement: %v", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "NAME" {
+			var name string
+			if err := dec.DecodeElement(&name, &se); err != nil {
+				t.Fatal(err)
+			}
+			if name != strings.Repeat("é", 32) {
+				t.Errorf("NAME = %q, want the first 32 characters of the label", name)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// outputName is what --output-template sees of an export.
+type outputName struct {
+	Wallet   string   // the exported address, or combined
+	Wallets  []string // every exported address
+	Format   string   // name of the format, e.g. koinly
+	Ext      string   // its extension, including the dot and any compression suffix
+	From, To string   // first and last days covered, as 2006-01-02 in --timezone
+
+	FromHeight, ToHeight int // the --from-height and --to-height epochs, 0 if unset
+}
+
+// parseOutputTemplate parses an --output-template, checking it against a
+// sample export so mistakes surface before anything is fetched.
+func parseOutputTemplate(text string) (*template.Template, error) {
+	t, err := template.New("output").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("--output-template: %w", err)
+	}
+	if err := t.Execute(new(bytes.Buffer), outputName{}); err != nil {
+		return nil, fmt.Errorf("--output-template: %w", err)
+	}
+	return t, nil
+}
+
+// outputPath is where to write the export of wallets' xfers: --output,
+// --output-template filled in, or the wallet's first nine characters (or
+// combined) followed by the format, unless ledger, and its extension.
+func (opts exportOptions) outputPath(wallets []string, xfers []Transfer) (string, error) {
+	name := cmp.Or(opts.format, "ledger")
+	wallet := wallets[0]
+	if len(wallets) > 1 {
+		wallet = "combined"
+	}
+	switch {
+	case opts.output != "":
+		return opts.output, nil
+	case opts.outputTemplate != nil:
+		data := outputName{
+			Wallet:     wallet,
+			Wallets:    wallets,
+			Format:     name,
+			Ext:        formats[name].ext + compressions[opts.compress],
+			FromHeight: opts.heights.From,
+			ToHeight:   opts.heights.To,
+		}
+		if from, to := opts.period(xfers); !from.IsZero() {
+			data.From = opts.localTime(from).Format(time.DateOnly)
+			data.To = opts.localTime(to).Format(time.DateOnly)
+		}
+		var b bytes.Buffer
+		if err := opts.outputTemplate.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("--output-template: %w", err)
+		}
+		if b.Len() == 0 {
+			return "", fmt.Errorf("--output-template named no file for %s", wallet)
+		}
+		return b.String(), nil
+	}
+	path := wallet[:min(len(wallet), 9)]
+	if name != "ledger" {
+		path += "-" + name
+	}
+	return path + formats[name].ext + compressions[opts.compress], nil
+}
+
+// period is the span of time an export of xfers covers: the --from and
+// --to range, or as far as the transfers go where it is unbounded. Both
+// are zero if neither says.
+func (opts exportOptions) period(xfers []Transfer) (from, to time.Time) {
+	from, to = opts.from, opts.to
+	if !to.IsZero() {
+		to = to.Add(-time.Nanosecond) // the range excludes to
+	}
+	for _, x := range xfers {
+		if opts.from.IsZero() && (from.IsZero() || x.Timestamp.Before(from)) {
+			from = x.Timestamp
+		}
+		if opts.to.IsZero() && x.Timestamp.After(to) {
+			to = x.Timestamp
+		}
+	}
+	if from.IsZero() || to.IsZero() {
+		return time.Time{}, time.Time{}
+	}
+	return from, to
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func init() {
+	registerFormat("parquet", format{description: "Parquet file for DuckDB, Spark and the like", ext: ".parquet", write: writeParquet})
+}
+
+// parquetTransfer is a row of the Parquet format. Amounts are DECIMAL(38, 18)
+// in whole units of their currency, token amounts included; fees are always
+// FIL.
+type parquetTransfer struct {
+	Wallet        string   `parquet:"wallet,dict"`
+	Kind          string   `parquet:"kind,dict"`
+	Height        int64    `parquet:"height"`
+	Timestamp     int64    `parquet:"timestamp,timestamp(millisecond)"`
+	MessageID     string   `parquet:"message_id"`
+	From          string   `parquet:"from,dict"`
+	To            string   `parquet:"to,dict"`
+	Amount        [16]byte `parquet:"amount,decimal(18:38)"`
+	Currency      string   `parquet:"currency,dict"`
+	TokenContract string   `parquet:"token_contract,optional,dict"`
+	TokenID       string   `parquet:"token_id,optional"`
+	TokenDecimals int32    `parquet:"token_decimals,optional"`
+	MinerFee      [16]byte `parquet:"miner_fee,optional,decimal(18:38)"`
+	BurnFee       [16]byte `parquet:"burn_fee,optional,decimal(18:38)"`
+	FeeOnly       bool     `parquet:"fee_only"`
+	Self          bool     `parquet:"self"`
+	Pending       bool     `parquet:"pending"`
+	Method        string   `parquet:"method,optional,dict"`
+	Label         string   `parquet:"label,optional,dict"`
+	Category      string   `parquet:"category,optional,dict"`
+	Tags          []string `parquet:"tags,list"`
+	Account       string   `parquet:"account,optional,dict"`
+	ToAccount     string   `parquet:"to_account,optional,dict"`
+	Balance       [16]byte `parquet:"balance,optional,decimal(18:38)"`
+	Note          string   `parquet:"note,optional"`
+	ExitCode      *int32   `parquet:"exit_code,optional"`
+}
+
+// writeParquet writes xfers as a Snappy compressed Parquet file, for loading
+// into DuckDB, Spark and the like.
+func writeParquet(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	rows := make([]parquetTransfer, len(xfers))
+	for i, x := range xfers {
+		var err error
+		decimal := func(v *big.Int, decimals int) [16]byte {
+			b, derr := parquetDecimal(v, decimals)
+			if err == nil {
+				err = derr
+			}
+			return b
+		}
+		r := parquetTransfer{
+			Wallet:    x.Wallet,
+			Kind:      string(x.Kind),
+			Height:    int64(x.Height),
+			Timestamp: x.Timestamp.UnixMilli(),
+			MessageID: x.MessageID,
+			From:      x.From,
+			To:        x.To,
+			Amount:    decimal(x.Amount, x.decimals()),
+			Currency:  x.Ticker(),
+			MinerFee:  decimal(x.MinerFee, 18),
+			BurnFee:   decimal(x.BurnFee, 18),
+			FeeOnly:   x.FeeOnly,
+			Self:      x.Self,
+			Pending:   x.Pending,
+			Method:    x.Method,
+			Label:     x.Label,
+			Category:  x.Category,
+			Tags:      x.Tags,
+			Account:   x.Account,
+			ToAccount: x.ToAccount,
+			Balance:   decimal(x.Balance, 18),
+			Note:      x.Note,
+		}
+		if err != nil {
+			return fmt.Errorf("message %s: %s %w; --skip-spam omits zero-value airdrops, and --spam-senders others", x.MessageID, x.Ticker(), err)
+		}
+		if x.Token != nil {
+			r.TokenContract, r.TokenID, r.TokenDecimals = x.Token.Contract, x.Token.ID, int32(x.Token.Decimals)
+		}
+		if x.ExitCode != nil {
+			code := int32(*x.ExitCode)
+			r.ExitCode = &code
+		}
+		rows[i] = r
+	}
+
+	pw := parquet.NewGenericWriter[parquetTransfer](w, parquet.Compression(&parquet.Snappy))
+	if _, err := pw.Write(rows); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// maxParquetDecimal bounds the unscaled values of DECIMAL(38, 18).
+var maxParquetDecimal = new(big.Int).Exp(big.NewInt(10), big.NewInt(38), nil)
+
+// parquetDecimal encodes v, in units of 10^-decimals, as the big-endian two's
+// complement of its value at scale 18. Amounts of tokens with more decimals
+// are truncated. Values beyond the 38 digits of precision, such as the
+// near-2^256 amounts of spam airdrops, are an error.
+func parquetDecimal(v *big.Int, decimals int) ([16]byte, error) {
+	var b [16]byte
+	if v == nil {
+		return b, nil
+	}
+	n := new(big.Int).Set(v)
+	if decimals < 18 {
+		n.Mul(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(18-decimals)), nil))
+	} else if decimals > 18 {
+		n.Quo(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals-18)), nil))
+	}
+	if new(big.Int).Abs(n).Cmp(maxParquetDecimal) >= 0 {
+		return b, fmt.Errorf("amount of %s units exceeds the precision of DECIMAL(38, 18)", v)
+	}
+	if n.Sign() < 0 {
+		// Two's complement: 2^128 + n
+		n.Add(n, new(big.Int).Lsh(big.NewInt(1), 128))
+	}
+	n.FillBytes(b[:])
+	return b, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParquetDecimal(t *testing.T) {
+	huge, _ := new(big.Int).SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935", 10)
+	tests := []struct {
+		name     string
+		v        *big.Int
+		decimals int
+		want     int64 // low 8 bytes, big-endian
+		err      bool
+	}{
+		{"FIL", big.NewInt(5), 18, 5, false},
+		{"negative", big.NewInt(-1), 18, -1, false},
+		{"fewer decimals", big.NewInt(3), 6, 3_000_000_000_000, false},
+		{"near 2^256 airdrop", huge, 18, 0, true},
+		{"0-decimal token above 1e20", new(big.Int).Exp(big.NewInt(10), big.NewInt(21), nil), 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := parquetDecimal(tt.v, tt.decimals)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				return
+			}
+			if got := int64(new(big.Int).SetBytes(b[8:]).Uint64()); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
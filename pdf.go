@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+func init() {
+	registerFormat("pdf", format{description: "PDF account statement per wallet", ext: ".pdf", write: writePDF, openings: true})
+}
+
+// pdfColumns are the columns of a statement's transfer list, in millimetres
+// across a landscape A4 page.
+var pdfColumns = []struct {
+	name  string
+	width float64
+	align string
+}{
+	{"Date", 30, "L"},
+	{"Description", 85, "L"},
+	{"Message", 32, "L"},
+	{"Out", 32, "R"},
+	{"In", 32, "R"},
+	{"Fee (FIL)", 32, "R"},
+	{"Balance (FIL)", 34, "R"},
+}
+
+// writePDF writes a bank style statement for each wallet of xfers, each
+// starting on a new page: its opening FIL balance, every transfer in or out
+// of it with the running balance, and the totals in, out and paid in fees
+// that make up its closing balance. Opening balances come from
+// opts.openings, zero for wallets without one.
+func writePDF(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetTitle("Filecoin account statement", true)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+	tr := pdf.UnicodeTranslatorFromDescriptor("") // core fonts are cp1252
+
+	var footer string // the wallet of the current page
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "", 7)
+		pdf.CellFormat(0, 5, tr(footer), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.SetFillColor(235, 235, 235)
+		for _, c := range pdfColumns {
+			pdf.CellFormat(c.width, 6, c.name, "B", 0, c.align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 8)
+	}
+
+	oldest := slices.Clone(xfers)
+	slices.SortStableFunc(oldest, func(a, b Transfer) int { return a.Timestamp.Compare(b.Timestamp) })
+	var wallets []string
+	for _, x := range oldest {
+		for _, w := range []string{x.Wallet, x.ToWallet} {
+			if w != "" && !slices.Contains(wallets, w) {
+				wallets = append(wallets, w)
+			}
+		}
+	}
+	if len(wallets) == 0 {
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 8, "No transfers in this period.", "", 1, "L", false, 0, "")
+	}
+
+	fil := func(v *big.Int) string { return opts.amounts.format(v, 18) }
+	for _, wallet := range wallets {
+		pdf.AddPage()
+		footer = wallet
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 8, "Filecoin account statement", "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(0, 5, tr("Account: "+wallet), "", 1, "L", false, 0, "")
+		from, to := opts.period(oldest)
+		period := opts.localTime(from).Format(time.DateOnly) + " to " + opts.localTime(to).Format(time.DateOnly)
+		pdf.CellFormat(0, 5, "Period: "+period, "", 1, "L", false, 0, "")
+		pdf.CellFormat(0, 5, "Issued: "+opts.localTime(time.Now()).Format(time.DateOnly), "", 1, "L", false, 0, "")
+		pdf.Ln(3)
+
+		balance := new(big.Int)
+		if b := opts.openings[wallet]; b != nil {
+			balance.Set(b)
+		}
+		opening := new(big.Int).Set(balance)
+		in, out, fees := new(big.Int), new(big.Int), new(big.Int)
+
+		header()
+		pdf.SetHeaderFuncMode(header, false) // repeated on continuation pages
+		for _, x := range oldest {
+			if x.Wallet != wallet && x.ToWallet != wallet {
+				continue
+			}
+			var delta *big.Int
+			amount, decimals := x.moved()
+			counterparty := x.counterparty()
+			if x.Wallet == wallet {
+				delta, _ = balanceDelta(x)
+			} else {
+				// The receiving side of a transfer between exported wallets
+				_, delta = balanceDelta(x)
+				amount.Neg(amount)
+				counterparty = x.Wallet
+			}
+			var fee *big.Int
+			if x.Wallet == wallet {
+				fee = x.fee()
+			}
+
+			var outCell, inCell, feeCell string
+			if amount.Sign() != 0 && x.Kind != KindPledge && !x.FeeOnly && !(x.Self && x.From == x.To) {
+				value := opts.amounts.format(new(big.Int).Abs(amount), decimals)
+				if x.Ticker() != "FIL" {
+					value += " " + x.Ticker()
+				}
+				if amount.Sign() < 0 {
+					outCell = value
+				} else {
+					inCell = value
+				}
+				if x.Kind != KindToken && x.Kind != KindNFT {
+					if amount.Sign() < 0 {
+						out.Sub(out, amount)
+					} else {
+						in.Add(in, amount)
+					}
+				}
+			}
+			if fee != nil && fee.Sign() != 0 {
+				feeCell = fil(fee)
+				fees.Add(fees, fee)
+			}
+			balance.Add(balance, delta)
+
+			description := string(x.Kind) + " " + counterparty
+			if d := x.description(); d != "" {
+				description += "; " + d
+			}
+			if x.Pending {
+				description += " (pending)"
+			}
+			cells := []string{
+				opts.localTime(x.Timestamp).Format(time.DateTime),
+				description,
+				x.MessageID,
+				outCell,
+				inCell,
+				feeCell,
+				fil(balance),
+			}
+			for i, c := range pdfColumns {
+				pdf.CellFormat(c.width, 5, pdfFit(pdf, tr(cells[i]), c.width-2), "", 0, c.align, false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+		pdf.SetHeaderFuncMode(nil, false)
+
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "", 9)
+		for _, total := range []struct {
+			name  string
+			value *big.Int
+		}{
+			{"Opening balance", opening},
+			{"Total in", in},
+			{"Total out", out},
+			{"Total fees", fees},
+			{"Closing balance", balance},
+		} {
+			if total.name == "Closing balance" {
+				pdf.SetFont("Helvetica", "B", 9)
+			}
+			pdf.CellFormat(60, 6, total.name, "", 0, "L", false, 0, "")
+			pdf.CellFormat(50, 6, fil(total.value)+" FIL", "", 1, "R", false, 0, "")
+		}
+	}
+	return pdf.Output(w)
+}
+
+// pdfFit shortens s, encoded in cp1252, with an ellipsis to fit width in
+// the current font.
+func pdfFit(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	const ellipsis = "\x85"
+	for len(s) > 0 && pdf.GetStringWidth(s+ellipsis) > width {
+		s = s[:len(s)-1]
+	}
+	return strings.TrimSpace(s) + ellipsis
+}
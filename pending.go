@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+	"time"
+)
+
+// pendingTransfer is the pending command's output for one mempool message.
+// They are never written to exports, since they may yet be replaced or
+// dropped.
+type pendingTransfer struct {
+	MessageID string    `json:"message_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Direction string    `json:"direction"`
+	Amount    string    `json:"amount"` // attoFIL
+	Nonce     int       `json:"nonce"`
+	Method    string    `json:"method"`
+	Seen      time.Time `json:"seen"`
+	Status    string    `json:"status"`
+}
+
+// runPending prints the in-flight mempool messages of an address.
+func runPending(ctx context.Context, w io.Writer, args []string, opts fetchOptions) error {
+	fs := flag.NewFlagSet("pending", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError{errors.New("usage: pending [--json] <address>")}
+	}
+	address, err := normalizeAddress(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	hc, err := newHTTPClient(opts)
+	if err != nil {
+		return err
+	}
+	msgs, err := newFilfoxClient(hc, address, opts).PendingMessages(ctx, address)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]pendingTransfer, len(msgs))
+	for i, m := range msgs {
+		direction := "IN"
+		if m.From == address {
+			direction = "OUT"
+		}
+		pending[i] = pendingTransfer{
+			MessageID: m.Cid,
+			From:      m.From,
+			To:        m.To,
+			Direction: direction,
+			Amount:    m.Value,
+			Nonce:     m.Nonce,
+			Method:    m.Method,
+			Seen:      time.Unix(int64(m.CreateTimestamp), 0).UTC(),
+			Status:    "Unconfirmed",
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(pending)
+	}
+
+	if len(pending) == 0 {
+		fmt.Fprintf(w, "No pending messages for %s\n", address)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tSEEN\tDIR\tAMOUNT (FIL)\tNONCE\tMETHOD\tCOUNTERPARTY\tMESSAGE")
+	for _, p := range pending {
+		amount, _ := new(big.Int).SetString(p.Amount, 10)
+		counterparty := p.To
+		if p.Direction == "IN" {
+			counterparty = p.From
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			p.Status, p.Seen.Format(time.RFC3339), p.Direction, attoFILToFIL(amount).Text('f', -1),
+			p.Nonce, p.Method, counterparty, p.MessageID)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,213 @@
+// Package address validates Filecoin addresses, and converts between
+// Ethereum style 0x addresses and their Filecoin delegated (f410) and ID
+// (f0) counterparts.
+package address
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// ErrInvalid is returned for addresses that can't be converted.
+var ErrInvalid = errors.New("invalid address")
+
+const (
+	protocolID        = 0
+	protocolSecp256k1 = 1
+	protocolActor     = 2
+	protocolBLS       = 3
+	protocolDelegated = 4
+	eamNamespace      = 10 // the Ethereum Address Manager actor, f010
+	checksumLength    = 4
+	ethLength         = 20
+	hashLength        = 20 // of secp256k1 and actor payloads
+	blsLength         = 48
+	maxSubaddress     = 54 // of delegated payloads
+)
+
+var encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// maskedIDPrefix marks a 0x address that is a masked f0 ID address rather
+// than an f410 address.
+var maskedIDPrefix = []byte{0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+// IsEth reports whether s looks like a 0x address.
+func IsEth(s string) bool {
+	return len(s) == 2+2*ethLength && (strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X"))
+}
+
+// FromEth converts a 0x address into the Filecoin address for network ("f"
+// for mainnet, "t" for testnets): an f0 ID address for masked IDs, otherwise
+// an f410 delegated address.
+func FromEth(eth, network string) (string, error) {
+	if !IsEth(eth) {
+		return "", fmt.Errorf("%w: %q is not a 0x address", ErrInvalid, eth)
+	}
+	payload, err := hex.DecodeString(eth[2:])
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %v", ErrInvalid, eth, err)
+	}
+	if bytes.HasPrefix(payload, maskedIDPrefix) {
+		id := binary.BigEndian.Uint64(payload[len(maskedIDPrefix):])
+		return network + "0" + strconv.FormatUint(id, 10), nil
+	}
+	sum := checksum(payload)
+	return network + "410f" + encoding.EncodeToString(append(payload, sum...)), nil
+}
+
+// ToEth converts an f410 or f0 address into its 0x form, with EIP-55
+// checksum casing.
+func ToEth(addr string) (string, error) {
+	if len(addr) < 3 || (addr[0] != 'f' && addr[0] != 't') {
+		return "", fmt.Errorf("%w: %q is not a Filecoin address", ErrInvalid, addr)
+	}
+	var payload []byte
+	switch {
+	case strings.HasPrefix(addr[1:], "410f"):
+		raw, err := encoding.DecodeString(addr[5:])
+		if err != nil || len(raw) != ethLength+checksumLength || encoding.EncodeToString(raw) != addr[5:] {
+			return "", fmt.Errorf("%w: %q is not a valid f410 address", ErrInvalid, addr)
+		}
+		payload = raw[:ethLength]
+		if !bytes.Equal(checksum(payload), raw[ethLength:]) {
+			return "", fmt.Errorf("%w: %q has a bad checksum", ErrInvalid, addr)
+		}
+	case addr[1] == '0':
+		id, err := strconv.ParseUint(addr[2:], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not a valid ID address", ErrInvalid, addr)
+		}
+		payload = binary.BigEndian.AppendUint64(bytes.Clone(maskedIDPrefix), id)
+	default:
+		return "", fmt.Errorf("%w: %q has no 0x form", ErrInvalid, addr)
+	}
+	return eip55(payload), nil
+}
+
+// Validate checks that s is a well formed Filecoin address of any protocol,
+// f0 to f4 (or t for testnets), with a correct checksum, so typos are
+// caught before a backend is asked about an address that can't exist. 0x
+// addresses in mixed case must match their EIP-55 checksum casing.
+func Validate(s string) error {
+	if IsEth(s) {
+		if _, err := FromEth(s, "f"); err != nil {
+			return err
+		}
+		// Mixed case carries an EIP-55 checksum; all one case carries none
+		digits := s[2:]
+		if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) {
+			payload, _ := hex.DecodeString(digits)
+			if want := eip55(payload); digits != want[2:] {
+				return fmt.Errorf("%w: %q has a bad EIP-55 checksum, want %s", ErrInvalid, s, want)
+			}
+		}
+		return nil
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return fmt.Errorf("%w: %q is not 40 hex digits after 0x", ErrInvalid, s)
+	}
+	if len(s) < 3 || (s[0] != 'f' && s[0] != 't') {
+		return fmt.Errorf("%w: %q doesn't start with f or t and a protocol, as in f1", ErrInvalid, s)
+	}
+
+	var payload []byte
+	protocol, rest := s[1], s[2:]
+	switch protocol - '0' {
+	case protocolID:
+		if _, err := strconv.ParseUint(rest, 10, 64); err != nil || (len(rest) > 1 && rest[0] == '0') {
+			return fmt.Errorf("%w: %q is not an ID address, f0 and a number", ErrInvalid, s)
+		}
+		return nil
+	case protocolSecp256k1, protocolActor, protocolBLS:
+		n := hashLength
+		if protocol-'0' == protocolBLS {
+			n = blsLength
+		}
+		raw, err := decode(s, rest, n+checksumLength)
+		if err != nil {
+			return err
+		}
+		payload = raw
+	case protocolDelegated:
+		ns, sub, ok := strings.Cut(rest, "f")
+		namespace, err := strconv.ParseUint(ns, 10, 64)
+		if !ok || err != nil {
+			return fmt.Errorf("%w: %q is not a delegated address, as in f410f", ErrInvalid, s)
+		}
+		raw, err := decode(s, sub, 0)
+		if err != nil {
+			return err
+		}
+		if len(raw) < checksumLength || len(raw) > maxSubaddress+checksumLength {
+			return fmt.Errorf("%w: %q has a subaddress of the wrong length", ErrInvalid, s)
+		}
+		payload = append(binary.AppendUvarint(nil, namespace), raw...)
+	default:
+		return fmt.Errorf("%w: %q has unknown protocol %c, want 0 to 4", ErrInvalid, s, protocol)
+	}
+
+	body, sum := payload[:len(payload)-checksumLength], payload[len(payload)-checksumLength:]
+	if !bytes.Equal(addressChecksum(protocol-'0', body), sum) {
+		return fmt.Errorf("%w: %q has a bad checksum, so is likely mistyped", ErrInvalid, s)
+	}
+	return nil
+}
+
+// decode reads the base32 part of address s, of n bytes if n is set.
+func decode(s, part string, n int) ([]byte, error) {
+	if i := strings.IndexFunc(part, func(r rune) bool { return (r < 'a' || r > 'z') && (r < '2' || r > '7') }); i >= 0 {
+		r, _ := utf8.DecodeRuneInString(part[i:])
+		return nil, fmt.Errorf("%w: %q has %q, which isn't one of lowercase a-z and 2-7", ErrInvalid, s, r)
+	}
+	if n > 0 && len(part) != encoding.EncodedLen(n) {
+		return nil, fmt.Errorf("%w: %q is %d characters long, not %d", ErrInvalid, s, len(s), len(s)-len(part)+encoding.EncodedLen(n))
+	}
+	raw, err := encoding.DecodeString(part)
+	switch {
+	case err != nil && n == 0:
+		return nil, fmt.Errorf("%w: %q has a subaddress of the wrong length", ErrInvalid, s)
+	case err != nil || encoding.EncodeToString(raw) != part:
+		// Stray bits past the end of the checksum
+		return nil, fmt.Errorf("%w: %q has a bad checksum, so is likely mistyped", ErrInvalid, s)
+	}
+	return raw, nil
+}
+
+// checksum is the Filecoin address checksum of an f410 payload.
+func checksum(payload []byte) []byte {
+	return addressChecksum(protocolDelegated, append(binary.AppendUvarint(nil, eamNamespace), payload...))
+}
+
+// addressChecksum is the checksum of an address of protocol with payload.
+func addressChecksum(protocol byte, payload []byte) []byte {
+	h, _ := blake2b.New(checksumLength, nil)
+	h.Write([]byte{protocol})
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// eip55 formats payload as a 0x address with mixed-case checksum.
+func eip55(payload []byte) string {
+	lower := hex.EncodeToString(payload)
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(lower))
+	hash := h.Sum(nil)
+
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && (hash[i/2]>>(4*(1-i%2)))&0xf >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
@@ -0,0 +1,33 @@
+package address
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateEth(t *testing.T) {
+	const checksummed = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	tests := []struct {
+		name string
+		s    string
+		ok   bool
+	}{
+		{"checksummed", checksummed, true},
+		{"lower case", strings.ToLower(checksummed), true},
+		{"upper case", "0x" + strings.ToUpper(checksummed[2:]), true},
+		{"bad checksum", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", false},
+		{"not hex", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeZ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.s)
+			if tt.ok && err != nil {
+				t.Errorf("Validate(%q) = %v", tt.s, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalid) {
+				t.Errorf("Validate(%q) = %v, want ErrInvalid", tt.s, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Package export is the registry of contributed formats filfoxy can export
+// transfers in. A format is an Exporter registered under the name --format
+// selects it by, from an init function of a file of its own:
+//
+//	func init() {
+//		export.Register("example", export.Format{
+//			Description: "Example CSV",
+//			Ext:         ".csv",
+//			CSV:         true,
+//			Exporter:    export.ExporterFunc(writeExample),
+//		})
+//	}
+//
+// Registered formats are listed by filfoxy formats alongside the built in
+// ones, and honour the flags every format does, through Options.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Transfer is a movement of value to or from an exported wallet.
+type Transfer struct {
+	Wallet       string // the exported address
+	Kind         string // transfer, reward, penalty, pledge, token, nft, internal or other
+	Flow         string // In or Out of the wallet's holdings, or Internal to them
+	Height       int
+	Timestamp    time.Time
+	MessageID    string
+	From, To     string
+	Counterparty string // whichever of From and To isn't the wallet
+
+	// Amount is signed, negative when leaving the wallet, in units of
+	// 10^-Decimals of Currency: attoFIL, or a token's smallest unit.
+	Amount   *big.Int
+	Currency string // FIL, or the token's symbol
+	Decimals int
+
+	Fee     *big.Int // the attoFIL the wallet paid in gas, zero if none
+	FeeOnly bool     // only the fee was paid, as by a failed message
+	Self    bool     // between the user's own addresses
+	Pending bool     // not yet final, so may still be reverted
+
+	Method   string   // with --messages
+	Label    string   // the counterparty's, from --labels
+	Category string   // from --rules
+	Tags     []string // from --rules
+	Account  string   // the miner sub-account, with --miner-accounts
+	Note     string   // with --vesting
+}
+
+// Options are the settings of an export every format can honour.
+type Options struct {
+	Location *time.Location // zone of exported dates, from --timezone
+	Combined bool           // several wallets are exported into one file
+
+	// FormatAmount writes v, in units of 10^-decimals, as a decimal rounded
+	// as --decimals, --rounding and --raw-amounts ask.
+	FormatAmount func(v *big.Int, decimals int) string
+
+	// FormatDate writes t in layout, or the --date-format in its place.
+	FormatDate func(t time.Time, layout string) string
+
+	// Delimiter separates the fields of CSV formats; a comma if zero.
+	Delimiter rune
+}
+
+// CSVWriter writes CSV records to w separated by the export's delimiter.
+func (opts Options) CSVWriter(w io.Writer) *csv.Writer {
+	writer := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		writer.Comma = opts.Delimiter
+	}
+	return writer
+}
+
+// An Exporter writes transfers in a file layout.
+type Exporter interface {
+	// Export writes xfers, newest first, to w.
+	Export(w io.Writer, xfers []Transfer, opts Options) error
+}
+
+// ExporterFunc adapts a function to an Exporter.
+type ExporterFunc func(w io.Writer, xfers []Transfer, opts Options) error
+
+// Export calls f.
+func (f ExporterFunc) Export(w io.Writer, xfers []Transfer, opts Options) error {
+	return f(w, xfers, opts)
+}
+
+// Format describes a registered Exporter.
+type Format struct {
+	Description string // a line for filfoxy formats
+	Ext         string // file name extension, including the dot
+	CSV         bool   // delimited text, so --delimiter and --decimal-comma apply
+	Exporter    Exporter
+}
+
+var (
+	mu      sync.Mutex
+	formats = make(map[string]Format)
+)
+
+// Register makes f selectable with --format name. It panics if name is
+// already registered or f has no Exporter, as both are programming errors.
+func Register(name string, f Format) {
+	mu.Lock()
+	defer mu.Unlock()
+	if f.Exporter == nil {
+		panic(fmt.Sprintf("export: format %s has no Exporter", name))
+	}
+	if _, ok := formats[name]; ok {
+		panic(fmt.Sprintf("export: format %s registered twice", name))
+	}
+	formats[name] = f
+}
+
+// Lookup returns the format registered as name.
+func Lookup(name string) (Format, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, ok := formats[name]
+	return f, ok
+}
+
+// Names lists the registered formats, sorted.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,63 @@
+package export_test
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/mroth/filfoxy/pkg/export"
+)
+
+func writeAmounts(w io.Writer, xfers []export.Transfer, opts export.Options) error {
+	writer := opts.CSVWriter(w)
+	for _, x := range xfers {
+		if err := writer.Write([]string{x.MessageID, opts.FormatAmount(x.Amount, x.Decimals), x.Currency}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func TestRegister(t *testing.T) {
+	export.Register("test-amounts", export.Format{Description: "amounts", Ext: ".csv", CSV: true, Exporter: export.ExporterFunc(writeAmounts)})
+
+	f, ok := export.Lookup("test-amounts")
+	if !ok || f.Ext != ".csv" || !f.CSV {
+		t.Fatalf("Lookup = %+v, %v", f, ok)
+	}
+	if !slices.Contains(export.Names(), "test-amounts") {
+		t.Errorf("Names() = %v lacks test-amounts", export.Names())
+	}
+
+	var buf bytes.Buffer
+	opts := export.Options{Delimiter: ';', FormatAmount: func(v *big.Int, decimals int) string { return v.String() }}
+	if err := f.Exporter.Export(&buf, []export.Transfer{{MessageID: "bafy", Amount: big.NewInt(5), Currency: "FIL"}}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "bafy;5;FIL\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	f := export.Format{Exporter: export.ExporterFunc(writeAmounts)}
+	export.Register("test-twice", f)
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a name twice didn't panic")
+		}
+	}()
+	export.Register("test-twice", f)
+}
+
+func TestRegisterWithoutExporter(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a format without an Exporter didn't panic")
+		}
+	}()
+	export.Register("test-nil", export.Format{})
+}
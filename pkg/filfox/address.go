@@ -0,0 +1,61 @@
+package filfox
+
+import (
+	"context"
+	"fmt"
+)
+
+// Address is the overview of an address from /address/{address}. Only the
+// fields filfoxy uses are modelled.
+type Address struct {
+	Address           string `json:"address"`
+	ID                string `json:"id"`     // f0 ID address
+	Robust            string `json:"robust"` // f1/f2/f3/f4 address, if any
+	Actor             string `json:"actor"`  // e.g. account, multisig, storageminer, evm
+	CreateHeight      int    `json:"createHeight"`
+	CreateTimestamp   int    `json:"createTimestamp"`
+	LastSeenHeight    int    `json:"lastSeenHeight"`
+	LastSeenTimestamp int    `json:"lastSeenTimestamp"`
+	Balance           string `json:"balance"` // in attoFIL as a string
+	Nonce             int    `json:"nonce"`
+	MessageCount      int    `json:"messageCount"`
+	TransferCount     int    `json:"transferCount"`
+
+	Multisig *Multisig `json:"multisig,omitempty"` // only for multisig actors
+	Miner    *Miner    `json:"miner,omitempty"`    // only for storage miner actors
+}
+
+// Miner is the state of a storage miner actor. Only the addresses funds flow
+// through are modelled.
+type Miner struct {
+	Owner            MinerAccount   `json:"owner"`
+	Worker           MinerAccount   `json:"worker"`
+	Beneficiary      MinerAccount   `json:"beneficiary"`
+	ControlAddresses []MinerAccount `json:"controlAddresses"`
+}
+
+// MinerAccount is an address associated with a miner.
+type MinerAccount struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"` // in attoFIL as a string
+}
+
+// Multisig is the state of a multisig actor, including its vesting schedule
+// when InitialBalance is locked.
+type Multisig struct {
+	Signers           []string `json:"signers"`
+	ApprovalThreshold int      `json:"approvalThreshold"`
+	InitialBalance    string   `json:"initialBalance"` // in attoFIL as a string
+	LockedBalance     string   `json:"lockedBalance"`  // in attoFIL as a string
+	StartEpoch        int      `json:"startEpoch"`
+	UnlockDuration    int      `json:"unlockDuration"` // in epochs
+}
+
+// Address retrieves the overview of address.
+func (c *Client) Address(ctx context.Context, address string) (*Address, error) {
+	var a Address
+	if err := c.get(ctx, "/address/"+address, nil, &a); err != nil {
+		return nil, fmt.Errorf("retrieving address %s: %w", address, err)
+	}
+	return &a, nil
+}
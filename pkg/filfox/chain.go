@@ -0,0 +1,39 @@
+package filfox
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// Tipset summarises a tipset as reported by /tipset/recent.
+type Tipset struct {
+	Height       int `json:"height"`
+	Timestamp    int `json:"timestamp"`
+	MessageCount int `json:"messageCount"`
+}
+
+// RecentTipsets returns the most recent count tipsets, newest first.
+func (c *Client) RecentTipsets(ctx context.Context, count int) ([]Tipset, error) {
+	q := url.Values{}
+	q.Add("count", strconv.Itoa(count))
+
+	var tipsets []Tipset
+	if err := c.get(ctx, "/tipset/recent", q, &tipsets); err != nil {
+		return nil, err
+	}
+	return tipsets, nil
+}
+
+// ChainHead returns the latest tipset known to Filfox.
+func (c *Client) ChainHead(ctx context.Context) (Tipset, error) {
+	tipsets, err := c.RecentTipsets(ctx, 1)
+	if err != nil {
+		return Tipset{}, err
+	}
+	if len(tipsets) == 0 {
+		return Tipset{}, errors.New("no recent tipsets returned")
+	}
+	return tipsets[0], nil
+}
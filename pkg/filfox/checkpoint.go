@@ -0,0 +1,216 @@
+package filfox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// WithCheckpoint persists transfer pages to the file at path as they are
+// retrieved, so that an interrupted fetch can later pick up where it left off.
+// When resume is true, pages already recorded in a compatible checkpoint are
+// not fetched again; otherwise any existing checkpoint is discarded. The file
+// is removed once a fetch completes successfully. A fetch holds path.lock
+// while it uses the checkpoint, so that concurrent fetches sharing path fail
+// rather than corrupting it.
+func WithCheckpoint(path string, resume bool) Option {
+	return func(c *Client) {
+		c.checkpointPath = path
+		c.resume = resume
+	}
+}
+
+// The checkpoint file is JSON lines: a header identifying the fetch, followed
+// by one line per completed page. Appending keeps saves cheap for wallets with
+// hundreds of pages, and a torn final line from a crash is simply ignored.
+// Transfers landing since the pages were saved don't invalidate them: they
+// shift the history rather than change it, and Transfers stitches the saved
+// pages to the fresh ones by their overlap.
+type checkpointHeader struct {
+	Address   string `json:"address"`
+	PageSize  int    `json:"pageSize"`
+	Types     string `json:"types,omitempty"`
+	MinHeight int    `json:"minHeight,omitempty"`
+	MaxHeight int    `json:"maxHeight,omitempty"`
+}
+
+type checkpointPage struct {
+	Page      int        `json:"page"`
+	Transfers []Transfer `json:"transfers"`
+}
+
+type checkpoint struct {
+	path   string
+	header checkpointHeader
+	pages  map[int][]Transfer // pages restored from a previous run
+	lock   string             // held while the checkpoint is in use
+
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// openCheckpoint prepares the checkpoint for a fetch of address, restoring
+// previously saved pages when resuming. It returns nil if checkpointing is
+// not configured.
+func (c *Client) openCheckpoint(address string) (*checkpoint, error) {
+	if c.checkpointPath == "" {
+		return nil, nil
+	}
+
+	lock := c.checkpointPath + ".lock"
+	lf, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, fmt.Errorf("checkpoint %s is in use by another fetch (remove %s if none is running)", c.checkpointPath, lock)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(lf, os.Getpid())
+	lf.Close()
+
+	cp, err := c.restoreCheckpoint(address)
+	if err != nil {
+		os.Remove(lock)
+		return nil, err
+	}
+	cp.lock = lock
+	return cp, nil
+}
+
+func (c *Client) restoreCheckpoint(address string) (*checkpoint, error) {
+	header := checkpointHeader{
+		Address:   address,
+		PageSize:  c.pageSize,
+		Types:     strings.Join(c.types, ","),
+		MinHeight: c.minHeight,
+		MaxHeight: c.maxHeight,
+	}
+	cp := &checkpoint{path: c.checkpointPath, header: header, pages: make(map[int][]Transfer)}
+	if c.resume {
+		pages, err := readCheckpoint(c.checkpointPath, header)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			slog.Info("No checkpoint to resume from, starting fresh", "path", c.checkpointPath)
+		case err != nil:
+			slog.Warn("Discarding unusable checkpoint", "path", c.checkpointPath, "err", err)
+		default:
+			slog.Info("Resuming from checkpoint", "path", c.checkpointPath, "pages", len(pages))
+			cp.pages = pages
+		}
+	}
+
+	// Rewrite the file with only what we kept, so stale or torn lines never
+	// accumulate across runs.
+	f, err := os.Create(c.checkpointPath)
+	if err != nil {
+		return nil, err
+	}
+	cp.f = f
+	cp.enc = json.NewEncoder(f)
+	if err := cp.enc.Encode(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	for page, xfers := range cp.pages {
+		if err := cp.enc.Encode(checkpointPage{Page: page, Transfers: xfers}); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return cp, nil
+}
+
+var errCheckpointMismatch = errors.New("checkpoint is for a different fetch")
+
+func readCheckpoint(path string, want checkpointHeader) (map[int][]Transfer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	if !scanner.Scan() {
+		return nil, errCheckpointMismatch
+	}
+	var header checkpointHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, err
+	}
+	if header != want {
+		return nil, errCheckpointMismatch
+	}
+
+	pages := make(map[int][]Transfer)
+	for scanner.Scan() {
+		var p checkpointPage
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			break // torn write from an interrupted run
+		}
+		pages[p.Page] = p.Transfers
+	}
+	return pages, nil
+}
+
+// restored returns the saved transfers for page from a resumed checkpoint.
+func (cp *checkpoint) restored(page int) ([]Transfer, bool) {
+	if cp == nil {
+		return nil, false
+	}
+	xfers, ok := cp.pages[page]
+	return xfers, ok
+}
+
+// save records a completed page. Failures are logged rather than returned,
+// since losing the checkpoint shouldn't abort an otherwise healthy fetch.
+func (cp *checkpoint) save(page int, xfers []Transfer) {
+	if cp == nil {
+		return
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if err := cp.enc.Encode(checkpointPage{Page: page, Transfers: xfers}); err != nil {
+		slog.Warn("Failed to save checkpoint", "path", cp.path, "err", err)
+	}
+}
+
+// discard drops the restored pages, both in memory and on disk, so that the
+// fetch continues as a fresh one.
+func (cp *checkpoint) discard() {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.pages = make(map[int][]Transfer)
+	err := cp.f.Truncate(0)
+	if err == nil {
+		_, err = cp.f.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		err = cp.enc.Encode(cp.header)
+	}
+	if err != nil {
+		slog.Warn("Failed to reset checkpoint", "path", cp.path, "err", err)
+	}
+}
+
+// close finishes the checkpoint, removing it if the fetch completed.
+func (cp *checkpoint) close(completed bool) {
+	if cp == nil {
+		return
+	}
+	cp.f.Close()
+	if completed {
+		os.Remove(cp.path)
+	} else {
+		slog.Info("Fetch progress saved to checkpoint", "path", cp.path)
+	}
+	os.Remove(cp.lock)
+}
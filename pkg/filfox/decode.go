@@ -0,0 +1,63 @@
+package filfox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// WithStrictDecoding makes responses containing fields this package doesn't
+// know about fail with an error, rather than just logging a warning. Use it to
+// notice Filfox schema changes before they can silently affect results.
+func WithStrictDecoding(strict bool) Option {
+	return func(c *Client) { c.strict = strict }
+}
+
+// schemaChecked is implemented by response types that model every field
+// Filfox returns, so that unknown fields indicate schema drift. Endpoints we
+// only partially model skip the check.
+type schemaChecked interface {
+	schemaChecked()
+}
+
+// warnedFields remembers which unknown fields have already been logged, so
+// lenient mode warns once per field rather than once per page.
+var warnedFields sync.Map
+
+// decode reads JSON from r into v, checking it against the expected schema.
+func (c *Client) decode(r io.Reader, path string, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if _, ok := v.(schemaChecked); !ok {
+		return nil
+	}
+
+	// Decode again, strictly, into a throwaway value to detect schema drift
+	// without disturbing the lenient result.
+	probe := reflect.New(reflect.TypeOf(v).Elem()).Interface()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(probe)
+	if err == nil || !strings.HasPrefix(err.Error(), "json: unknown field ") {
+		return nil
+	}
+
+	if c.strict {
+		return fmt.Errorf("response schema changed for %s: %w", path, err)
+	}
+	field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+	if _, warned := warnedFields.LoadOrStore(field, true); !warned {
+		slog.Warn("API response contains unknown field, ignoring", "path", path, "field", field)
+	}
+	return nil
+}
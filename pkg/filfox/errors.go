@@ -0,0 +1,58 @@
+package filfox
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is matched by errors for 404 responses, typically an
+	// address or message the explorer has never seen. It is not retried.
+	ErrNotFound = errors.New("not found")
+
+	// ErrRateLimited is matched by errors for 429 responses that persisted
+	// after waiting out the server's Retry-After.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// StatusError is a non-success HTTP response from the API. Use errors.Is with
+// ErrNotFound or ErrRateLimited to branch on common cases.
+type StatusError struct {
+	StatusCode int
+	Status     string // e.g. "404 Not Found"
+	URL        string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API call returned non-success code: %s", e.Status)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// ServerError is a 5xx response, indicating trouble on the explorer's side.
+// These are retried, and returned once retries are exhausted.
+type ServerError struct {
+	StatusError
+}
+
+// NewStatusError returns the typed error for resp's status code, a
+// *ServerError for 5xx responses and a *StatusError otherwise.
+func NewStatusError(resp *http.Response) error {
+	se := StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	if resp.Request != nil {
+		se.URL = resp.Request.URL.String()
+	}
+	if resp.StatusCode >= 500 {
+		return &ServerError{se}
+	}
+	return &se
+}
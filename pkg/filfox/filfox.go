@@ -0,0 +1,474 @@
+// Package filfox is a client for the Filfox block explorer API.
+package filfox
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the public Filfox mainnet API endpoint.
+	DefaultBaseURL = "https://filfox.info/api/v1"
+
+	// DefaultPageSize is the number of records requested per page.
+	DefaultPageSize = 100
+
+	// DefaultUserAgent identifies requests made by this package.
+	DefaultUserAgent = "filfoxy (+https://github.com/mroth/filfoxy)"
+
+	// DefaultTimeout bounds each individual HTTP request, including reading
+	// the response body.
+	DefaultTimeout = 60 * time.Second
+)
+
+// Client retrieves data from the Filfox API. The zero value is not usable,
+// create one with NewClient.
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	timeout     time.Duration
+	transport   http.RoundTripper
+	apiKey      string
+	userAgent   string
+	pageSize    int
+	types       []string
+	minHeight   int
+	maxHeight   int
+	concurrency int
+	maxPages    int
+	strict      bool
+	limiter     *RateLimiter
+	progress    func(Progress)
+
+	checkpointPath string
+	resume         bool
+
+	maxRetries     int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL overrides the API endpoint, e.g. for calibration net or a mock server.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) { c.baseURL = baseURL }
+}
+
+// WithHTTPClient sets the HTTP client used for requests. The client is copied,
+// so WithTimeout and WithTransport never modify the caller's value.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithTimeout sets the time limit for each HTTP request. Zero means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithTransport sets the RoundTripper used for requests, e.g. an
+// *http.Transport configured with a proxy or custom TLS settings.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) { c.transport = rt }
+}
+
+// WithAPIKey authenticates requests with a Filfox API key, which grants
+// higher rate limits.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// WithPageSize sets the number of records requested per page.
+func WithPageSize(n int) Option {
+	return func(c *Client) { c.pageSize = n }
+}
+
+// WithHeightRange restricts transfers to epochs in [minHeight, maxHeight],
+// where zero leaves that end unbounded. The transfers endpoint has no height
+// parameters, but since it returns records newest first, pagination stops as
+// soon as a page reaches below minHeight instead of downloading the full
+// history.
+func WithHeightRange(minHeight, maxHeight int) Option {
+	return func(c *Client) {
+		c.minHeight = minHeight
+		c.maxHeight = maxHeight
+	}
+}
+
+// inHeightRange reports whether height falls within the configured range.
+func (c *Client) inHeightRange(height int) bool {
+	return (c.minHeight == 0 || height >= c.minHeight) && (c.maxHeight == 0 || height <= c.maxHeight)
+}
+
+// belowHeightRange reports whether a page of newest-first records has reached
+// past the lower height bound, so no later page can contain wanted records.
+func (c *Client) belowHeightRange(xfers []Transfer) bool {
+	return c.minHeight > 0 && len(xfers) > 0 && xfers[len(xfers)-1].Height < c.minHeight
+}
+
+// WithTypes restricts transfers to the given types (e.g. "send", "receive"),
+// filtered server side. By default all types are returned.
+func WithTypes(types ...string) Option {
+	return func(c *Client) { c.types = types }
+}
+
+// NewClient returns a Client configured with the given options.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		baseURL:     DefaultBaseURL,
+		httpClient:  &http.Client{Timeout: DefaultTimeout},
+		timeout:     -1, // keep the HTTP client's own timeout
+		pageSize:    DefaultPageSize,
+		concurrency: DefaultConcurrency,
+		maxPages:    DefaultMaxPages,
+
+		maxRetries:     DefaultMaxRetries,
+		retryBaseDelay: defaultRetryBaseDelay,
+		retryMaxDelay:  defaultRetryMaxDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	hc := *c.httpClient
+	if c.timeout >= 0 {
+		hc.Timeout = c.timeout
+	}
+	if c.transport != nil {
+		hc.Transport = c.transport
+	}
+	c.httpClient = &hc
+	return c
+}
+
+// get issues a GET request for path with the query parameters and decodes the
+// JSON response body into v. Transient failures are retried with backoff, and
+// rate limited requests wait as long as the server asks before trying again.
+func (c *Client) get(ctx context.Context, path string, query url.Values, v any) error {
+	var retries, rateLimited int
+	for {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
+		res := c.getOnce(ctx, path, query, v)
+		if res.err == nil {
+			return nil
+		}
+
+		var delay time.Duration
+		switch {
+		case res.rateLimited && rateLimited < maxRateLimitRetries:
+			delay = res.retryAfter
+			if delay < 0 {
+				delay = defaultRateLimitDelay
+			}
+			rateLimited++
+			slog.Warn("API rate limited, waiting", "path", path, "delay", delay)
+		case res.retry && retries < c.maxRetries:
+			delay = c.backoff(retries)
+			retries++
+			slog.Warn("API call failed, retrying", "path", path, "attempt", retries, "delay", delay, "err", res.err)
+		default:
+			return res.err
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// attempt is the outcome of a single request.
+type attempt struct {
+	err         error
+	retry       bool          // failure is transient and worth retrying
+	rateLimited bool          // server responded 429 Too Many Requests
+	retryAfter  time.Duration // server requested wait, negative if unspecified
+}
+
+// getOnce performs a single request attempt.
+func (c *Client) getOnce(ctx context.Context, path string, query url.Values, v any) attempt {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return attempt{err: err}
+	}
+	req.URL.RawQuery = query.Encode()
+	// Set explicitly rather than relying on the transport, since a custom
+	// RoundTripper may not negotiate compression on its own.
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	slog.Debug("API call", "url", req.URL.String())
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Network errors are transient, unless caused by our own cancellation
+		return attempt{err: err, retry: ctx.Err() == nil}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var serverErr *ServerError
+		err := NewStatusError(resp)
+		return attempt{
+			err:         err,
+			retry:       errors.As(err, &serverErr),
+			rateLimited: resp.StatusCode == http.StatusTooManyRequests,
+			retryAfter:  parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	body, err := decodedBody(resp)
+	if err != nil {
+		return attempt{err: err, retry: true}
+	}
+	defer body.Close()
+	return attempt{err: c.decode(body, path, v)}
+}
+
+// decodedBody returns the response body, decompressing it if the server
+// honored our Accept-Encoding.
+func decodedBody(resp *http.Response) (io.ReadCloser, error) {
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp.Body, nil
+	}
+	return gzip.NewReader(resp.Body)
+}
+
+// Transfers returns an iterator over the complete transfer history for
+// address, yielding records page by page as they arrive so that memory stays
+// bounded for very large wallets. The first page reveals the total record
+// count, after which up to the configured concurrency of pages are fetched
+// ahead in parallel; records are always yielded in page order. Iteration
+// stops after the first error, and cancelling ctx aborts in-flight requests.
+func (c *Client) Transfers(ctx context.Context, address string) iter.Seq2[Transfer, error] {
+	return func(yield func(Transfer, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		first, err := c.transfersPage(ctx, address, 0)
+		if err != nil {
+			yield(Transfer{}, err)
+			return
+		}
+		total := c.pageCount(first.TotalCount)
+		if total > c.maxPages {
+			yield(Transfer{}, fmt.Errorf("%w: totalCount %d needs %d pages of %d records, over the limit of %d pages",
+				ErrInconsistentPagination, first.TotalCount, total, c.pageSize, c.maxPages))
+			return
+		}
+
+		cp, err := c.openCheckpoint(address)
+		if err != nil {
+			yield(Transfer{}, err)
+			return
+		}
+		completed := false
+		defer func() { cp.close(completed) }()
+
+		if err := checkPage(0, total, first); err != nil {
+			yield(Transfer{}, err)
+			return
+		}
+
+		// Transfers landing mid-fetch push older records onto later pages, so
+		// the tail of one page can be served again at the head of the next.
+		// Only that overlap is dropped, and by no more records than the
+		// history grew between the two pages where a saved page doesn't
+		// leave that unknown, so that genuine repeats such as equal legs of
+		// one message survive and only the previous page need be kept.
+		var prev []Transfer
+		prevCount := first.TotalCount
+		dropped := 0
+		emit := func(xfers []Transfer, totalCount int) bool {
+			limit := len(xfers)
+			if totalCount > 0 && prevCount > 0 {
+				limit = max(totalCount-prevCount, 0)
+			}
+			n := overlap(prev, xfers, limit)
+			dropped += n
+			prev, prevCount = xfers, totalCount
+			for _, xfer := range xfers[n:] {
+				if !c.inHeightRange(xfer.Height) {
+					continue
+				}
+				if !yield(xfer, nil) {
+					return false
+				}
+			}
+			return true
+		}
+		defer func() {
+			if dropped > 0 {
+				slog.Debug("Dropped records duplicated across page boundaries", "address", address, "count", dropped)
+			}
+		}()
+
+		records := len(first.Transfers)
+		report := func(pages int, done bool) {
+			if c.progress != nil {
+				c.progress(Progress{Address: address, Pages: pages, TotalPages: total, Records: records, TotalRecords: first.TotalCount, Done: done})
+			}
+		}
+
+		if !emit(first.Transfers, first.TotalCount) {
+			return
+		}
+		// The first page is always fresh. A saved one from before transfers
+		// landed is stitched on after it, for the records that have since
+		// been pushed to the page after, which may itself be saved. If the
+		// two don't overlap, a page or more has landed in between and the
+		// records there would be lost, so the checkpoint is abandoned.
+		saved, ok := cp.restored(0)
+		if ok && len(saved) > 0 && len(first.Transfers) > 0 && overlap(first.Transfers, saved, len(saved)) == 0 {
+			slog.Warn("Discarding checkpoint, as more transfers have landed since it was saved than a page holds", "path", c.checkpointPath)
+			cp.discard()
+			ok = false
+		}
+		if ok {
+			if !emit(saved, 0) {
+				return
+			}
+		} else {
+			cp.save(0, first.Transfers)
+		}
+		if c.belowHeightRange(first.Transfers) || total <= 1 {
+			report(1, true)
+			completed = true
+			return
+		}
+		report(1, false)
+
+		// Pages are fetched into a sliding window of result channels, each
+		// buffered so abandoned fetches never block once ctx is cancelled.
+		pending := make(map[int]chan pageResult, c.concurrency)
+		start := func(page, total int) {
+			ch := make(chan pageResult, 1)
+			pending[page] = ch
+			if xfers, ok := cp.restored(page); ok {
+				ch <- pageResult{transfers: xfers, restored: true}
+				return
+			}
+			go func() {
+				resp, err := c.transfersPage(ctx, address, page)
+				if err == nil {
+					err = checkPage(page, total, resp)
+				}
+				if err != nil {
+					ch <- pageResult{err: err}
+					return
+				}
+				ch <- pageResult{transfers: resp.Transfers, totalCount: resp.TotalCount}
+			}()
+		}
+
+		next := 1
+		for ; next < total && next <= c.concurrency; next++ {
+			start(next, total)
+		}
+		for page := 1; page < total; page++ {
+			res := <-pending[page]
+			delete(pending, page)
+			if res.err != nil {
+				yield(Transfer{}, res.err)
+				return
+			}
+			if !res.restored {
+				cp.save(page, res.transfers)
+			}
+
+			// If the history grew while we were paging, the oldest records
+			// have been pushed past our last page; extend the fetch to reach
+			// them.
+			if grown := c.pageCount(res.totalCount); grown > total && grown <= c.maxPages {
+				slog.Debug("Transfer history grew during fetch", "address", address, "pages", grown)
+				total = grown
+			}
+			if next < total {
+				start(next, total)
+				next++
+			}
+
+			if !emit(res.transfers, res.totalCount) {
+				return
+			}
+			records += len(res.transfers)
+			if c.belowHeightRange(res.transfers) {
+				break
+			}
+			if page+1 < total {
+				report(page+1, false)
+			}
+		}
+		report(total, true)
+		completed = true
+	}
+}
+
+// overlap is the number of records at the head of page repeating the tail of
+// prev, up to limit.
+func overlap(prev, page []Transfer, limit int) int {
+	for n := min(len(prev), len(page), limit); n > 0; n-- {
+		if slices.Equal(prev[len(prev)-n:], page[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// checkPage verifies a page made progress. An empty page while more records
+// are expected would otherwise silently truncate the history.
+func checkPage(page, total int, resp *TransfersResponse) error {
+	if len(resp.Transfers) == 0 && page < total {
+		return fmt.Errorf("%w: page %d of %d was empty, but totalCount is %d",
+			ErrInconsistentPagination, page, total, resp.TotalCount)
+	}
+	return nil
+}
+
+type pageResult struct {
+	transfers  []Transfer
+	totalCount int
+	restored   bool // from a resumed checkpoint rather than the network
+	err        error
+}
+
+// transfersPage retrieves a single page of transfer records for address.
+func (c *Client) transfersPage(ctx context.Context, address string, page int) (*TransfersResponse, error) {
+	q := url.Values{}
+	q.Add("pageSize", strconv.Itoa(c.pageSize))
+	q.Add("page", strconv.Itoa(page))
+	if len(c.types) > 0 {
+		q.Add("types", strings.Join(c.types, ","))
+	}
+
+	var resp TransfersResponse
+	if err := c.get(ctx, "/address/"+address+"/transfers", q, &resp); err != nil {
+		return nil, fmt.Errorf("retrieving transfers page %d: %w", page, err)
+	}
+	return &resp, nil
+}
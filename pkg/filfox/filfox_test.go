@@ -0,0 +1,227 @@
+package filfox_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+	"github.com/mroth/filfoxy/pkg/filfox/filfoxtest"
+)
+
+const wallet = "f1abjxfbp274xpdqcpuaykwkfb43omjotacm2p3za"
+
+// history is n receives of wallet, newest first.
+func history(n int) []filfox.Transfer {
+	xfers := make([]filfox.Transfer, n)
+	for i := range xfers {
+		height := 1000 - i
+		xfers[i] = filfox.Transfer{
+			Height:  height,
+			Message: "bafy" + strconv.Itoa(height),
+			From:    "f1sender",
+			To:      wallet,
+			Value:   "1",
+			Type:    "receive",
+		}
+	}
+	return xfers
+}
+
+func collect(t *testing.T, c *filfox.Client) []filfox.Transfer {
+	t.Helper()
+	var xfers []filfox.Transfer
+	for x, err := range c.Transfers(context.Background(), wallet) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		xfers = append(xfers, x)
+	}
+	return xfers
+}
+
+func TestTransfersPaged(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	want := history(7)
+	srv.SetTransfers(wallet, want)
+
+	got := collect(t, srv.Client(filfox.WithPageSize(3)))
+	if len(got) != len(want) {
+		t.Fatalf("got %d transfers, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("transfer %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if n := srv.Requests(); n != 3 {
+		t.Errorf("made %d requests, want 3", n)
+	}
+}
+
+// afterFirst runs fn once the first request has been answered.
+type afterFirst struct {
+	next http.RoundTripper
+	once sync.Once
+	fn   func()
+}
+
+func (a *afterFirst) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := a.next.RoundTrip(req)
+	a.once.Do(a.fn)
+	return resp, err
+}
+
+func TestTransfersShiftedPages(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	want := history(6)
+	srv.SetTransfers(wallet, want)
+
+	// Two transfers landing after the first page push its last two records
+	// onto the second page.
+	rt := &afterFirst{next: srv.Server.Client().Transport, fn: func() {
+		srv.PrependTransfers(wallet, filfox.Transfer{Height: 1002, Message: "bafynew1", Type: "receive", Value: "1"},
+			filfox.Transfer{Height: 1001, Message: "bafynew2", Type: "receive", Value: "1"})
+	}}
+	got := collect(t, srv.Client(filfox.WithPageSize(3), filfox.WithConcurrency(1), filfox.WithTransport(rt)))
+	if len(got) != len(want) {
+		t.Fatalf("got %d transfers, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("transfer %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTransfersKeepsEqualLegsAcrossPages(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	// One message paying wallet twice the same amount, split across pages.
+	leg := filfox.Transfer{Height: 1000, Message: "bafybatch", From: "f1sender", To: wallet, Value: "5", Type: "receive"}
+	srv.SetTransfers(wallet, append([]filfox.Transfer{leg, leg}, history(2)...))
+
+	got := collect(t, srv.Client(filfox.WithPageSize(1)))
+	if len(got) != 4 {
+		t.Fatalf("got %d transfers, want 4: %+v", len(got), got)
+	}
+}
+
+// failPage fails requests for page until fail is cleared.
+type failPage struct {
+	next http.RoundTripper
+	page string
+	fail bool
+}
+
+func (f *failPage) RoundTrip(req *http.Request) (*http.Response, error) {
+	if f.fail && req.URL.Query().Get("page") == f.page {
+		return nil, errors.New("connection reset")
+	}
+	return f.next.RoundTrip(req)
+}
+
+func TestTransfersResume(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	want := history(9)
+	srv.SetTransfers(wallet, want)
+	path := filepath.Join(t.TempDir(), "checkpoint")
+
+	rt := &failPage{next: srv.Server.Client().Transport, page: "2", fail: true}
+	c := srv.Client(filfox.WithPageSize(3), filfox.WithConcurrency(1), filfox.WithTransport(rt), filfox.WithCheckpoint(path, true))
+	var err error
+	for _, err = range c.Transfers(context.Background(), wallet) {
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Fatal("fetch succeeded despite a failing page")
+	}
+
+	// Transfers landing before the rerun shift every saved page
+	landed := []filfox.Transfer{
+		{Height: 1002, Message: "bafynew1", To: wallet, Value: "1", Type: "receive"},
+		{Height: 1001, Message: "bafynew2", To: wallet, Value: "1", Type: "receive"},
+	}
+	srv.PrependTransfers(wallet, landed...)
+	want = append(landed, want...)
+	rt.fail = false
+	before := srv.Requests()
+	got := collect(t, c)
+	if n := srv.Requests() - before; n != 3 {
+		t.Errorf("resumed fetch made %d requests, want 3 with page 1 restored", n)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d transfers, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("transfer %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("checkpoint left behind after a completed fetch: %v", err)
+	}
+}
+
+func TestTransfersResumeAfterAPageLanded(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	want := history(9)
+	srv.SetTransfers(wallet, want)
+	path := filepath.Join(t.TempDir(), "checkpoint")
+
+	rt := &failPage{next: srv.Server.Client().Transport, page: "2", fail: true}
+	c := srv.Client(filfox.WithPageSize(3), filfox.WithConcurrency(1), filfox.WithTransport(rt), filfox.WithCheckpoint(path, true))
+	for _, err := range c.Transfers(context.Background(), wallet) {
+		if err != nil {
+			break
+		}
+	}
+
+	// More transfers than a page holds land before the rerun, so the fresh
+	// first page no longer reaches the saved one
+	var landed []filfox.Transfer
+	for i := range 4 {
+		landed = append(landed, filfox.Transfer{Height: 1010 - i, Message: "bafynew" + strconv.Itoa(i), To: wallet, Value: "1", Type: "receive"})
+	}
+	srv.PrependTransfers(wallet, landed...)
+	want = append(landed, want...)
+	rt.fail = false
+	got := collect(t, c)
+	if len(got) != len(want) {
+		t.Fatalf("got %d transfers, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("transfer %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTransfersCheckpointInUse(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	srv.SetTransfers(wallet, history(3))
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	if err := os.WriteFile(path+".lock", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range srv.Client(filfox.WithCheckpoint(path, true)).Transfers(context.Background(), wallet) {
+		if err == nil {
+			t.Fatal("fetch used a checkpoint locked by another")
+		}
+		break
+	}
+}
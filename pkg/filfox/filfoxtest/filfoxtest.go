@@ -0,0 +1,238 @@
+// Package filfoxtest provides a mock Filfox API server for testing code that
+// uses the filfox client, without depending on the network or the live chain.
+package filfoxtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+)
+
+// Server is an httptest.Server emulating the Filfox API endpoints used by
+// the filfox package. Transfers are served newest first, paginated according
+// to the pageSize and page query parameters, as Filfox does.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	transfers map[string][]filfox.Transfer
+	messages  map[string]filfox.Message
+	lists     map[string][]filfox.MessageSummary
+	addresses map[string]filfox.Address
+	failures  []int // status codes to respond with before serving normally
+	requests  int
+}
+
+// NewServer starts and returns a new Server. The caller should call Close
+// when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		transfers: make(map[string][]filfox.Transfer),
+		messages:  make(map[string]filfox.Message),
+		lists:     make(map[string][]filfox.MessageSummary),
+		addresses: make(map[string]filfox.Address),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/address/{address}/transfers", s.handleTransfers)
+	mux.HandleFunc("GET /api/v1/address/{address}/messages", s.handleMessages)
+	mux.HandleFunc("GET /api/v1/message/{cid}", s.handleMessage)
+	mux.HandleFunc("GET /api/v1/address/{address}", s.handleAddress)
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// BaseURL returns the API endpoint to pass to filfox.WithBaseURL.
+func (s *Server) BaseURL() string {
+	return s.URL + "/api/v1"
+}
+
+// Client returns a filfox.Client talking to this server. Retries are
+// disabled unless re-enabled by opts, so injected failures surface directly.
+func (s *Server) Client(opts ...filfox.Option) *filfox.Client {
+	base := []filfox.Option{
+		filfox.WithBaseURL(s.BaseURL()),
+		filfox.WithHTTPClient(s.Server.Client()),
+		filfox.WithMaxRetries(0),
+	}
+	return filfox.NewClient(append(base, opts...)...)
+}
+
+// SetTransfers replaces the transfer history served for address. Records
+// should be ordered newest first.
+func (s *Server) SetTransfers(address string, xfers []filfox.Transfer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers[address] = xfers
+}
+
+// PrependTransfers adds new records to the front of the history for address,
+// simulating transfers landing while a client is paginating.
+func (s *Server) PrependTransfers(address string, xfers ...filfox.Transfer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transfers[address] = append(xfers, s.transfers[address]...)
+}
+
+// SetMessages replaces the message list served for address. Messages should
+// be ordered newest first.
+func (s *Server) SetMessages(address string, msgs []filfox.MessageSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lists[address] = msgs
+}
+
+// SetMessage registers the details served for msg.Cid.
+func (s *Server) SetMessage(msg filfox.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages[msg.Cid] = msg
+}
+
+// SetAddress registers the overview served for a.Address.
+func (s *Server) SetAddress(a filfox.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addresses[a.Address] = a
+}
+
+// FailNext makes the next len(statuses) requests fail with the given HTTP
+// status codes, in order.
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// Requests returns the number of requests received so far.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		var status int
+		if len(s.failures) > 0 {
+			status, s.failures = s.failures[0], s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		if status != 0 {
+			if status == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleTransfers(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	s.mu.Lock()
+	all, ok := s.transfers[address]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "address not found", http.StatusNotFound)
+		return
+	}
+	types := transferTypes(all)
+	if filter := r.URL.Query().Get("types"); filter != "" {
+		all = filterTypes(all, strings.Split(filter, ","))
+	}
+
+	lo, hi := pageBounds(r, len(all))
+	writeJSON(w, filfox.TransfersResponse{
+		TotalCount: len(all),
+		Transfers:  all[lo:hi],
+		Types:      types,
+	})
+}
+
+func filterTypes(xfers []filfox.Transfer, types []string) []filfox.Transfer {
+	var out []filfox.Transfer
+	for _, x := range xfers {
+		if slices.Contains(types, x.Type) {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	all, ok := s.lists[r.PathValue("address")]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "address not found", http.StatusNotFound)
+		return
+	}
+
+	lo, hi := pageBounds(r, len(all))
+	writeJSON(w, filfox.MessagesResponse{
+		TotalCount: len(all),
+		Messages:   all[lo:hi],
+	})
+}
+
+// pageBounds returns the slice bounds of the requested page of n records.
+func pageBounds(r *http.Request, n int) (lo, hi int) {
+	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
+	if err != nil || pageSize <= 0 {
+		pageSize = filfox.DefaultPageSize
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	lo = min(max(page, 0)*pageSize, n)
+	hi = min(lo+pageSize, n)
+	return lo, hi
+}
+
+func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	msg, ok := s.messages[r.PathValue("cid")]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, msg)
+}
+
+func (s *Server) handleAddress(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	a, ok := s.addresses[r.PathValue("address")]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "address not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, a)
+}
+
+func transferTypes(xfers []filfox.Transfer) []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, x := range xfers {
+		if !seen[x.Type] {
+			seen[x.Type] = true
+			types = append(types, x.Type)
+		}
+	}
+	return types
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
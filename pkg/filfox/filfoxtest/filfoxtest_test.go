@@ -0,0 +1,126 @@
+package filfoxtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+	"github.com/mroth/filfoxy/pkg/filfox/filfoxtest"
+)
+
+const wallet = "f1abjxfbp274xpdqcpuaykwkfb43omjotacm2p3za"
+
+func transfers(n int) []filfox.Transfer {
+	xfers := make([]filfox.Transfer, 0, 2*n)
+	for i := range n {
+		msg := "bafy" + strconv.Itoa(i)
+		xfers = append(xfers,
+			filfox.Transfer{Height: 100 - i, Message: msg, From: wallet, To: "f1other", Value: "-10", Type: "send"},
+			filfox.Transfer{Height: 100 - i, Message: msg, From: wallet, To: "f099", Value: "-1", Type: "burn-fee"})
+	}
+	return xfers
+}
+
+func TestServerTransfers(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	srv.SetTransfers(wallet, transfers(5))
+
+	var got []filfox.Transfer
+	for x, err := range srv.Client(filfox.WithPageSize(3), filfox.WithTypes("send")).Transfers(context.Background(), wallet) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, x)
+	}
+	if len(got) != 5 {
+		t.Fatalf("got %d sends, want 5: %+v", len(got), got)
+	}
+	for i, x := range got {
+		if x.Type != "send" || x.Height != 100-i {
+			t.Errorf("transfer %d = %+v, want the send at height %d", i, x, 100-i)
+		}
+	}
+	if n := srv.Requests(); n != 2 {
+		t.Errorf("made %d requests, want 2 pages", n)
+	}
+}
+
+func TestServerNotFound(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+
+	for _, err := range srv.Client().Transfers(context.Background(), wallet) {
+		if !errors.Is(err, filfox.ErrNotFound) {
+			t.Errorf("err = %v, want ErrNotFound", err)
+		}
+		break
+	}
+	if _, err := srv.Client().Message(context.Background(), "bafynone"); !errors.Is(err, filfox.ErrNotFound) {
+		t.Errorf("Message err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestServerFailures(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	srv.SetAddress(filfox.Address{Address: wallet, Balance: "42", Actor: "account"})
+
+	// A rate limit is waited out for as long as the server asks, here no
+	// time at all, but only ten times over
+	srv.FailNext(http.StatusTooManyRequests)
+	if _, err := srv.Client().Address(context.Background(), wallet); err != nil {
+		t.Errorf("err = %v, want the limit waited out", err)
+	}
+	for range 11 {
+		srv.FailNext(http.StatusTooManyRequests)
+	}
+	if _, err := srv.Client().Address(context.Background(), wallet); !errors.Is(err, filfox.ErrRateLimited) {
+		t.Errorf("err = %v, want ErrRateLimited once the limit persists", err)
+	}
+
+	// Retried past a transient server error
+	srv.FailNext(http.StatusServiceUnavailable)
+	before := srv.Requests()
+	c := srv.Client(filfox.WithMaxRetries(1), filfox.WithRetryBackoff(time.Millisecond, time.Millisecond))
+	a, err := c.Address(context.Background(), wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Balance != "42" || a.Actor != "account" {
+		t.Errorf("got %+v", a)
+	}
+	if n := srv.Requests() - before; n != 2 {
+		t.Errorf("made %d requests, want 2", n)
+	}
+}
+
+func TestServerMessages(t *testing.T) {
+	srv := filfoxtest.NewServer()
+	defer srv.Close()
+	srv.SetMessage(filfox.Message{Cid: "bafy1", From: wallet, To: "f1other", Value: "10", Method: "Send"})
+	srv.SetMessages(wallet, []filfox.MessageSummary{{Cid: "bafy2", Method: "Send"}, {Cid: "bafy1", Method: "Send"}})
+
+	m, err := srv.Client().Message(context.Background(), "bafy1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.From != wallet || m.Value != "10" || m.Method != "Send" {
+		t.Errorf("got %+v", m)
+	}
+
+	var cids []string
+	for s, err := range srv.Client(filfox.WithPageSize(1)).Messages(context.Background(), wallet) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		cids = append(cids, s.Cid)
+	}
+	if len(cids) != 2 || cids[0] != "bafy2" || cids[1] != "bafy1" {
+		t.Errorf("got messages %v, want [bafy2 bafy1]", cids)
+	}
+}
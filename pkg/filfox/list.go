@@ -0,0 +1,54 @@
+package filfox
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+// list iterates over a simple paginated endpoint, decoding each page into R
+// and extracting its records with items. Unlike Transfers, pages are fetched
+// sequentially and without checkpointing; these endpoints are much smaller.
+func list[R, T any](ctx context.Context, c *Client, what, path string, query url.Values, items func(*R) (total int, records []T)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		seen := 0
+		for page := 0; ; page++ {
+			if page >= c.maxPages {
+				yield(zero, fmt.Errorf("%w: %s exceed %d pages", ErrInconsistentPagination, what, c.maxPages))
+				return
+			}
+
+			q := url.Values{}
+			for k, vs := range query {
+				q[k] = vs
+			}
+			q.Set("pageSize", strconv.Itoa(c.pageSize))
+			q.Set("page", strconv.Itoa(page))
+
+			var resp R
+			if err := c.get(ctx, path, q, &resp); err != nil {
+				yield(zero, fmt.Errorf("retrieving %s page %d: %w", what, page, err))
+				return
+			}
+
+			total, records := items(&resp)
+			for _, r := range records {
+				if !yield(r, nil) {
+					return
+				}
+			}
+			seen += len(records)
+			if seen >= total {
+				return
+			}
+			if len(records) == 0 {
+				yield(zero, fmt.Errorf("%w: %s page %d was empty after %d of %d records",
+					ErrInconsistentPagination, what, page, seen, total))
+				return
+			}
+		}
+	}
+}
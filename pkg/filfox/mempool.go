@@ -0,0 +1,42 @@
+package filfox
+
+import (
+	"context"
+	"net/url"
+)
+
+// PendingMessage is a message waiting in the mempool, not yet included in a
+// tipset.
+type PendingMessage struct {
+	Cid             string `json:"cid"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Nonce           int    `json:"nonce"`
+	Value           string `json:"value"` // in attoFIL as a string
+	GasLimit        int64  `json:"gasLimit"`
+	GasFeeCap       string `json:"gasFeeCap"`
+	GasPremium      string `json:"gasPremium"`
+	Method          string `json:"method"`
+	MethodNumber    int    `json:"methodNumber"`
+	CreateTimestamp int    `json:"createTimestamp"`
+}
+
+// MempoolResponse is a single page from /message/mempool/filtered-list.
+type MempoolResponse struct {
+	TotalCount int              `json:"totalCount"`
+	Messages   []PendingMessage `json:"messages"`
+}
+
+// PendingMessages returns the mempool messages sent from or to address.
+func (c *Client) PendingMessages(ctx context.Context, address string) ([]PendingMessage, error) {
+	var pending []PendingMessage
+	q := url.Values{"address": {address}}
+	for m, err := range list(ctx, c, "pending messages", "/message/mempool/filtered-list", q,
+		func(r *MempoolResponse) (int, []PendingMessage) { return r.TotalCount, r.Messages }) {
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, m)
+	}
+	return pending, nil
+}
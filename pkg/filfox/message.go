@@ -0,0 +1,81 @@
+package filfox
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// Message is the detail of a single message from /message/{cid}. Only the
+// fields filfoxy uses are modelled.
+type Message struct {
+	Cid          string         `json:"cid"`
+	Height       int            `json:"height"`
+	Timestamp    int            `json:"timestamp"`
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Nonce        int            `json:"nonce"`
+	Value        string         `json:"value"` // in attoFIL as a string
+	GasLimit     int64          `json:"gasLimit"`
+	GasFeeCap    string         `json:"gasFeeCap"`
+	GasPremium   string         `json:"gasPremium"`
+	Method       string         `json:"method"`
+	MethodNumber int            `json:"methodNumber"`
+	BaseFee      string         `json:"baseFee"`
+	Receipt      MessageReceipt `json:"receipt"`
+	Fee          MessageFee     `json:"fee"`
+}
+
+// MessageReceipt is the execution result of a message.
+type MessageReceipt struct {
+	ExitCode int    `json:"exitCode"`
+	Return   string `json:"return"`
+	GasUsed  int64  `json:"gasUsed"`
+}
+
+// MessageFee breaks down the gas fees paid by a message, in attoFIL strings.
+type MessageFee struct {
+	BaseFeeBurn        string `json:"baseFeeBurn"`
+	OverEstimationBurn string `json:"overEstimationBurn"`
+	MinerPenalty       string `json:"minerPenalty"`
+	MinerTip           string `json:"minerTip"`
+	Refund             string `json:"refund"`
+}
+
+// Message retrieves the details of the message with the given CID.
+func (c *Client) Message(ctx context.Context, cid string) (*Message, error) {
+	var msg Message
+	if err := c.get(ctx, "/message/"+cid, nil, &msg); err != nil {
+		return nil, fmt.Errorf("retrieving message %s: %w", cid, err)
+	}
+	return &msg, nil
+}
+
+// MessagesResponse is a single page from the /address/{address}/messages
+// endpoint.
+type MessagesResponse struct {
+	TotalCount int              `json:"totalCount"`
+	Messages   []MessageSummary `json:"messages"`
+	Methods    []string         `json:"methods"`
+}
+
+// MessageSummary is a message as listed for an address.
+type MessageSummary struct {
+	Cid          string         `json:"cid"`
+	Height       int            `json:"height"`
+	Timestamp    int            `json:"timestamp"`
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Nonce        int            `json:"nonce"`
+	Value        string         `json:"value"` // in attoFIL as a string
+	Method       string         `json:"method"`
+	MethodNumber int            `json:"methodNumber"`
+	Receipt      MessageReceipt `json:"receipt"`
+}
+
+// Messages returns an iterator over the messages sent or received by
+// address, newest first. Iteration stops after the first error.
+func (c *Client) Messages(ctx context.Context, address string) iter.Seq2[MessageSummary, error] {
+	return list(ctx, c, "messages", "/address/"+address+"/messages", nil,
+		func(r *MessagesResponse) (int, []MessageSummary) { return r.TotalCount, r.Messages })
+}
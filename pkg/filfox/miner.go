@@ -0,0 +1,60 @@
+package filfox
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+// Block is a block mined by a storage provider, from /address/{miner}/blocks.
+type Block struct {
+	Cid          string `json:"cid"`
+	Height       int    `json:"height"`
+	Timestamp    int    `json:"timestamp"`
+	Size         int    `json:"size"`
+	WinCount     int    `json:"winCount"`
+	MessageCount int    `json:"messageCount"`
+	Reward       string `json:"reward"`  // in attoFIL as a string
+	Penalty      string `json:"penalty"` // in attoFIL as a string
+}
+
+// BlocksResponse is a single page from the /address/{miner}/blocks endpoint.
+type BlocksResponse struct {
+	TotalCount int     `json:"totalCount"`
+	Blocks     []Block `json:"blocks"`
+}
+
+// Blocks returns an iterator over the blocks mined by miner, newest first.
+func (c *Client) Blocks(ctx context.Context, miner string) iter.Seq2[Block, error] {
+	return list(ctx, c, "blocks", "/address/"+miner+"/blocks", nil,
+		func(r *BlocksResponse) (int, []Block) { return r.TotalCount, r.Blocks })
+}
+
+// BalanceStat is a sample of an actor's balance breakdown, from
+// /address/{address}/balance-stats. Amounts are attoFIL strings; the locked
+// fields are only reported for miners.
+type BalanceStat struct {
+	Height              int    `json:"height"`
+	Timestamp           int    `json:"timestamp"`
+	Balance             string `json:"balance"`
+	AvailableBalance    string `json:"availableBalance"`
+	SectorPledgeBalance string `json:"sectorPledgeBalance"`
+	PreCommitDeposits   string `json:"preCommitDeposits"`
+	VestingFunds        string `json:"vestingFunds"`
+}
+
+// BalanceStats returns samples of address's balance over the trailing
+// duration (in Filfox notation, e.g. "24h", "30d" or "1y"), oldest first.
+func (c *Client) BalanceStats(ctx context.Context, address, duration string, samples int) ([]BalanceStat, error) {
+	q := url.Values{
+		"duration": {duration},
+		"samples":  {strconv.Itoa(samples)},
+	}
+	var stats []BalanceStat
+	if err := c.get(ctx, "/address/"+address+"/balance-stats", q, &stats); err != nil {
+		return nil, fmt.Errorf("retrieving balance stats for %s: %w", address, err)
+	}
+	return stats, nil
+}
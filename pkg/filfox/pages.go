@@ -0,0 +1,49 @@
+package filfox
+
+import "errors"
+
+const (
+	// DefaultConcurrency is the number of pages fetched in parallel.
+	DefaultConcurrency = 4
+
+	// DefaultMaxPages caps how many pages a single fetch may request, as a
+	// safety net against a nonsensical totalCount.
+	DefaultMaxPages = 10_000
+)
+
+// ErrInconsistentPagination is returned when the API's paging does not add
+// up, e.g. an empty page before totalCount records have been seen. Retrying
+// later usually helps, since it tends to happen while new records land.
+var ErrInconsistentPagination = errors.New("inconsistent pagination from API")
+
+// WithConcurrency sets how many pages may be fetched ahead in parallel once
+// the total number of pages is known. One fetches pages strictly in sequence.
+func WithConcurrency(n int) Option {
+	return func(c *Client) { c.concurrency = max(n, 1) }
+}
+
+// pageCount returns the number of pages needed to hold total records.
+func (c *Client) pageCount(total int) int {
+	return (total + c.pageSize - 1) / c.pageSize
+}
+
+// WithMaxPages sets the maximum number of pages a single fetch may request.
+func WithMaxPages(n int) Option {
+	return func(c *Client) { c.maxPages = n }
+}
+
+// Progress is how far a Transfers fetch has got, reported after each page.
+type Progress struct {
+	Address      string
+	Pages        int // fetched or restored from a checkpoint so far
+	TotalPages   int // may grow if the history does during the fetch
+	Records      int // received so far, before height filtering
+	TotalRecords int
+	Done         bool // the last report of a completed fetch
+}
+
+// WithProgress calls fn with the Progress of Transfers fetches as each page
+// is consumed, in order.
+func WithProgress(fn func(Progress)) Option {
+	return func(c *Client) { c.progress = fn }
+}
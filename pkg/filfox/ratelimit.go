@@ -0,0 +1,83 @@
+package filfox
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting how often requests are issued. A
+// single RateLimiter may be shared between several Clients so that their
+// combined traffic stays under the limit.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time to accrue one token, zero if unlimited
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// NewRateLimiter returns a limiter allowing rps requests per second on
+// average, with bursts of up to burst requests. A non-positive rps places no
+// limit at all, and one so small a token would take longer than the longest
+// Duration to accrue is held to that.
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	burst = max(burst, 1)
+	var interval time.Duration
+	if rps > 0 {
+		interval = duration(float64(time.Second) / rps)
+	}
+	return &RateLimiter{
+		interval: interval,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// duration converts ns nanoseconds to a Duration, held to the longest one
+// rather than overflowing.
+func duration(ns float64) time.Duration {
+	if ns >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(ns)
+}
+
+// Wait blocks until a request may be issued or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.last))/float64(l.interval))
+	l.last = now
+
+	// Reserve a token, possibly going into debt that later callers wait out.
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = duration(-l.tokens * float64(l.interval))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-t.C:
+		return nil
+	}
+}
+
+// WithRateLimiter throttles all requests made by the Client through l.
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(c *Client) { c.limiter = l }
+}
@@ -0,0 +1,36 @@
+package filfox
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterNonPositiveRate(t *testing.T) {
+	for _, rps := range []float64{0, -1, math.Inf(-1), math.NaN()} {
+		l := NewRateLimiter(rps, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		for range 100 {
+			if err := l.Wait(ctx); err != nil {
+				t.Fatalf("rps %v: %v, want no limit", rps, err)
+			}
+		}
+		cancel()
+	}
+}
+
+func TestRateLimiterTinyRate(t *testing.T) {
+	l := NewRateLimiter(1e-12, 1)
+	if l.interval <= 0 {
+		t.Fatalf("interval = %v, want the longest Duration", l.interval)
+	}
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("second request went through, want it held past the deadline")
+	}
+}
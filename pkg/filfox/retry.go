@@ -0,0 +1,66 @@
+package filfox
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the number of times a transient failure is retried.
+	DefaultMaxRetries = 3
+
+	defaultRetryBaseDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay  = 30 * time.Second
+
+	// Rate limited requests are retried separately from transient failures,
+	// since waiting out the limit is expected to succeed.
+	defaultRateLimitDelay = 10 * time.Second
+	maxRateLimitRetries   = 10
+)
+
+// WithMaxRetries sets how many times a request is retried after a transient
+// failure (network error or 5xx response). Zero disables retries.
+func WithMaxRetries(n int) Option {
+	return func(c *Client) { c.maxRetries = n }
+}
+
+// WithRetryBackoff sets the initial and maximum delay between retries. The
+// delay doubles after each failed attempt up to max.
+func WithRetryBackoff(base, max time.Duration) Option {
+	return func(c *Client) {
+		c.retryBaseDelay = base
+		c.retryMaxDelay = max
+	}
+}
+
+// backoff returns the delay before retry number attempt (zero-indexed), using
+// exponential growth with jitter so concurrent clients don't retry in lockstep.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.retryBaseDelay << attempt
+	if d <= 0 || d > c.retryMaxDelay {
+		d = c.retryMaxDelay
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+// parseRetryAfter interprets a Retry-After header value, which may be either a
+// number of seconds or an HTTP date. It returns -1 if the value is absent or
+// malformed.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return -1
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return -1
+}
@@ -0,0 +1,89 @@
+package filfox
+
+import (
+	"context"
+	"iter"
+)
+
+// TokenTransfer is an FRC-20/ERC-20 token transfer, from
+// /address/{address}/token-transfers.
+type TokenTransfer struct {
+	Height    int    `json:"height"`
+	Timestamp int    `json:"timestamp"`
+	Message   string `json:"message"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Token     string `json:"token"` // contract address
+	Name      string `json:"tokenName"`
+	Symbol    string `json:"symbol"`
+	Decimals  int    `json:"decimals"`
+	Value     string `json:"value"` // in the token's smallest unit as a string
+}
+
+// TokenTransfersResponse is a single page from the
+// /address/{address}/token-transfers endpoint.
+type TokenTransfersResponse struct {
+	TotalCount int             `json:"totalCount"`
+	Transfers  []TokenTransfer `json:"transfers"`
+}
+
+// TokenTransfers returns an iterator over the token transfers sent or
+// received by address, newest first.
+func (c *Client) TokenTransfers(ctx context.Context, address string) iter.Seq2[TokenTransfer, error] {
+	return list(ctx, c, "token transfers", "/address/"+address+"/token-transfers", nil,
+		func(r *TokenTransfersResponse) (int, []TokenTransfer) { return r.TotalCount, r.Transfers })
+}
+
+// NFTTransfer is an ERC-721 token transfer, from
+// /address/{address}/nft-transfers.
+type NFTTransfer struct {
+	Height    int    `json:"height"`
+	Timestamp int    `json:"timestamp"`
+	Message   string `json:"message"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Contract  string `json:"contract"`
+	Name      string `json:"name"`
+	Symbol    string `json:"symbol"`
+	TokenID   string `json:"tokenId"`
+}
+
+// NFTTransfersResponse is a single page from the
+// /address/{address}/nft-transfers endpoint.
+type NFTTransfersResponse struct {
+	TotalCount int           `json:"totalCount"`
+	Transfers  []NFTTransfer `json:"transfers"`
+}
+
+// NFTTransfers returns an iterator over the NFT transfers sent or received by
+// address, newest first.
+func (c *Client) NFTTransfers(ctx context.Context, address string) iter.Seq2[NFTTransfer, error] {
+	return list(ctx, c, "NFT transfers", "/address/"+address+"/nft-transfers", nil,
+		func(r *NFTTransfersResponse) (int, []NFTTransfer) { return r.TotalCount, r.Transfers })
+}
+
+// InternalTransfer is a movement of FIL made by a contract during execution
+// of a message, from /address/{address}/internal-transfers. These don't appear
+// among the address's regular transfers.
+type InternalTransfer struct {
+	Height    int    `json:"height"`
+	Timestamp int    `json:"timestamp"`
+	Message   string `json:"message"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Value     string `json:"value"` // in attoFIL as a string
+}
+
+// InternalTransfersResponse is a single page from the
+// /address/{address}/internal-transfers endpoint.
+type InternalTransfersResponse struct {
+	TotalCount int                `json:"totalCount"`
+	Transfers  []InternalTransfer `json:"transfers"`
+}
+
+// InternalTransfers returns an iterator over the contract-level transfers
+// sent or received by address, newest first.
+func (c *Client) InternalTransfers(ctx context.Context, address string) iter.Seq2[InternalTransfer, error] {
+	return list(ctx, c, "internal transfers", "/address/"+address+"/internal-transfers", nil,
+		func(r *InternalTransfersResponse) (int, []InternalTransfer) { return r.TotalCount, r.Transfers })
+}
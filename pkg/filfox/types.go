@@ -0,0 +1,22 @@
+package filfox
+
+// TransfersResponse is a single page from the /address/{address}/transfers endpoint.
+type TransfersResponse struct {
+	TotalCount int        `json:"totalCount"`
+	Transfers  []Transfer `json:"transfers"`
+	Types      []string   `json:"types"`
+}
+
+func (*TransfersResponse) schemaChecked() {}
+
+// Transfer is a single transfer record as reported by Filfox. A message may
+// produce several records, e.g. a send plus its miner and burn fees.
+type Transfer struct {
+	Height    int    `json:"height"`
+	Timestamp int    `json:"timestamp"`
+	Message   string `json:"message"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Value     string `json:"value"` // in attoFIL as a string
+	Type      string `json:"type"`  // [send, receive, miner-fee, burn-fee]
+}
@@ -0,0 +1,168 @@
+// Package httpcache implements an on-disk HTTP cache as an http.RoundTripper.
+//
+// Responses are keyed by URL and revalidated with conditional requests using
+// their ETag and Last-Modified validators, so an unchanged page costs a 304
+// rather than a full download.
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Transport is a caching http.RoundTripper.
+type Transport struct {
+	dir  string
+	next http.RoundTripper
+
+	// MaxAge is how long a stored response is served without revalidating it.
+	// Zero always revalidates.
+	MaxAge time.Duration
+}
+
+// New returns a Transport storing responses in dir and forwarding requests to
+// next, or http.DefaultTransport if nil.
+func New(dir string, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{dir: dir, next: next}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.next.RoundTrip(req)
+	}
+
+	path := t.path(req)
+	cached, stored := t.load(path, req)
+	if cached != nil && t.MaxAge > 0 && time.Since(stored) < t.MaxAge {
+		slog.Debug("HTTP cache hit", "url", req.URL.String())
+		return cached, nil
+	}
+
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := cached.Header.Get("Last-Modified"); lm != "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		if cached != nil {
+			cached.Body.Close()
+		}
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		slog.Debug("HTTP cache revalidated", "url", req.URL.String())
+		resp.Body.Close()
+		now := time.Now()
+		os.Chtimes(path, now, now) // restart the MaxAge window
+		return cached, nil
+	case resp.StatusCode == http.StatusOK && hasValidator(resp.Header, t.MaxAge):
+		if cached != nil {
+			cached.Body.Close()
+		}
+		return t.store(path, resp)
+	default:
+		if cached != nil {
+			cached.Body.Close()
+		}
+		return resp, nil
+	}
+}
+
+// hasValidator reports whether a response is worth caching: either it can be
+// revalidated, or it can be served fresh for a while.
+func hasValidator(h http.Header, maxAge time.Duration) bool {
+	return h.Get("ETag") != "" || h.Get("Last-Modified") != "" || maxAge > 0
+}
+
+func (t *Transport) path(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String()))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:]))
+}
+
+// load returns the stored response for path and when it was last validated,
+// or nil if there is none.
+func (t *Transport) load(path string, req *http.Request) (*http.Response, time.Time) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, time.Time{}
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, time.Time{}
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(f), req)
+	if err != nil {
+		f.Close()
+		slog.Warn("Ignoring corrupt HTTP cache entry", "path", path, "err", err)
+		return nil, time.Time{}
+	}
+	resp.Body = readCloser{resp.Body, f}
+	return resp, info.ModTime()
+}
+
+// store saves resp to path and returns an equivalent response for the caller.
+func (t *Transport) store(path string, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.TransferEncoding = nil
+
+	if err := t.write(path, resp); err != nil {
+		slog.Warn("Failed to write HTTP cache entry", "path", path, "err", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// write atomically replaces the cache entry at path with resp.
+func (t *Transport) write(path string, resp *http.Response) error {
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(t.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := resp.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// readCloser closes the underlying file along with the response body.
+type readCloser struct {
+	io.Reader
+	f *os.File
+}
+
+func (rc readCloser) Close() error { return rc.f.Close() }
@@ -0,0 +1,105 @@
+package price
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+)
+
+// DefaultCoinGeckoURL is the public CoinGecko API endpoint.
+const DefaultCoinGeckoURL = "https://api.coingecko.com/api/v3"
+
+// CoinGecko retrieves FIL prices from the CoinGecko API. Its history is
+// hourly for ranges of up to 90 days and daily beyond.
+type CoinGecko struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewCoinGecko returns a Source for the CoinGecko API at baseURL, or
+// DefaultCoinGeckoURL if empty. apiKey, if set, is sent as a demo API key.
+func NewCoinGecko(hc *http.Client, baseURL, apiKey string) *CoinGecko {
+	if baseURL == "" {
+		baseURL = DefaultCoinGeckoURL
+	}
+	return &CoinGecko{baseURL: baseURL, apiKey: apiKey, httpClient: hc}
+}
+
+func (c *CoinGecko) Name() string { return "coingecko" }
+
+func (c *CoinGecko) History(ctx context.Context, currency string, from, to time.Time) (History, error) {
+	q := url.Values{
+		"vs_currency": {strings.ToLower(currency)},
+		// A day early, so the first transfers have a price before them
+		"from": {strconv.FormatInt(from.AddDate(0, 0, -1).Unix(), 10)},
+		"to":   {strconv.FormatInt(to.Unix(), 10)},
+	}
+	var resp struct {
+		Prices [][2]json.Number `json:"prices"` // [unix milliseconds, price]
+	}
+	if err := c.get(ctx, "/coins/filecoin/market_chart/range?"+q.Encode(), &resp); err != nil {
+		return nil, fmt.Errorf("retrieving coingecko price history: %w", err)
+	}
+
+	h := make(History, 0, len(resp.Prices))
+	for _, p := range resp.Prices {
+		ms, err := p[0].Int64()
+		if err != nil {
+			return nil, fmt.Errorf("coingecko price history: invalid time %q", p[0])
+		}
+		price, err := parseRat(p[1].String())
+		if err != nil {
+			return nil, fmt.Errorf("coingecko price history: %w", err)
+		}
+		h = append(h, Point{Time: time.UnixMilli(ms), Price: price})
+	}
+	if len(h) == 0 {
+		return nil, fmt.Errorf("coingecko has no %s prices of FIL from %s to %s", currency, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	}
+	return h, nil
+}
+
+func (c *CoinGecko) Spot(ctx context.Context, currency string) (*big.Rat, error) {
+	currency = strings.ToLower(currency)
+	q := url.Values{"ids": {"filecoin"}, "vs_currencies": {currency}}
+	var resp map[string]map[string]json.Number
+	if err := c.get(ctx, "/simple/price?"+q.Encode(), &resp); err != nil {
+		return nil, fmt.Errorf("retrieving coingecko spot price: %w", err)
+	}
+	price, ok := resp["filecoin"][currency]
+	if !ok {
+		return nil, fmt.Errorf("coingecko has no %s price of FIL", currency)
+	}
+	return parseRat(price.String())
+}
+
+func (c *CoinGecko) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("x-cg-demo-api-key", c.apiKey)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return filfox.NewStatusError(resp)
+	}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	return dec.Decode(v)
+}
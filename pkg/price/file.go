@@ -0,0 +1,78 @@
+package price
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// File serves FIL prices from a CSV file of date,price rows in a single
+// currency, such as a history exported from an exchange. Dates are
+// YYYY-MM-DD days in UTC or RFC 3339 times; a header row is skipped.
+type File struct {
+	path    string
+	history History
+}
+
+// OpenFile reads the prices in the file at path.
+func OpenFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var h History
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if len(rec) < 2 || strings.HasPrefix(rec[0], "#") {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, rec[0])
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, rec[0]); err != nil {
+				if line == 1 {
+					continue // header
+				}
+				return nil, fmt.Errorf("%s:%d: date %q is neither YYYY-MM-DD nor RFC 3339", path, line, rec[0])
+			}
+		}
+		price, err := parseRat(strings.TrimSpace(rec[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		h = append(h, Point{Time: t, Price: price})
+	}
+	if len(h) == 0 {
+		return nil, fmt.Errorf("%s: no prices", path)
+	}
+	slices.SortStableFunc(h, func(a, b Point) int { return a.Time.Compare(b.Time) })
+	return &File{path: path, history: h}, nil
+}
+
+func (f *File) Name() string { return f.path }
+
+// History returns every price in the file, whatever currency is asked for.
+func (f *File) History(ctx context.Context, currency string, from, to time.Time) (History, error) {
+	return f.history, nil
+}
+
+// Spot returns the latest price in the file.
+func (f *File) Spot(ctx context.Context, currency string) (*big.Rat, error) {
+	return f.history[len(f.history)-1].Price, nil
+}
@@ -0,0 +1,75 @@
+// Package price looks up what FIL was worth in a fiat currency, for exports
+// that value transfers at the rate of their day.
+package price
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+)
+
+// A Source retrieves FIL exchange rates.
+type Source interface {
+	// Name identifies the source, e.g. "coingecko".
+	Name() string
+
+	// History returns the prices of FIL in currency, such as USD, from at
+	// least the day of from to to.
+	History(ctx context.Context, currency string, from, to time.Time) (History, error)
+
+	// Spot returns the current price of FIL in currency.
+	Spot(ctx context.Context, currency string) (*big.Rat, error)
+}
+
+// A Point is the price of FIL at a time.
+type Point struct {
+	Time  time.Time
+	Price *big.Rat
+}
+
+// History is a series of prices, oldest first.
+type History []Point
+
+// At returns the price at t: the latest one at or before it, or the
+// earliest if t precedes them all. It is nil for an empty history. Covers
+// checks that times are near enough the history for these to be of their day.
+func (h History) At(t time.Time) *big.Rat {
+	if len(h) == 0 {
+		return nil
+	}
+	i, found := slices.BinarySearchFunc(h, t, func(p Point, t time.Time) int { return p.Time.Compare(t) })
+	if !found {
+		i--
+	}
+	return h[max(i, 0)].Price
+}
+
+// maxGap is how far a time may be from the history for At to still value it:
+// a day, since prices are daily.
+const maxGap = 24 * time.Hour
+
+// Covers reports an error unless h has prices within a day of from and of
+// to, so that At values every time between them at a price of its day.
+func (h History) Covers(from, to time.Time) error {
+	const day = "2006-01-02"
+	switch {
+	case len(h) == 0:
+		return fmt.Errorf("no prices from %s to %s", from.Format(day), to.Format(day))
+	case h[0].Time.Sub(from) > maxGap:
+		return fmt.Errorf("prices start on %s, after %s", h[0].Time.Format(day), from.Format(day))
+	case to.Sub(h[len(h)-1].Time) > maxGap:
+		return fmt.Errorf("prices end on %s, before %s", h[len(h)-1].Time.Format(day), to.Format(day))
+	}
+	return nil
+}
+
+// parseRat reads a decimal price as reported by an API or file.
+func parseRat(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid price %q", s)
+	}
+	return r, nil
+}
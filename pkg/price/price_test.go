@@ -0,0 +1,32 @@
+package price
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestHistoryCovers(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	h := History{{day(10), big.NewRat(5, 1)}, {day(11), big.NewRat(6, 1)}, {day(12), big.NewRat(7, 1)}}
+
+	tests := []struct {
+		name     string
+		h        History
+		from, to time.Time
+		ok       bool
+	}{
+		{"within", h, day(10).Add(time.Hour), day(12).Add(23 * time.Hour), true},
+		{"a day early", h, day(9), day(11), true},
+		{"starts too late", h, day(8), day(11), false},
+		{"ends too early", h, day(10), day(14), false},
+		{"empty", nil, day(10), day(10), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.h.Covers(tt.from, tt.to); (err == nil) != tt.ok {
+				t.Errorf("Covers = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
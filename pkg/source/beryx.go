@@ -0,0 +1,144 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultBeryxURL is the Zondax Beryx mainnet data API endpoint.
+const DefaultBeryxURL = "https://api.zondax.ch/fil/data/v3/mainnet"
+
+// Beryx retrieves transfers from the Zondax Beryx API, which requires a
+// bearer token.
+type Beryx struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewBeryx returns a TransferSource for the Beryx API at baseURL, or
+// DefaultBeryxURL if empty.
+func NewBeryx(hc *http.Client, baseURL, token string) *Beryx {
+	if baseURL == "" {
+		baseURL = DefaultBeryxURL
+	}
+	return &Beryx{baseURL: baseURL, token: token, httpClient: hc}
+}
+
+func (b *Beryx) Name() string { return "beryx" }
+
+type beryxResponse struct {
+	Transactions []beryxTransaction `json:"transactions"`
+	NextCursor   string             `json:"next_cursor"`
+}
+
+type beryxTransaction struct {
+	Height    int         `json:"height"`
+	TxCid     string      `json:"tx_cid"`
+	TxFrom    string      `json:"tx_from"`
+	TxTo      string      `json:"tx_to"`
+	Amount    json.Number `json:"amount"` // attoFIL, absolute
+	TxType    string      `json:"tx_type"`
+	Status    string      `json:"status"`
+	Timestamp time.Time   `json:"tx_timestamp"`
+}
+
+// Beryx reports fees as separate "Fee" transactions; those sent to the burnt
+// funds actor are the base fee burn, the remainder is the miner tip.
+const burntFundsActor = "f099"
+
+func (b *Beryx) Transfers(ctx context.Context, address string) ([]Record, error) {
+	var records []Record
+	cursor := ""
+	seen := make(map[string]bool)
+
+	for page := 0; ; page++ {
+		if page >= maxPages {
+			return nil, fmt.Errorf("beryx pagination exceeded %d pages, aborting", maxPages)
+		}
+		q := url.Values{}
+		q.Set("limit", "1000")
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"/transactions/address/"+address+"?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if b.token != "" {
+			req.Header.Set("Authorization", "Bearer "+b.token)
+		}
+
+		var resp beryxResponse
+		if err := doJSON(b.httpClient, req, &resp); err != nil {
+			return nil, fmt.Errorf("retrieving beryx transactions: %w", err)
+		}
+
+		for _, tx := range resp.Transactions {
+			if tx.Status != "" && tx.Status != "Ok" && tx.TxType != "Fee" {
+				continue // failed messages move no value, only their fees count
+			}
+			if tx.TxFrom != address && tx.TxTo != address {
+				continue // internal calls of messages involving address
+			}
+
+			var typ string
+			switch {
+			case tx.TxType == "Fee" && tx.TxTo == burntFundsActor:
+				typ = "burn-fee"
+			case tx.TxType == "Fee":
+				typ = "miner-fee"
+			case tx.TxFrom == address:
+				typ = "send"
+			default:
+				typ = "receive"
+			}
+
+			records = append(records, Record{
+				Height:    tx.Height,
+				Timestamp: tx.Timestamp.Unix(),
+				Message:   tx.TxCid,
+				From:      tx.TxFrom,
+				To:        tx.TxTo,
+				Value:     signed(tx.Amount.String(), tx.TxFrom, address),
+				Type:      typ,
+			})
+		}
+
+		if resp.NextCursor == "" || len(resp.Transactions) == 0 {
+			break
+		}
+		if seen[resp.NextCursor] {
+			return nil, fmt.Errorf("beryx returned cursor %q twice, pagination is not progressing", resp.NextCursor)
+		}
+		seen[resp.NextCursor] = true
+		cursor = resp.NextCursor
+	}
+
+	return records, nil
+}
+
+type beryxTipset struct {
+	Height    int       `json:"height"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (b *Beryx) ChainHead(ctx context.Context) (Head, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"/tipset/latest", nil)
+	if err != nil {
+		return Head{}, err
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+
+	var ts beryxTipset
+	if err := doJSON(b.httpClient, req, &ts); err != nil {
+		return Head{}, err
+	}
+	return Head{Height: ts.Height, Timestamp: ts.Timestamp}, nil
+}
@@ -0,0 +1,96 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+)
+
+// ErrCircuitOpen is returned by a Breaker that has stopped calling its
+// backend after too many consecutive failures.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Breaker wraps a TransferSource and stops calling it once threshold
+// consecutive calls have failed, so a degraded backend produces one clear
+// diagnosis instead of a confusing failure for every remaining wallet.
+type Breaker struct {
+	src       TransferSource
+	threshold int
+
+	mu       sync.Mutex
+	failures int
+	lastErr  error
+}
+
+// NewBreaker returns a Breaker around src that trips after threshold
+// consecutive failures.
+func NewBreaker(src TransferSource, threshold int) *Breaker {
+	return &Breaker{src: src, threshold: max(threshold, 1)}
+}
+
+func (b *Breaker) Name() string { return b.src.Name() }
+
+// Unwrap returns the wrapped TransferSource.
+func (b *Breaker) Unwrap() TransferSource { return b.src }
+
+func (b *Breaker) Transfers(ctx context.Context, address string) ([]Record, error) {
+	b.mu.Lock()
+	if b.failures >= b.threshold {
+		err := fmt.Errorf("%w: %s failed %d consecutive times, last error: %w", ErrCircuitOpen, b.src.Name(), b.failures, b.lastErr)
+		b.mu.Unlock()
+		return nil, err
+	}
+	b.mu.Unlock()
+
+	records, err := b.src.Transfers(ctx, address)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case err == nil || errors.Is(err, filfox.ErrNotFound):
+		// An unknown address is a healthy answer, not a backend failure
+		b.failures = 0
+	case ctx.Err() == nil:
+		// Our own cancellation says nothing about the backend's health
+		b.failures++
+		b.lastErr = err
+		if b.failures == b.threshold {
+			slog.Error("Backend appears to be down, not sending further requests", "backend", b.src.Name(), "failures", b.failures, "err", err)
+		}
+	}
+	return records, err
+}
+
+// Failover tries primary, falling back to secondary when it fails.
+type Failover struct {
+	primary, secondary TransferSource
+}
+
+// NewFailover returns a TransferSource that uses secondary whenever primary
+// returns an error.
+func NewFailover(primary, secondary TransferSource) *Failover {
+	return &Failover{primary: primary, secondary: secondary}
+}
+
+func (f *Failover) Name() string { return f.primary.Name() + "+" + f.secondary.Name() }
+
+// Unwrap returns the primary TransferSource.
+func (f *Failover) Unwrap() TransferSource { return f.primary }
+
+func (f *Failover) Transfers(ctx context.Context, address string) ([]Record, error) {
+	records, err := f.primary.Transfers(ctx, address)
+	if err == nil || ctx.Err() != nil || errors.Is(err, filfox.ErrNotFound) {
+		return records, err
+	}
+
+	slog.Warn("Primary backend failed, failing over", "primary", f.primary.Name(), "secondary", f.secondary.Name(), "err", err)
+	records, err2 := f.secondary.Transfers(ctx, address)
+	if err2 != nil {
+		return nil, fmt.Errorf("%s: %w; %s: %w", f.primary.Name(), err, f.secondary.Name(), err2)
+	}
+	return records, nil
+}
@@ -0,0 +1,37 @@
+package source
+
+import (
+	"fmt"
+	"time"
+)
+
+// Mainnet epoch timing, for converting between heights and wall clock time.
+var (
+	genesis       = time.Unix(1598306400, 0).UTC()
+	epochDuration = 30 * time.Second
+)
+
+// HeightTime returns the time of the epoch at height on mainnet.
+func HeightTime(height int) time.Time {
+	return genesis.Add(time.Duration(height) * epochDuration)
+}
+
+// HeightsBetween returns the range of mainnet epochs whose times fall within
+// [from, to). Either bound may be zero to leave that side open. It fails if
+// no epoch after genesis is before to, as a range cannot be empty.
+func HeightsBetween(from, to time.Time) (HeightRange, error) {
+	var r HeightRange
+	if !from.IsZero() {
+		// The first epoch at or after from
+		r.From = max(int((from.Sub(genesis)+epochDuration-1)/epochDuration), 0)
+	}
+	if !to.IsZero() {
+		// The last epoch before to
+		r.To = int((to.Sub(genesis)+epochDuration-1)/epochDuration) - 1
+		if r.To < 1 {
+			return HeightRange{}, fmt.Errorf("the range ends before mainnet's first epoch after genesis, at %s",
+				HeightTime(1).Format(time.RFC3339))
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,44 @@
+package source
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeightsBetween(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     HeightRange
+		wantErr  bool
+	}{
+		{name: "open", want: HeightRange{}},
+		{name: "a year", from: day("2024-01-01"), to: day("2025-01-01"), want: HeightRange{From: 3525360, To: 4579439}},
+		{name: "from before genesis", from: day("2019-01-01"), to: day("2024-01-01"), want: HeightRange{From: 0, To: 3525359}},
+		{name: "to before genesis", to: day("2020-01-01"), wantErr: true},
+		{name: "to at genesis", to: genesis, wantErr: true},
+		{name: "to within the genesis epoch", to: genesis.Add(epochDuration), wantErr: true},
+		{name: "to after the first epoch", to: genesis.Add(2 * epochDuration), want: HeightRange{To: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HeightsBetween(tt.from, tt.to)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("HeightsBetween = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("HeightsBetween = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
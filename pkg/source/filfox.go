@@ -0,0 +1,278 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+)
+
+// Filfox retrieves transfers from the Filfox explorer API.
+type Filfox struct {
+	client *filfox.Client
+}
+
+// NewFilfox returns a TransferSource backed by client.
+func NewFilfox(client *filfox.Client) *Filfox {
+	return &Filfox{client: client}
+}
+
+func (f *Filfox) Name() string { return "filfox" }
+
+func (f *Filfox) Transfers(ctx context.Context, address string) ([]Record, error) {
+	var records []Record
+	for x, err := range f.client.Transfers(ctx, address) {
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, Record{
+			Height:    x.Height,
+			Timestamp: int64(x.Timestamp),
+			Message:   x.Message,
+			From:      x.From,
+			To:        x.To,
+			Value:     x.Value,
+			Type:      x.Type,
+		})
+	}
+	return records, nil
+}
+
+func (f *Filfox) ChainHead(ctx context.Context) (Head, error) {
+	ts, err := f.client.ChainHead(ctx)
+	if err != nil {
+		return Head{}, err
+	}
+	return Head{Height: ts.Height, Timestamp: time.Unix(int64(ts.Timestamp), 0)}, nil
+}
+
+func (f *Filfox) MessageDetails(ctx context.Context, cid string) (MessageDetails, error) {
+	msg, err := f.client.Message(ctx, cid)
+	if err != nil {
+		return MessageDetails{}, err
+	}
+	return MessageDetails{
+		GasLimit:    msg.GasLimit,
+		GasFeeCap:   msg.GasFeeCap,
+		GasPremium:  msg.GasPremium,
+		BaseFeeBurn: msg.Fee.BaseFeeBurn,
+		ExitCode:    msg.Receipt.ExitCode,
+
+		OverEstimationBurn: msg.Fee.OverEstimationBurn,
+		MinerTip:           msg.Fee.MinerTip,
+	}, nil
+}
+
+func (f *Filfox) Messages(ctx context.Context, address string) ([]Message, error) {
+	var msgs []Message
+	for m, err := range f.client.Messages(ctx, address) {
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, Message{
+			Cid:          m.Cid,
+			Height:       m.Height,
+			Timestamp:    int64(m.Timestamp),
+			From:         m.From,
+			To:           m.To,
+			Nonce:        m.Nonce,
+			Value:        m.Value,
+			MethodNumber: m.MethodNumber,
+			Method:       DecodeMethod(m.MethodNumber, m.Method),
+			ExitCode:     m.Receipt.ExitCode,
+		})
+	}
+	return msgs, nil
+}
+
+func (f *Filfox) BlockRewards(ctx context.Context, miner string) ([]BlockReward, error) {
+	var rewards []BlockReward
+	for b, err := range f.client.Blocks(ctx, miner) {
+		if err != nil {
+			return nil, err
+		}
+		rewards = append(rewards, BlockReward{
+			Block:     b.Cid,
+			Height:    b.Height,
+			Timestamp: int64(b.Timestamp),
+			WinCount:  b.WinCount,
+			Reward:    b.Reward,
+			Penalty:   b.Penalty,
+		})
+	}
+	return rewards, nil
+}
+
+// PledgeHistory samples the miner's locked collateral daily since before
+// since.
+func (f *Filfox) PledgeHistory(ctx context.Context, miner string, since time.Time) ([]PledgeSample, error) {
+	duration, days := balanceWindow(since, time.Now())
+	stats, err := f.client.BalanceStats(ctx, miner, duration, days)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]PledgeSample, 0, len(stats))
+	for _, s := range stats {
+		locked := new(big.Int)
+		for _, v := range []string{s.SectorPledgeBalance, s.PreCommitDeposits} {
+			if v == "" {
+				continue
+			}
+			n, ok := new(big.Int).SetString(v, 10)
+			if !ok {
+				return nil, fmt.Errorf("parsing locked balance %q at height %d", v, s.Height)
+			}
+			locked.Add(locked, n)
+		}
+		samples = append(samples, PledgeSample{
+			Height:    s.Height,
+			Timestamp: int64(s.Timestamp),
+			Locked:    locked.String(),
+		})
+	}
+	return samples, nil
+}
+
+func (f *Filfox) Vesting(ctx context.Context, address string) (Vesting, error) {
+	a, err := f.client.Address(ctx, address)
+	if err != nil {
+		return Vesting{}, err
+	}
+	m := a.Multisig
+	if m == nil || m.InitialBalance == "" {
+		return Vesting{}, nil
+	}
+	initial, ok := new(big.Int).SetString(m.InitialBalance, 10)
+	if !ok {
+		return Vesting{}, fmt.Errorf("parsing initial balance %q of %s", m.InitialBalance, address)
+	}
+	return Vesting{
+		InitialBalance: initial,
+		StartHeight:    m.StartEpoch,
+		UnlockDuration: m.UnlockDuration,
+	}, nil
+}
+
+func (f *Filfox) TokenTransfers(ctx context.Context, address string) ([]TokenTransfer, error) {
+	var xfers []TokenTransfer
+	for t, err := range f.client.TokenTransfers(ctx, address) {
+		if err != nil {
+			return nil, err
+		}
+		xfers = append(xfers, TokenTransfer{
+			Height:    t.Height,
+			Timestamp: int64(t.Timestamp),
+			Message:   t.Message,
+			From:      t.From,
+			To:        t.To,
+			Contract:  t.Token,
+			Symbol:    t.Symbol,
+			Decimals:  t.Decimals,
+			Value:     strings.TrimPrefix(t.Value, "-"),
+		})
+	}
+	return xfers, nil
+}
+
+func (f *Filfox) NFTTransfers(ctx context.Context, address string) ([]NFTTransfer, error) {
+	var xfers []NFTTransfer
+	for t, err := range f.client.NFTTransfers(ctx, address) {
+		if err != nil {
+			return nil, err
+		}
+		xfers = append(xfers, NFTTransfer{
+			Height:    t.Height,
+			Timestamp: int64(t.Timestamp),
+			Message:   t.Message,
+			From:      t.From,
+			To:        t.To,
+			Contract:  t.Contract,
+			Symbol:    t.Symbol,
+			TokenID:   t.TokenID,
+		})
+	}
+	return xfers, nil
+}
+
+func (f *Filfox) InternalTransfers(ctx context.Context, address string) ([]Record, error) {
+	var records []Record
+	for t, err := range f.client.InternalTransfers(ctx, address) {
+		if err != nil {
+			return nil, err
+		}
+		value, typ := strings.TrimPrefix(t.Value, "-"), "receive"
+		if t.From == address {
+			value, typ = "-"+value, "send"
+		}
+		records = append(records, Record{
+			Height:    t.Height,
+			Timestamp: int64(t.Timestamp),
+			Message:   t.Message,
+			From:      t.From,
+			To:        t.To,
+			Value:     value,
+			Type:      typ,
+		})
+	}
+	return records, nil
+}
+
+func (f *Filfox) ResolveID(ctx context.Context, id string) (string, error) {
+	a, err := f.client.Address(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return a.Robust, nil
+}
+
+func (f *Filfox) MinerAccounts(ctx context.Context, miner string) (MinerAccounts, error) {
+	a, err := f.client.Address(ctx, miner)
+	if err != nil {
+		return MinerAccounts{}, err
+	}
+	if a.Miner == nil {
+		return MinerAccounts{}, fmt.Errorf("%s is a %s actor, not a miner", miner, a.Actor)
+	}
+	return MinerAccounts{
+		Owner:       a.Miner.Owner.Address,
+		Worker:      a.Miner.Worker.Address,
+		Beneficiary: a.Miner.Beneficiary.Address,
+	}, nil
+}
+
+func (f *Filfox) Balance(ctx context.Context, address string) (string, error) {
+	a, err := f.client.Address(ctx, address)
+	if err != nil {
+		return "", err
+	}
+	return a.Balance, nil
+}
+
+// BalanceHistory samples the address's balance daily since before since,
+// oldest first.
+func (f *Filfox) BalanceHistory(ctx context.Context, address string, since time.Time) ([]BalanceSample, error) {
+	duration, days := balanceWindow(since, time.Now())
+	stats, err := f.client.BalanceStats(ctx, address, duration, days)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]BalanceSample, len(stats))
+	for i, s := range stats {
+		samples[i] = BalanceSample{Height: s.Height, Timestamp: int64(s.Timestamp), Balance: s.Balance}
+	}
+	return samples, nil
+}
+
+// balanceWindow is the balance-stats duration, in days, reaching from now
+// back to a day before since, or to genesis if since is zero, sampled daily.
+func balanceWindow(since, now time.Time) (duration string, days int) {
+	if since.IsZero() || since.Before(genesis) {
+		since = genesis
+	}
+	days = int(now.Sub(since)/(24*time.Hour)) + 2
+	return strconv.Itoa(days) + "d", days
+}
@@ -0,0 +1,129 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultFilscanURL is the Filscan mainnet API endpoint.
+const DefaultFilscanURL = "https://api-v2.filscan.io/api/v1"
+
+// Filscan retrieves transfers from the Filscan explorer API.
+type Filscan struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewFilscan returns a TransferSource for the Filscan API at baseURL, or
+// DefaultFilscanURL if empty.
+func NewFilscan(hc *http.Client, baseURL string) *Filscan {
+	if baseURL == "" {
+		baseURL = DefaultFilscanURL
+	}
+	return &Filscan{baseURL: baseURL, httpClient: hc}
+}
+
+func (f *Filscan) Name() string { return "filscan" }
+
+type filscanRequest struct {
+	AccountID string         `json:"account_id"`
+	Filters   filscanFilters `json:"filters"`
+}
+
+type filscanFilters struct {
+	Index int `json:"index"`
+	Limit int `json:"limit"`
+}
+
+type filscanResponse struct {
+	Result struct {
+		TotalCount   int               `json:"total_count"`
+		TransferList []filscanTransfer `json:"transfer_list"`
+	} `json:"result"`
+}
+
+type filscanTransfer struct {
+	Height     int    `json:"height"`
+	BlockTime  int64  `json:"block_time"`
+	MessageCid string `json:"message_cid"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Value      string `json:"value"` // attoFIL, absolute
+	Type       string `json:"type"`  // same vocabulary as Filfox
+}
+
+func (f *Filscan) Transfers(ctx context.Context, address string) ([]Record, error) {
+	const pageSize = 100
+	var records []Record
+
+	for page := 0; ; page++ {
+		if page >= maxPages {
+			return nil, fmt.Errorf("filscan pagination exceeded %d pages, aborting", maxPages)
+		}
+		body, err := json.Marshal(filscanRequest{
+			AccountID: address,
+			Filters:   filscanFilters{Index: page, Limit: pageSize},
+		})
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, "POST", f.baseURL+"/TransferList", bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		var resp filscanResponse
+		if err := doJSON(f.httpClient, req, &resp); err != nil {
+			return nil, fmt.Errorf("retrieving filscan transfers page %d: %w", page, err)
+		}
+
+		for _, x := range resp.Result.TransferList {
+			records = append(records, Record{
+				Height:    x.Height,
+				Timestamp: x.BlockTime,
+				Message:   x.MessageCid,
+				From:      x.From,
+				To:        x.To,
+				Value:     signed(x.Value, x.From, address),
+				Type:      x.Type,
+			})
+		}
+
+		if len(records) >= resp.Result.TotalCount {
+			break
+		}
+		if len(resp.Result.TransferList) == 0 {
+			return nil, fmt.Errorf("filscan page %d was empty after %d of %d records, pagination is inconsistent",
+				page, len(records), resp.Result.TotalCount)
+		}
+	}
+
+	return records, nil
+}
+
+type filscanHeightResponse struct {
+	Result struct {
+		Height    int   `json:"height"`
+		BlockTime int64 `json:"block_time"`
+	} `json:"result"`
+}
+
+func (f *Filscan) ChainHead(ctx context.Context) (Head, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", f.baseURL+"/FinalHeight", strings.NewReader("{}"))
+	if err != nil {
+		return Head{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var resp filscanHeightResponse
+	if err := doJSON(f.httpClient, req, &resp); err != nil {
+		return Head{}, err
+	}
+	return Head{Height: resp.Result.Height, Timestamp: time.Unix(resp.Result.BlockTime, 0)}, nil
+}
@@ -0,0 +1,117 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+)
+
+// Merge combines the records of several TransferSources into one
+// deduplicated set, for auditing how complete each backend's history is.
+type Merge struct {
+	sources []TransferSource
+	report  func(address string, gaps []Gap)
+}
+
+// A Gap is a record that some of a Merge's sources did not return.
+type Gap struct {
+	Record  Record
+	Missing []string // names of the sources lacking the record
+}
+
+// NewMerge returns a TransferSource with the union of the records of
+// sources, which are queried concurrently. If report is not nil it is called
+// with the records missing from any of them after every successful lookup.
+func NewMerge(sources []TransferSource, report func(address string, gaps []Gap)) *Merge {
+	return &Merge{sources: sources, report: report}
+}
+
+func (m *Merge) Name() string {
+	names := make([]string, len(m.sources))
+	for i, src := range m.sources {
+		names[i] = src.Name()
+	}
+	return strings.Join(names, "&")
+}
+
+// Unwrap returns the first TransferSource, which serves every optional
+// interface.
+func (m *Merge) Unwrap() TransferSource { return m.sources[0] }
+
+func (m *Merge) Transfers(ctx context.Context, address string) ([]Record, error) {
+	results := make([][]Record, len(m.sources))
+	errs := make([]error, len(m.sources))
+	var wg sync.WaitGroup
+	for i, src := range m.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = src.Transfers(ctx, address)
+		}()
+	}
+	wg.Wait()
+
+	// An address unknown to one backend is merely a gap in it
+	var found bool
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			found = true
+		case errors.Is(err, filfox.ErrNotFound):
+			errs[i] = nil
+		default:
+			return nil, fmt.Errorf("%s: %w", m.sources[i].Name(), err)
+		}
+	}
+	if !found {
+		return nil, filfox.ErrNotFound
+	}
+
+	// Records are the same across backends if they agree on the message,
+	// type, parties and amount. A source may legitimately repeat one, so each
+	// is kept as often as the source with the most copies has it.
+	type identity struct {
+		message, typ, from, to, value string
+	}
+	id := func(r Record) identity {
+		return identity{r.Message, r.Type, r.From, r.To, strings.TrimPrefix(r.Value, "-")}
+	}
+	counts := make([]map[identity]int, len(m.sources))
+	kept := make(map[identity]int)
+	var merged []Record
+	for i, records := range results {
+		counts[i] = make(map[identity]int)
+		for _, r := range records {
+			k := id(r)
+			counts[i][k]++
+			if counts[i][k] > kept[k] {
+				kept[k]++
+				merged = append(merged, r)
+			}
+		}
+	}
+
+	if m.report != nil {
+		var gaps []Gap
+		reported := make(map[identity]int)
+		for _, r := range merged {
+			k := id(r)
+			reported[k]++
+			var missing []string
+			for i, src := range m.sources {
+				if counts[i][k] < reported[k] {
+					missing = append(missing, src.Name())
+				}
+			}
+			if len(missing) > 0 {
+				gaps = append(gaps, Gap{Record: r, Missing: missing})
+			}
+		}
+		m.report(address, gaps)
+	}
+	return merged, nil
+}
@@ -0,0 +1,31 @@
+package source
+
+import "strconv"
+
+// Method numbers that mean the same thing regardless of the receiving actor.
+// Others, such as WithdrawBalance (16 on miners) or Exec (2 on the init
+// actor), depend on the actor and are taken from the explorer's own decoding.
+const (
+	MethodSend           = 0
+	MethodConstructor    = 1
+	MethodInvokeContract = 3844450837 // FRC-42 hash, used by FEVM contract calls
+)
+
+// DecodeMethod returns a human readable name for a message method, preferring
+// the name reported by the backend and falling back to the well known
+// actor-independent numbers.
+func DecodeMethod(number int, name string) string {
+	if name != "" {
+		return name
+	}
+	switch number {
+	case MethodSend:
+		return "Send"
+	case MethodConstructor:
+		return "Constructor"
+	case MethodInvokeContract:
+		return "InvokeContract"
+	default:
+		return "Method" + strconv.Itoa(number)
+	}
+}
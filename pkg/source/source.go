@@ -0,0 +1,305 @@
+// Package source abstracts the block explorers filfoxy can retrieve transfer
+// history from, normalising their responses into a common Record shape.
+package source
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+)
+
+// A TransferSource retrieves the transfer history of an address.
+type TransferSource interface {
+	// Name identifies the backend, e.g. "filfox".
+	Name() string
+
+	// Transfers returns every transfer record involving address.
+	Transfers(ctx context.Context, address string) ([]Record, error)
+}
+
+// Find returns the first source in src's chain of wrappers, such as Breaker,
+// that implements the optional interface T, e.g. MessageSource.
+func Find[T any](src TransferSource) (T, bool) {
+	for src != nil {
+		if t, ok := src.(T); ok {
+			return t, true
+		}
+		u, ok := src.(interface{ Unwrap() TransferSource })
+		if !ok {
+			break
+		}
+		src = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// A HeadSource can report the current chain head, which doubles as a cheap
+// health check of the backend.
+type HeadSource interface {
+	ChainHead(ctx context.Context) (Head, error)
+}
+
+// A MessageSource can look up the execution details of a message.
+type MessageSource interface {
+	MessageDetails(ctx context.Context, cid string) (MessageDetails, error)
+}
+
+// MessageDetails are the gas and execution details of a message. Amounts are
+// attoFIL strings, as in Record.
+type MessageDetails struct {
+	GasLimit    int64
+	GasFeeCap   string
+	GasPremium  string
+	BaseFeeBurn string
+	ExitCode    int
+
+	// OverEstimationBurn and MinerTip complete the split of the fees. With
+	// BaseFeeBurn they sum to the message's burn-fee and miner-fee records.
+	OverEstimationBurn string
+	MinerTip           string
+}
+
+// A MessageLister can list the messages of an address, which carry the
+// method information that transfer records lack.
+type MessageLister interface {
+	Messages(ctx context.Context, address string) ([]Message, error)
+}
+
+// Message is a message sent or received by an address, normalised across
+// backends.
+type Message struct {
+	Cid          string
+	Height       int
+	Timestamp    int64 // unix seconds
+	From         string
+	To           string
+	Nonce        int
+	Value        string // in attoFIL as a string
+	MethodNumber int
+	Method       string // decoded name, see DecodeMethod
+	ExitCode     int
+}
+
+// A RewardSource can list the block rewards earned by a miner actor.
+type RewardSource interface {
+	BlockRewards(ctx context.Context, miner string) ([]BlockReward, error)
+}
+
+// BlockReward is the reward for a single mined block. Amounts are attoFIL
+// strings, as in Record.
+type BlockReward struct {
+	Block     string // block CID
+	Height    int
+	Timestamp int64 // unix seconds
+	WinCount  int
+	Reward    string
+	Penalty   string
+}
+
+// A PledgeSource can report how much of a miner's balance was locked as
+// collateral over time, from before since, or over its whole history if
+// since is zero.
+type PledgeSource interface {
+	PledgeHistory(ctx context.Context, miner string, since time.Time) ([]PledgeSample, error)
+}
+
+// PledgeSample is the collateral locked by a miner at an epoch: its initial
+// pledge plus any precommit deposits, in attoFIL as a string.
+type PledgeSample struct {
+	Height    int
+	Timestamp int64 // unix seconds
+	Locked    string
+}
+
+// A TokenSource can list the FEVM token transfers of an address.
+type TokenSource interface {
+	TokenTransfers(ctx context.Context, address string) ([]TokenTransfer, error)
+}
+
+// TokenTransfer is a movement of an FRC-20/ERC-20 token. Value is in the
+// token's smallest unit as a string, and always positive; direction is given
+// by From and To.
+type TokenTransfer struct {
+	Height    int
+	Timestamp int64 // unix seconds
+	Message   string
+	From      string
+	To        string
+	Contract  string
+	Symbol    string
+	Decimals  int
+	Value     string
+}
+
+// An NFTSource can list the ERC-721 transfers of an address.
+type NFTSource interface {
+	NFTTransfers(ctx context.Context, address string) ([]NFTTransfer, error)
+}
+
+// NFTTransfer is a movement of a single ERC-721 token.
+type NFTTransfer struct {
+	Height    int
+	Timestamp int64 // unix seconds
+	Message   string
+	From      string
+	To        string
+	Contract  string
+	Symbol    string
+	TokenID   string
+}
+
+// An InternalSource can list the FIL moved to or from an address by contract
+// internal calls, which backends don't report as regular transfers. The
+// records use the same conventions as Transfers, with Type send or receive.
+type InternalSource interface {
+	InternalTransfers(ctx context.Context, address string) ([]Record, error)
+}
+
+// An AddressResolver can look up the robust (f1/f2/f3/f410) address behind
+// an f0 ID address. It returns "" for actors without one, such as miners and
+// builtin actors.
+type AddressResolver interface {
+	ResolveID(ctx context.Context, id string) (string, error)
+}
+
+// A MinerInfoSource can look up the addresses associated with a miner.
+type MinerInfoSource interface {
+	MinerAccounts(ctx context.Context, miner string) (MinerAccounts, error)
+}
+
+// MinerAccounts are the addresses funds flow through for a miner. Any may be
+// the same address, and Beneficiary is empty if the backend doesn't report it.
+type MinerAccounts struct {
+	Owner       string
+	Worker      string
+	Beneficiary string
+}
+
+// A BalanceSource can report the current balance of an address, in attoFIL
+// as a string.
+type BalanceSource interface {
+	Balance(ctx context.Context, address string) (string, error)
+}
+
+// A BalanceHistorySource can sample the balance of an address over time,
+// from before since, or over its whole history if since is zero.
+type BalanceHistorySource interface {
+	BalanceHistory(ctx context.Context, address string, since time.Time) ([]BalanceSample, error)
+}
+
+// BalanceSample is an address's balance at a height, in attoFIL as a string.
+type BalanceSample struct {
+	Height    int
+	Timestamp int64 // unix seconds
+	Balance   string
+}
+
+// Head is the latest tipset known to a backend.
+type Head struct {
+	Height    int
+	Timestamp time.Time
+}
+
+// Record is a single movement of value, normalised across backends. A message
+// may produce several records, e.g. a send plus its miner and burn fees.
+type Record struct {
+	Height    int    `json:"height"`
+	Timestamp int64  `json:"timestamp"` // unix seconds
+	Message   string `json:"message"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Value     string `json:"value"` // in attoFIL as a string, negative when leaving the address
+	Type      string `json:"type"`  // [send, receive, miner-fee, burn-fee, burn, penalty]
+}
+
+// HeightRange restricts a fetch to records at epochs in [From, To], where a
+// zero bound is open.
+type HeightRange struct {
+	From, To int
+}
+
+// Contains reports whether height is within the range.
+func (r HeightRange) Contains(height int) bool {
+	return (r.From == 0 || height >= r.From) && (r.To == 0 || height <= r.To)
+}
+
+// IsZero reports whether the range is unbounded at both ends.
+func (r HeightRange) IsZero() bool { return r.From == 0 && r.To == 0 }
+
+// Bounded filters the records of a backend that cannot apply a HeightRange
+// itself. Prefer a backend's native support where it exists, e.g.
+// filfox.WithHeightRange, which avoids downloading out of range pages.
+func Bounded(src TransferSource, r HeightRange) TransferSource {
+	if r.IsZero() {
+		return src
+	}
+	return &bounded{src: src, r: r}
+}
+
+type bounded struct {
+	src TransferSource
+	r   HeightRange
+}
+
+func (b *bounded) Name() string { return b.src.Name() }
+
+func (b *bounded) Unwrap() TransferSource { return b.src }
+
+func (b *bounded) Transfers(ctx context.Context, address string) ([]Record, error) {
+	records, err := b.src.Transfers(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(records, func(r Record) bool { return !b.r.Contains(r.Height) }), nil
+}
+
+// maxPages caps pagination of the simpler backends, guarding against an API
+// that never signals the end of results.
+const maxPages = 10_000
+
+// Names lists the supported backends, in order of preference.
+var Names = []string{"filfox", "beryx", "filscan"}
+
+// doJSON performs req and decodes the (possibly gzipped) JSON response body
+// into v.
+func doJSON(hc *http.Client, req *http.Request, v any) error {
+	req.Header.Set("Accept-Encoding", "gzip")
+	slog.Debug("API call", "url", req.URL.String())
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return filfox.NewStatusError(resp)
+	}
+
+	var body io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		body = zr
+	}
+	return json.NewDecoder(body).Decode(v)
+}
+
+// signed negates value when the record moves funds away from address, matching
+// the Filfox convention the rest of filfoxy expects.
+func signed(value, from, address string) string {
+	if from == address && value != "0" && value != "" && value[0] != '-' {
+		return "-" + value
+	}
+	return value
+}
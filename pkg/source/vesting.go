@@ -0,0 +1,64 @@
+package source
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+var epochsPerMonth = int(30 * 24 * time.Hour / epochDuration)
+
+// A VestingSource can report the vesting schedule of a multisig account.
+type VestingSource interface {
+	Vesting(ctx context.Context, address string) (Vesting, error)
+}
+
+// Vesting is a linear unlock of InitialBalance over UnlockDuration epochs
+// from StartHeight, as enforced by the multisig actor. A zero Vesting means
+// the account has no locked funds.
+type Vesting struct {
+	InitialBalance *big.Int
+	StartHeight    int
+	UnlockDuration int // in epochs
+}
+
+// IsZero reports whether v locks nothing.
+func (v Vesting) IsZero() bool {
+	return v.InitialBalance == nil || v.InitialBalance.Sign() == 0 || v.UnlockDuration <= 0
+}
+
+// EndHeight is the epoch at which the whole balance has unlocked.
+func (v Vesting) EndHeight() int { return v.StartHeight + v.UnlockDuration }
+
+// LockedAt returns the balance still locked at height, using the same
+// integer arithmetic as the multisig actor.
+func (v Vesting) LockedAt(height int) *big.Int {
+	if v.IsZero() || height >= v.EndHeight() {
+		return new(big.Int)
+	}
+	if height <= v.StartHeight {
+		return new(big.Int).Set(v.InitialBalance)
+	}
+	remaining := big.NewInt(int64(v.EndHeight() - height))
+	locked := new(big.Int).Mul(v.InitialBalance, remaining)
+	return locked.Quo(locked, big.NewInt(int64(v.UnlockDuration)))
+}
+
+// Schedule samples the locked balance monthly from the start of vesting until
+// it has fully unlocked, including both ends.
+func (v Vesting) Schedule() []VestingPoint {
+	if v.IsZero() {
+		return nil
+	}
+	var points []VestingPoint
+	for h := v.StartHeight; h < v.EndHeight(); h += epochsPerMonth {
+		points = append(points, VestingPoint{Height: h, Locked: v.LockedAt(h)})
+	}
+	return append(points, VestingPoint{Height: v.EndHeight(), Locked: new(big.Int)})
+}
+
+// VestingPoint is the locked balance at a height in a vesting schedule.
+type VestingPoint struct {
+	Height int
+	Locked *big.Int
+}
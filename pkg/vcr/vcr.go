@@ -0,0 +1,288 @@
+// Package vcr records HTTP exchanges to a directory and replays them later,
+// so code built on the explorer clients can be exercised against realistic
+// payloads without network access.
+//
+// A cassette is a directory of numbered files, one set per exchange:
+//
+//	0001-request.txt   request line and headers
+//	0001-response.txt  status line and headers
+//	0001-response.json response body, decompressed
+//
+// The files are plain text so that they can be inspected, edited by hand, and
+// attached to bug reports.
+package vcr
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Mode selects how New treats the network.
+type Mode int
+
+const (
+	// ModeReplay serves every request from the cassette.
+	ModeReplay Mode = iota
+	// ModeRecord forwards every request and records the exchange.
+	ModeRecord
+	// ModeReplayOrRecord replays requests found in the cassette and records
+	// the rest, which is convenient for building up test fixtures.
+	ModeReplayOrRecord
+)
+
+// New returns a RoundTripper for the cassette in dir operating in mode. next
+// is used for network requests, defaulting to http.DefaultTransport.
+func New(dir string, mode Mode, next http.RoundTripper) (http.RoundTripper, error) {
+	switch mode {
+	case ModeReplay:
+		return NewReplayer(dir)
+	case ModeRecord:
+		return NewRecorder(dir, next)
+	case ModeReplayOrRecord:
+		rec, err := NewRecorder(dir, next)
+		if err != nil {
+			return nil, err
+		}
+		rep, err := loadReplayer(dir)
+		if err != nil {
+			return nil, err
+		}
+		rep.fallback = rec
+		return rep, nil
+	default:
+		return nil, fmt.Errorf("vcr: unknown mode %d", mode)
+	}
+}
+
+// Recorder is an http.RoundTripper that writes every exchange passing through
+// it to a cassette. Authorization headers are redacted.
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+	seq  *atomic.Int64
+}
+
+// sequences numbers exchanges per directory across every Recorder in the
+// process, so several clients can share one cassette.
+var (
+	sequencesMu sync.Mutex
+	sequences   = make(map[string]*atomic.Int64)
+)
+
+// NewRecorder returns a Recorder appending to the cassette in dir.
+func NewRecorder(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	seq, err := sequence(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{dir: dir, next: next, seq: seq}, nil
+}
+
+// sequence returns the shared counter for dir, continuing after any exchanges
+// already recorded there.
+func sequence(dir string) (*atomic.Int64, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	sequencesMu.Lock()
+	defer sequencesMu.Unlock()
+	if seq, ok := sequences[abs]; ok {
+		return seq, nil
+	}
+
+	names, err := requestFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	seq := new(atomic.Int64)
+	for _, name := range names {
+		n, _ := strconv.ParseInt(strings.TrimSuffix(filepath.Base(name), "-request.txt"), 10, 64)
+		seq.Store(max(seq.Load(), n))
+	}
+	sequences[abs] = seq
+	return seq, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	prefix := filepath.Join(r.dir, fmt.Sprintf("%04d", r.seq.Add(1)))
+
+	redacted := req.Clone(req.Context())
+	if redacted.Header.Get("Authorization") != "" {
+		redacted.Header.Set("Authorization", "REDACTED")
+	}
+	reqDump, err := httputil.DumpRequestOut(redacted, false)
+	if err == nil {
+		err = os.WriteFile(prefix+"-request.txt", reqDump, 0o644)
+	}
+	if err != nil {
+		slog.Warn("Failed to record HTTP request", "url", req.URL.String(), "err", err)
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := writeResponse(prefix, resp, body); err != nil {
+		slog.Warn("Failed to record HTTP response", "url", req.URL.String(), "err", err)
+	}
+	return resp, nil
+}
+
+func writeResponse(prefix string, resp *http.Response, body []byte) error {
+	head, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(prefix+"-response.txt", head, 0o644); err != nil {
+		return err
+	}
+
+	plain := body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		if plain, err = io.ReadAll(zr); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(prefix+"-response.json", plain, 0o644)
+}
+
+// Replayer is an http.RoundTripper answering requests from a cassette,
+// never touching the network. Requests are matched on method, path and query;
+// when an exchange was recorded more than once the latest wins. Unmatched
+// requests get a 404 response.
+type Replayer struct {
+	exchanges map[string]string // request key -> file prefix
+	fallback  http.RoundTripper // used for unmatched requests, if set
+}
+
+// NewReplayer loads the cassette in dir, which must contain at least one
+// recorded exchange.
+func NewReplayer(dir string) (*Replayer, error) {
+	r, err := loadReplayer(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.exchanges) == 0 {
+		return nil, fmt.Errorf("vcr: no recorded requests found in %s", dir)
+	}
+	return r, nil
+}
+
+func loadReplayer(dir string) (*Replayer, error) {
+	names, err := requestFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Replayer{exchanges: make(map[string]string)}
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.ReadRequest(bufio.NewReader(f))
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("vcr: reading %s: %w", name, err)
+		}
+		r.exchanges[key(req)] = strings.TrimSuffix(name, "-request.txt")
+	}
+	slog.Debug("Loaded recorded HTTP exchanges", "dir", dir, "count", len(r.exchanges))
+	return r, nil
+}
+
+// requestFiles lists the recorded requests in dir in sequence order.
+func requestFiles(dir string) ([]string, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*-request.txt"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// key identifies a request independently of host and parameter order.
+func key(req *http.Request) string {
+	return req.Method + " " + req.URL.Path + "?" + req.URL.Query().Encode()
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	prefix, ok := r.exchanges[key(req)]
+	if !ok {
+		if r.fallback != nil {
+			return r.fallback.RoundTrip(req)
+		}
+		// A 404 rather than an error, so clients don't retry a lookup that
+		// can never succeed
+		return notFound(req), nil
+	}
+
+	head, err := os.Open(prefix + "-response.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer head.Close()
+	resp, err := http.ReadResponse(bufio.NewReader(head), req)
+	if err != nil {
+		return nil, fmt.Errorf("vcr: reading %s: %w", prefix, err)
+	}
+	resp.Body.Close()
+
+	body, err := os.ReadFile(prefix + "-response.json")
+	if err != nil {
+		return nil, err
+	}
+	// Bodies are recorded decompressed
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+func notFound(req *http.Request) *http.Response {
+	msg := "vcr: no recorded response for " + req.Method + " " + req.URL.String()
+	return &http.Response{
+		Status:        "404 Not Found",
+		StatusCode:    http.StatusNotFound,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain"}},
+		Body:          io.NopCloser(strings.NewReader(msg)),
+		ContentLength: int64(len(msg)),
+		Request:       req,
+	}
+}
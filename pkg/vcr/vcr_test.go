@@ -0,0 +1,112 @@
+package vcr
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, rt http.RoundTripper, url string, header http.Header) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("gzip") != "" {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			io.WriteString(zw, `{"page":"`+r.URL.Query().Get("page")+`"}`)
+			zw.Close()
+			return
+		}
+		io.WriteString(w, `{"page":"`+r.URL.Query().Get("page")+`"}`)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	rec, err := NewRecorder(dir, srv.Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get(t, rec, srv.URL+"/api/transfers?page=0&pageSize=2", http.Header{"Authorization": {"Bearer secret"}})
+	get(t, rec, srv.URL+"/api/transfers?page=1&pageSize=2&gzip=1", nil)
+
+	b, err := os.ReadFile(filepath.Join(dir, "0001-request.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "secret") {
+		t.Errorf("recorded request keeps its credentials:\n%s", b)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "0002-response.json")); string(b) != `{"page":"1"}` {
+		t.Errorf("recorded gzipped body = %q, want it decompressed", b)
+	}
+
+	srv.Close() // replay must not touch the network
+	rep, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Matched regardless of host and parameter order
+	if code, body := get(t, rep, "http://elsewhere/api/transfers?pageSize=2&page=0", nil); code != 200 || body != `{"page":"0"}` {
+		t.Errorf("replayed page 0 = %d %q", code, body)
+	}
+	if code, body := get(t, rep, "http://elsewhere/api/transfers?page=1&pageSize=2&gzip=1", nil); code != 200 || body != `{"page":"1"}` {
+		t.Errorf("replayed page 1 = %d %q", code, body)
+	}
+	if code, _ := get(t, rep, "http://elsewhere/api/transfers?page=2&pageSize=2", nil); code != http.StatusNotFound {
+		t.Errorf("unrecorded request got %d, want 404", code)
+	}
+}
+
+func TestReplayOrRecord(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	for range 2 {
+		rt, err := New(dir, ModeReplayOrRecord, srv.Client().Transport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, body := get(t, rt, srv.URL+"/a", nil); body != "/a" {
+			t.Errorf("body = %q, want /a", body)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("server got %d requests, want 1 with the second replayed", requests)
+	}
+}
+
+func TestNewReplayerEmpty(t *testing.T) {
+	if _, err := NewReplayer(t.TempDir()); err == nil {
+		t.Error("NewReplayer accepted an empty cassette")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerFormat("hledger", format{description: "hledger plain text journal", ext: ".journal", write: writeHledger})
+	registerFormat("ledger-cli", format{description: "Ledger CLI plain text journal", ext: ".ledger", write: writeLedgerCLI})
+}
+
+// writeHledger writes xfers as an hledger journal.
+func writeHledger(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	return writeJournal(w, xfers, opts, false)
+}
+
+// writeLedgerCLI writes xfers as a ledger-cli journal.
+func writeLedgerCLI(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	return writeJournal(w, xfers, opts, true)
+}
+
+// writeJournal writes xfers in the plain text accounting syntax hledger and
+// ledger-cli share, which only differ in how tags are written. Balance
+// assertions are dated transactions placed before the entries of their day,
+// so they check the balance at its start.
+func writeJournal(w io.Writer, xfers []Transfer, opts exportOptions, ledgerCLI bool) error {
+	const date = time.DateOnly
+	bw := bufio.NewWriter(w)
+	assertions := opts.assertions
+	assert := func(before time.Time) {
+		for len(assertions) > 0 && (before.IsZero() || !assertions[0].time.After(before)) {
+			a := assertions[0]
+			fmt.Fprintf(bw, "%s * Balance assertion\n", a.time.Format(date))
+			fmt.Fprintf(bw, "    %-40s 0 FIL = %s FIL\n\n", a.account, opts.amounts.format(a.amount, 18))
+			assertions = assertions[1:]
+		}
+	}
+
+	for _, e := range journalEntries(xfers, opts) {
+		assert(e.time)
+		flag := "*"
+		if e.pending {
+			flag = "!"
+		}
+		description := e.payee
+		if e.narration != "" {
+			if ledgerCLI {
+				description += "  ; " + e.narration
+			} else {
+				description += " | " + e.narration
+			}
+		}
+		fmt.Fprintf(bw, "%s %s %s\n", opts.localTime(e.time).Format(date), flag, description)
+		fmt.Fprintf(bw, "    ; message: %s\n", e.message)
+		if len(e.tags) > 0 {
+			tags := make([]string, len(e.tags))
+			for i, tag := range e.tags {
+				tags[i] = journalTag(tag)
+			}
+			if ledgerCLI {
+				fmt.Fprintf(bw, "    ; :%s:\n", strings.Join(tags, ":"))
+			} else {
+				fmt.Fprintf(bw, "    ; %s:\n", strings.Join(tags, ":, "))
+			}
+		}
+		for _, p := range e.postings {
+			fmt.Fprintf(bw, "    %-40s %s %s\n", p.account, p.amount, p.commodity)
+		}
+		fmt.Fprintln(bw)
+	}
+	assert(time.Time{})
+	return bw.Flush()
+}
+
+// journalTag replaces the characters that would end a tag name with dashes.
+func journalTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ':' || r == ',' || r == ' ' || r == '\t' {
+			return '-'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/filfox"
+)
+
+// progressPrinter shows how far retrieving transfers has got: a line
+// redrawn in place on a terminal, or else a log line every few seconds, so
+// that redirected output isn't flooded with redraws.
+type progressPrinter struct {
+	w     io.Writer
+	tty   bool
+	start time.Time // of the current fetch
+	shown time.Time // when a line was last logged, off a terminal
+}
+
+func newProgressPrinter(f *os.File) *progressPrinter {
+	return &progressPrinter{w: f, tty: isTerminal(f)}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *progressPrinter) update(pr filfox.Progress) {
+	now := time.Now()
+	if pr.Pages <= 1 {
+		p.start, p.shown = now, now
+	}
+	var eta time.Duration
+	if elapsed := now.Sub(p.start); !pr.Done && pr.Pages > 1 && elapsed > time.Second {
+		eta = (elapsed / time.Duration(pr.Pages-1) * time.Duration(pr.TotalPages-pr.Pages)).Round(time.Second)
+	}
+
+	switch {
+	case p.tty:
+		line := fmt.Sprintf("%s: page %d/%d, %d/%d records", pr.Address, pr.Pages, pr.TotalPages, pr.Records, pr.TotalRecords)
+		if eta > 0 {
+			line += fmt.Sprintf(", ETA %s", eta)
+		}
+		fmt.Fprintf(p.w, "\r\033[K%s", line) // clear the previous line
+		if pr.Done {
+			fmt.Fprintln(p.w)
+		}
+	case pr.Done && pr.TotalPages > 1, now.Sub(p.shown) >= 5*time.Second:
+		args := []any{"address", pr.Address, "page", pr.Pages, "pages", pr.TotalPages, "records", pr.Records, "total_records", pr.TotalRecords}
+		if eta > 0 {
+			args = append(args, "eta", eta)
+		}
+		slog.Info("Retrieval progress", args...)
+		p.shown = now
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxHistory is how many wallets the prompt remembers.
+const maxHistory = 10
+
+// historyPath is where the wallets given at the prompt are remembered,
+// newest first: filfoxy/history in the user's cache directory.
+func historyPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "filfoxy", "history")
+}
+
+// promptExport asks on in and out for a wallet to export, offering those
+// given before with their config account names, and the format to export it
+// in, for when filfoxy is run without arguments from a terminal.
+func promptExport(in io.Reader, out io.Writer, names map[string]string, format string) (wallet, chosen string, err error) {
+	history := readHistory(historyPath())
+	r := bufio.NewReader(in)
+	ask := func(question string) (string, error) {
+		fmt.Fprint(out, question)
+		line, err := r.ReadString('\n')
+		if errors.Is(err, io.EOF) && line != "" {
+			err = nil
+		}
+		return strings.TrimSpace(line), err
+	}
+
+	fmt.Fprintln(out, "Export the transfer history of a Filecoin wallet.")
+	if len(history) > 0 {
+		fmt.Fprintln(out, "Wallets exported before:")
+		for i, w := range history {
+			if name := names[w]; name != "" {
+				w += " (" + name + ")"
+			}
+			fmt.Fprintf(out, "  %d) %s\n", i+1, w)
+		}
+	}
+	for wallet == "" {
+		question := "Wallet address, 0x address or account name: "
+		if len(history) > 0 {
+			question = "Wallet, or number of one above [1]: "
+		}
+		answer, err := ask(question)
+		if err != nil {
+			return "", "", err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(history) {
+			answer = history[n-1]
+		} else if answer == "" && len(history) > 0 {
+			answer = history[0]
+		}
+		if answer == "" {
+			continue
+		}
+		if wallet, err = normalizeAddress(answer); err != nil {
+			fmt.Fprintln(out, err)
+		}
+	}
+
+	for chosen == "" {
+		answer, err := ask(fmt.Sprintf("Format, one of %s [%s]: ", strings.Join(formatNames(), ", "), format))
+		if err != nil {
+			return "", "", err
+		}
+		answer = strings.ToLower(answer)
+		if _, ok := formats[answer]; answer != "" && !ok {
+			fmt.Fprintf(out, "unknown format %q\n", answer)
+			continue
+		}
+		chosen = cmp.Or(answer, format)
+	}
+
+	if err := writeHistory(historyPath(), wallet, history); err != nil {
+		fmt.Fprintf(out, "Couldn't remember %s for next time: %v\n", wallet, err)
+	}
+	return wallet, chosen, nil
+}
+
+// readHistory reads the remembered wallets at path, if any.
+func readHistory(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	history, _ := readWalletList(f)
+	return history[:min(len(history), maxHistory)]
+}
+
+// writeHistory remembers wallet at path, ahead of the rest of history.
+func writeHistory(path, wallet string, history []string) error {
+	if path == "" {
+		return errors.New("no cache directory")
+	}
+	history = slices.DeleteFunc(slices.Clone(history), func(w string) bool { return w == wallet })
+	history = append([]string{wallet}, history[:min(len(history), maxHistory-1)]...)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(history, "\n")+"\n"), 0o644)
+}
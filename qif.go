@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+)
+
+func init() {
+	registerFormat("qif", format{description: "Quicken Interchange Format bank register", ext: ".qif", write: writeQIF})
+}
+
+// writeQIF writes xfers as the entries of a Quicken Interchange Format bank
+// account denominated in FIL. Each entry's amount is the change to the
+// wallet's FIL balance, split between the value moved and the fees when a
+// transfer has both. Token and NFT transfers have no place in a FIL account
+// and are left out.
+func writeQIF(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "!Type:Bank")
+
+	var skipped int
+	for _, xfer := range xfers {
+		from, to := balanceDelta(xfer)
+		total := new(big.Int).Add(from, to)
+		if total.Sign() == 0 {
+			skipped++
+			continue
+		}
+
+		fmt.Fprintf(bw, "D%s\n", opts.localTime(xfer.Timestamp).Format("01/02/2006"))
+		fmt.Fprintf(bw, "T%s\n", opts.amounts.format(total, 18))
+		if !xfer.Pending {
+			fmt.Fprintln(bw, "CX")
+		}
+		fmt.Fprintf(bw, "P%s\n", cmp.Or(xfer.Label, xfer.counterparty()))
+		fmt.Fprintf(bw, "M%s\n", xfer.MessageID)
+		if xfer.Category != "" {
+			fmt.Fprintf(bw, "L%s\n", xfer.Category)
+		}
+		if fee := xfer.fee(); fee.Sign() != 0 {
+			if value := new(big.Int).Add(total, fee); value.Sign() != 0 {
+				fmt.Fprintf(bw, "S%s\n$%s\n", xfer.Category, opts.amounts.format(value, 18))
+				fmt.Fprintf(bw, "SFees\n$%s\n", opts.amounts.format(new(big.Int).Neg(fee), 18))
+			}
+		}
+		fmt.Fprintln(bw, "^")
+	}
+	if skipped > 0 {
+		slog.Info("Omitted transfers that don't change the FIL balance", "count", skipped)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"math/big"
+)
+
+func init() {
+	registerFormat("quickbooks", format{description: "QuickBooks Online 3-column bank CSV, valued in the --countervalue-currency", ext: ".csv", write: writeQuickBooksCSV, csv: true, fiat: true})
+	registerFormat("quickbooks-4col", format{description: "QuickBooks Online 4-column bank CSV, with credits and debits apart", ext: ".csv", write: writeQuickBooks4CSV, csv: true, fiat: true})
+}
+
+// writeQuickBooksCSV writes xfers as a QuickBooks Online bank CSV of Date,
+// Description and signed Amount columns, made of fiatLines. QuickBooks
+// suggests payees from the description, so it is the counterparty's label
+// or address, with what the transfer was annotated with after it.
+func writeQuickBooksCSV(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	return writeQuickBooks(w, xfers, opts, false)
+}
+
+// writeQuickBooks4CSV writes the lines of writeQuickBooksCSV with unsigned
+// Credit and Debit columns in place of Amount.
+func writeQuickBooks4CSV(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	return writeQuickBooks(w, xfers, opts, true)
+}
+
+func writeQuickBooks(w io.Writer, xfers []Transfer, opts exportOptions, fourColumn bool) error {
+	writer := opts.csvWriter(w)
+	defer writer.Flush()
+
+	header := []string{"Date", "Description", "Amount"}
+	if fourColumn {
+		header = []string{"Date", "Description", "Credit", "Debit"}
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, line := range fiatLines(xfers, opts) {
+		const quickBooksDate = "01/02/2006"
+		description := line.payee
+		if line.description != "" {
+			description += " - " + line.description
+		}
+		record := []string{opts.date(opts.localTime(line.time), quickBooksDate), description}
+		switch {
+		case !fourColumn:
+			record = append(record, opts.fiat(line.value))
+		case line.value.Sign() < 0:
+			record = append(record, "", opts.fiat(new(big.Rat).Neg(line.value)))
+		default:
+			record = append(record, opts.fiat(line.value), "")
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// balanceDelta is the change xfer made to its wallet's FIL balance, and to
+// ToWallet's for a paired internal transfer.
+func balanceDelta(xfer Transfer) (from, to *big.Int) {
+	from, to = new(big.Int), new(big.Int)
+	switch xfer.Kind {
+	case KindToken, KindNFT, KindPledge:
+		// Tokens aren't FIL, and pledged FIL is still part of the balance
+		return from, to
+	}
+	if !(xfer.Self && xfer.From == xfer.To) {
+		from.Set(xfer.Amount)
+	}
+	for _, fee := range []*big.Int{xfer.MinerFee, xfer.BurnFee} {
+		if fee != nil {
+			from.Sub(from, new(big.Int).Abs(fee))
+		}
+	}
+	if xfer.ToWallet != "" {
+		to.Neg(xfer.Amount)
+	}
+	return from, to
+}
+
+// runningBalances sets each transfer's Balance to its wallet's balance after
+// it, from the oldest transfer forward. It returns the final balance of each
+// wallet. xfers must be sorted newest first.
+func runningBalances(xfers []Transfer) map[string]*big.Int {
+	totals := make(map[string]*big.Int)
+	total := func(wallet string) *big.Int {
+		if totals[wallet] == nil {
+			totals[wallet] = new(big.Int)
+		}
+		return totals[wallet]
+	}
+	for i := len(xfers) - 1; i >= 0; i-- {
+		x := &xfers[i]
+		from, to := balanceDelta(*x)
+		t := total(x.Wallet)
+		t.Add(t, from)
+		if x.ToWallet != "" {
+			tt := total(x.ToWallet)
+			tt.Add(tt, to)
+		}
+		x.Balance = new(big.Int).Set(t)
+	}
+	return totals
+}
+
+// reconcile compares the computed final balances to the live balances from
+// bs, writing a report to w, and returns errBalanceMismatch for gaps.
+// partial notes that filters left the history incomplete, so gaps are
+// expected rather than an error.
+func reconcile(ctx context.Context, w io.Writer, bs source.BalanceSource, totals map[string]*big.Int, wallets []string, partial bool) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "WALLET\tCOMPUTED (FIL)\tON-CHAIN (FIL)\tGAP (FIL)")
+	gaps := 0
+	for _, wallet := range wallets {
+		computed := totals[wallet]
+		if computed == nil {
+			computed = new(big.Int)
+		}
+		live, err := bs.Balance(ctx, wallet)
+		if err != nil {
+			return err
+		}
+		actual, ok := new(big.Int).SetString(live, 10)
+		if !ok {
+			return fmt.Errorf("Failed to parse balance %s of %s", live, wallet)
+		}
+		gap := new(big.Int).Sub(actual, computed)
+		mark := ""
+		if gap.Sign() != 0 {
+			gaps++
+			mark = "  <-- mismatch"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s%s\n", wallet, attoFILToFIL(computed).Text('f', -1),
+			attoFILToFIL(actual).Text('f', -1), attoFILToFIL(gap).Text('f', -1), mark)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	switch {
+	case gaps > 0 && partial:
+		fmt.Fprintln(w, "Filters left the history incomplete, so gaps are expected.")
+	case gaps > 0:
+		fmt.Fprintln(w, "A gap usually means transfer types are missing from the export,",
+			"e.g. block rewards (--rewards), internal transfers (--internal) or penalties.")
+	default:
+		fmt.Fprintln(w, "Balances reconcile.")
+	}
+	if gaps > 0 && !partial {
+		return fmt.Errorf("%w: %d of %d wallets", errBalanceMismatch, gaps, len(wallets))
+	}
+	return nil
+}
+
+// sampleBefore is the latest of samples before t, or the first of them if
+// none is. samples must not be empty.
+func sampleBefore(samples []source.BalanceSample, t time.Time) source.BalanceSample {
+	i, _ := slices.BinarySearchFunc(samples, t.Unix(), func(s source.BalanceSample, t int64) int {
+		return cmp.Compare(s.Timestamp, t)
+	})
+	return samples[max(i-1, 0)]
+}
+
+// balanceAt derives the FIL balance of wallet just before t from its
+// on-chain samples, which must not be empty: the latest sample before t plus
+// the transfers of xfers since it, or the first sample less the transfers
+// from t to it.
+func balanceAt(samples []source.BalanceSample, xfers []Transfer, wallet string, t time.Time) (*big.Int, error) {
+	s := sampleBefore(samples, t)
+	amount, ok := new(big.Int).SetString(s.Balance, 10)
+	if !ok {
+		return nil, fmt.Errorf("parsing balance %q of %s at height %d", s.Balance, wallet, s.Height)
+	}
+	for _, x := range xfers {
+		from, to := balanceDelta(x)
+		delta := new(big.Int)
+		if x.Wallet == wallet {
+			delta.Add(delta, from)
+		}
+		if x.ToWallet == wallet {
+			delta.Add(delta, to)
+		}
+		switch {
+		case x.Height > s.Height && x.Timestamp.Before(t):
+			amount.Add(amount, delta)
+		case x.Height <= s.Height && !x.Timestamp.Before(t):
+			amount.Sub(amount, delta)
+		}
+	}
+	return amount, nil
+}
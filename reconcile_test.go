@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+func TestBalanceAt(t *testing.T) {
+	samples := []source.BalanceSample{
+		{Height: 100, Timestamp: 1000, Balance: "10"},
+		{Height: 200, Timestamp: 2000, Balance: "30"},
+	}
+	receive := func(height int, timestamp int64, amount int64) Transfer {
+		return Transfer{Wallet: testWallet, Kind: KindTransfer, Height: height, Timestamp: time.Unix(timestamp, 0),
+			From: testOther, To: testWallet, Amount: big.NewInt(amount)}
+	}
+	xfers := []Transfer{
+		receive(250, 2500, 7), // between the sample and t
+		receive(300, 3000, 5), // after t
+	}
+
+	tests := []struct {
+		name string
+		t    int64
+		want int64
+	}{
+		{"after a sample", 2800, 37},
+		{"before every sample", 500, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := balanceAt(samples, xfers, testWallet, time.Unix(tt.t, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Int64() != tt.want {
+				t.Errorf("balanceAt = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// dropReplaced removes the transfers of messages that another message with
+// the same sender and nonce replaced, such as a resend with a higher fee.
+// Only one of them can have executed: the one included at the greatest
+// height, which explorers that report both still place in a tipset.
+func dropReplaced(xfers []Transfer, msgs []source.Message) []Transfer {
+	type slot struct {
+		from  string
+		nonce int
+	}
+	executed := make(map[slot]source.Message)
+	for _, m := range msgs {
+		k := slot{m.From, m.Nonce}
+		if e, ok := executed[k]; !ok || m.Height > e.Height {
+			executed[k] = m
+		}
+	}
+
+	replaced := make(map[string]bool)
+	for _, m := range msgs {
+		e := executed[slot{m.From, m.Nonce}]
+		if e.Cid != m.Cid && !replaced[m.Cid] {
+			slog.Warn("Dropping replaced message", "message", m.Cid, "replacement", e.Cid, "nonce", m.Nonce, "from", m.From)
+			replaced[m.Cid] = true
+		}
+	}
+	return slices.DeleteFunc(xfers, func(x Transfer) bool { return replaced[x.MessageID] })
+}
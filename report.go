@@ -0,0 +1,266 @@
+package main
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+func init() {
+	registerFormat("html", format{description: "HTML statement with summary and monthly totals", ext: ".html", write: writeHTML})
+	registerFormat("markdown", format{description: "Markdown statement with summary and monthly totals", ext: ".md", write: writeMarkdown})
+}
+
+// currencyTotals sums the transfers of one currency. Fees, always paid in
+// FIL, count towards the FIL totals whatever was moved.
+type currencyTotals struct {
+	ticker       string
+	decimals     int
+	count        int
+	in, out, fee *big.Int
+}
+
+// net is what the transfers added to the wallet, after fees.
+func (t *currencyTotals) net() *big.Int {
+	net := new(big.Int).Sub(t.in, t.out)
+	return net.Sub(net, t.fee)
+}
+
+// totalsByCurrency sums xfers per currency, FIL first and the rest by
+// ticker.
+func totalsByCurrency(xfers []Transfer) []*currencyTotals {
+	byTicker := make(map[string]*currencyTotals)
+	get := func(ticker string, decimals int) *currencyTotals {
+		t := byTicker[ticker]
+		if t == nil {
+			t = &currencyTotals{ticker: ticker, decimals: decimals, in: new(big.Int), out: new(big.Int), fee: new(big.Int)}
+			byTicker[ticker] = t
+		}
+		return t
+	}
+	for _, x := range xfers {
+		amount, decimals := x.moved()
+		t := get(x.Ticker(), decimals)
+		t.count++
+		switch x.flow() {
+		case flowIn:
+			t.in.Add(t.in, amount.Abs(amount))
+		case flowOut:
+			t.out.Add(t.out, amount.Abs(amount))
+		}
+		if fee := x.fee(); fee.Sign() != 0 {
+			fil := get("FIL", 18)
+			fil.fee.Add(fil.fee, fee)
+		}
+	}
+
+	totals := make([]*currencyTotals, 0, len(byTicker))
+	for _, t := range byTicker {
+		totals = append(totals, t)
+	}
+	slices.SortFunc(totals, func(a, b *currencyTotals) int {
+		switch {
+		case a.ticker == b.ticker:
+			return 0
+		case a.ticker == "FIL":
+			return -1
+		case b.ticker == "FIL":
+			return 1
+		}
+		return strings.Compare(a.ticker, b.ticker)
+	})
+	return totals
+}
+
+// report is the statement rendered by the Markdown and HTML formats, with
+// every amount already formatted.
+type report struct {
+	Wallets   []string
+	From, To  string // dates of the first and last transfers
+	Summary   []reportTotals
+	Months    []reportMonth
+	Transfers []reportTransfer
+}
+
+type reportTotals struct {
+	Currency           string
+	Transfers          int
+	In, Out, Fees, Net string
+}
+
+type reportMonth struct {
+	Month  string
+	Totals []reportTotals
+}
+
+type reportTransfer struct {
+	Date         string
+	Kind         string
+	Flow         string
+	Amount       string
+	Currency     string
+	Fee          string
+	Counterparty string
+	Description  string
+	Message      string
+	Pending      bool
+}
+
+// newReport builds the statement of xfers, oldest first.
+func newReport(xfers []Transfer, opts exportOptions) report {
+	xfers = slices.Clone(xfers)
+	slices.SortStableFunc(xfers, func(a, b Transfer) int { return a.Timestamp.Compare(b.Timestamp) })
+
+	var r report
+	formatTotals := func(totals []*currencyTotals) []reportTotals {
+		rows := make([]reportTotals, len(totals))
+		for i, t := range totals {
+			rows[i] = reportTotals{
+				Currency:  t.ticker,
+				Transfers: t.count,
+				In:        opts.amounts.format(t.in, t.decimals),
+				Out:       opts.amounts.format(t.out, t.decimals),
+				Fees:      opts.amounts.format(t.fee, t.decimals),
+				Net:       opts.amounts.format(t.net(), t.decimals),
+			}
+		}
+		return rows
+	}
+	r.Summary = formatTotals(totalsByCurrency(xfers))
+
+	for start := 0; start < len(xfers); {
+		month := opts.localTime(xfers[start].Timestamp).Format("2006-01")
+		end := start + 1
+		for end < len(xfers) && opts.localTime(xfers[end].Timestamp).Format("2006-01") == month {
+			end++
+		}
+		r.Months = append(r.Months, reportMonth{month, formatTotals(totalsByCurrency(xfers[start:end]))})
+		start = end
+	}
+
+	for _, x := range xfers {
+		if !slices.Contains(r.Wallets, x.Wallet) {
+			r.Wallets = append(r.Wallets, x.Wallet)
+		}
+		amount, decimals := x.moved()
+		row := reportTransfer{
+			Date:         opts.localTime(x.Timestamp).Format(time.DateTime),
+			Kind:         string(x.Kind),
+			Flow:         x.flow().String(),
+			Amount:       opts.amounts.format(amount, decimals),
+			Currency:     x.Ticker(),
+			Counterparty: x.counterparty(),
+			Description:  x.description(),
+			Message:      x.MessageID,
+			Pending:      x.Pending,
+		}
+		if fee := x.fee(); fee.Sign() != 0 {
+			row.Fee = opts.amounts.format(fee, 18)
+		}
+		r.Transfers = append(r.Transfers, row)
+	}
+	if len(xfers) > 0 {
+		r.From = opts.localTime(xfers[0].Timestamp).Format(time.DateOnly)
+		r.To = opts.localTime(xfers[len(xfers)-1].Timestamp).Format(time.DateOnly)
+	}
+	return r
+}
+
+var markdownReport = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"cell": func(s string) string {
+		// Pipes end a table cell, and a newline the table
+		return strings.NewReplacer("|", `\|`, "\r", " ", "\n", " ").Replace(s)
+	},
+	"join": strings.Join,
+}).Parse(`# Filecoin statement
+
+Wallets: {{join .Wallets ", "}}
+{{- if .From}}
+
+Period: {{.From}} to {{.To}}
+{{- end}}
+
+## Summary
+
+| Currency | Transfers | In | Out | Fees | Net |
+|---|--:|--:|--:|--:|--:|
+{{range .Summary}}| {{cell .Currency}} | {{.Transfers}} | {{.In}} | {{.Out}} | {{.Fees}} | {{.Net}} |
+{{end}}
+## Monthly breakdown
+
+| Month | Currency | Transfers | In | Out | Fees | Net |
+|---|---|--:|--:|--:|--:|--:|
+{{range .Months}}{{$month := .Month}}{{range .Totals}}| {{$month}} | {{cell .Currency}} | {{.Transfers}} | {{.In}} | {{.Out}} | {{.Fees}} | {{.Net}} |
+{{end}}{{end}}
+## Transfers
+
+| Date | Kind | Flow | Amount | Currency | Fee | Counterparty | Description | Message |
+|---|---|---|--:|---|--:|---|---|---|
+{{range .Transfers}}| {{.Date}}{{if .Pending}} (pending){{end}} | {{.Kind}} | {{.Flow}} | {{.Amount}} | {{cell .Currency}} | {{.Fee}} | {{.Counterparty}} | {{cell .Description}} | ` + "`{{.Message}}`" + ` |
+{{end}}`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Filecoin statement: {{join .Wallets ", "}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #f3f3f3; }
+td.amount { text-align: right; font-variant-numeric: tabular-nums; white-space: nowrap; }
+tr.pending { color: #888; }
+code { font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>Filecoin statement</h1>
+<p>Wallets: {{join .Wallets ", "}}</p>
+{{- if .From}}
+<p>Period: {{.From}} to {{.To}}</p>
+{{- end}}
+
+<h2>Summary</h2>
+<table>
+<tr><th>Currency</th><th>Transfers</th><th>In</th><th>Out</th><th>Fees</th><th>Net</th></tr>
+{{- range .Summary}}
+<tr><td>{{.Currency}}</td><td class="amount">{{.Transfers}}</td><td class="amount">{{.In}}</td><td class="amount">{{.Out}}</td><td class="amount">{{.Fees}}</td><td class="amount">{{.Net}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Monthly breakdown</h2>
+<table>
+<tr><th>Month</th><th>Currency</th><th>Transfers</th><th>In</th><th>Out</th><th>Fees</th><th>Net</th></tr>
+{{- range .Months}}{{$month := .Month}}{{range .Totals}}
+<tr><td>{{$month}}</td><td>{{.Currency}}</td><td class="amount">{{.Transfers}}</td><td class="amount">{{.In}}</td><td class="amount">{{.Out}}</td><td class="amount">{{.Fees}}</td><td class="amount">{{.Net}}</td></tr>
+{{- end}}{{end}}
+</table>
+
+<h2>Transfers</h2>
+<table>
+<tr><th>Date</th><th>Kind</th><th>Flow</th><th>Amount</th><th>Currency</th><th>Fee</th><th>Counterparty</th><th>Description</th><th>Message</th></tr>
+{{- range .Transfers}}
+<tr{{if .Pending}} class="pending" title="pending"{{end}}><td>{{.Date}}</td><td>{{.Kind}}</td><td>{{.Flow}}</td><td class="amount">{{.Amount}}</td><td>{{.Currency}}</td><td class="amount">{{.Fee}}</td><td>{{.Counterparty}}</td><td>{{.Description}}</td><td><code>{{.Message}}</code></td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// writeMarkdown writes xfers as a statement of totals, monthly totals and
+// transfers in GitHub flavoured Markdown tables.
+func writeMarkdown(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	return markdownReport.Execute(w, newReport(xfers, opts))
+}
+
+// writeHTML writes the statement of writeMarkdown as a standalone HTML page.
+func writeHTML(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	return htmlReport.Execute(w, newReport(xfers, opts))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// idResolver maps f0 ID addresses to their robust form, remembering every
+// answer. Since an actor's robust address never changes, answers are also
+// persisted in the cache directory when one is configured.
+type idResolver struct {
+	src   source.AddressResolver
+	path  string            // where the cache is persisted, if anywhere
+	known map[string]string // ID -> robust address, or "" if it has none
+	dirty bool
+}
+
+func newIDResolver(src source.AddressResolver, cacheDir string) (*idResolver, error) {
+	r := &idResolver{src: src, known: make(map[string]string)}
+	if cacheDir == "" {
+		return r, nil
+	}
+	r.path = filepath.Join(cacheDir, "ids.json")
+	b, err := os.ReadFile(r.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &r.known); err != nil {
+		slog.Warn("Ignoring corrupt ID cache", "path", r.path, "error", err)
+	}
+	return r, nil
+}
+
+// resolve returns the robust form of addr if it is an ID address that has
+// one, and addr otherwise.
+func (r *idResolver) resolve(ctx context.Context, addr string) (string, error) {
+	if !strings.HasPrefix(addr, "f0") && !strings.HasPrefix(addr, "t0") {
+		return addr, nil
+	}
+	robust, ok := r.known[addr]
+	if !ok {
+		var err error
+		robust, err = r.src.ResolveID(ctx, addr)
+		if err != nil {
+			return "", err
+		}
+		r.known[addr] = robust
+		r.dirty = true
+	}
+	if robust == "" {
+		return addr, nil
+	}
+	return robust, nil
+}
+
+// resolveAll rewrites the ID addresses of each transfer's counterparties, so
+// they can still be compared with each other. The wallets exported keep the
+// form they were given in, as they key accounts, names and labels, and are
+// compared with From and To.
+func (r *idResolver) resolveAll(ctx context.Context, xfers []Transfer) error {
+	for i := range xfers {
+		x := &xfers[i]
+		for _, addr := range []*string{&x.From, &x.To} {
+			if *addr == "" || *addr == x.Wallet || *addr == x.ToWallet {
+				continue
+			}
+			robust, err := r.resolve(ctx, *addr)
+			if err != nil {
+				return err
+			}
+			*addr = robust
+		}
+	}
+	return nil
+}
+
+// save persists newly resolved addresses to the cache directory.
+func (r *idResolver) save() error {
+	if r.path == "" || !r.dirty {
+		return nil
+	}
+	b, err := json.MarshalIndent(r.known, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+// fakeResolver resolves the ID addresses it knows.
+type fakeResolver map[string]string
+
+func (f fakeResolver) ResolveID(ctx context.Context, id string) (string, error) {
+	return f[id], nil
+}
+
+func TestResolveAll(t *testing.T) {
+	r, err := newIDResolver(fakeResolver{
+		"f01234": testOther,
+		"f05678": testWallet,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xfers := []Transfer{
+		// A miner exported by its ID address keeps it, from and to
+		{Wallet: "f05678", Kind: KindTransfer, From: "f01234", To: "f05678", Amount: big.NewInt(1)},
+		{Wallet: testWallet, Kind: KindTransfer, From: testWallet, To: "f09999", Amount: big.NewInt(-1)},
+	}
+	if err := r.resolveAll(context.Background(), xfers); err != nil {
+		t.Fatal(err)
+	}
+
+	if x := xfers[0]; x.Wallet != "f05678" || x.From != testOther || x.To != "f05678" {
+		t.Errorf("got wallet %s, %s -> %s, want f05678, %s -> f05678", x.Wallet, x.From, x.To, testOther)
+	}
+	if x := xfers[0]; x.outgoing(x.Wallet) {
+		t.Error("receive of the miner resolved as outgoing")
+	}
+	// IDs without a robust address are left as they are
+	if x := xfers[1]; x.Wallet != testWallet || x.To != "f09999" {
+		t.Errorf("got wallet %s, to %s, want %s, f09999", x.Wallet, x.To, testWallet)
+	}
+}
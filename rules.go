@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A ruleSet classifies transfers by their first matching rule with a
+// category, and tags them with every matching rule's tags. Rules files are
+// YAML (or JSON), e.g.
+//
+//	rules:
+//	  - name: SP payouts
+//	    match:
+//	      counterparty: f1abc...
+//	      direction: in
+//	      min_amount: "10"
+//	    category: income
+//	    tags: [storage, payout]
+type ruleSet struct {
+	Rules []rule `yaml:"rules"`
+}
+
+type rule struct {
+	Name     string    `yaml:"name"`
+	Match    ruleMatch `yaml:"match"`
+	Category string    `yaml:"category"`
+	Tags     []string  `yaml:"tags"`
+
+	min, max *big.Int // Match amounts in units of 10^-18
+}
+
+// ruleMatch conditions must all hold for a rule to match. Empty conditions
+// match anything.
+type ruleMatch struct {
+	From         string `yaml:"from"`
+	To           string `yaml:"to"`
+	Counterparty string `yaml:"counterparty"` // the address on the other side from the wallet
+	Direction    string `yaml:"direction"`    // in or out
+	Method       string `yaml:"method"`       // requires --messages
+	Type         string `yaml:"type"`         // as for --types
+	MinAmount    string `yaml:"min_amount"`   // in whole units of the currency moved, inclusive
+	MaxAmount    string `yaml:"max_amount"`   // in whole units of the currency moved, inclusive
+}
+
+// loadRules reads and validates a rules file. Rules matching by method need
+// messages to be retrieved, which methods says they are.
+func loadRules(path string, methods bool) (*ruleSet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rs ruleSet
+	if err := yaml.Unmarshal(b, &rs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("#%d", i+1)
+		}
+		fail := func(err error) error { return fmt.Errorf("%s: rule %s: %w", path, r.Name, err) }
+
+		m := &r.Match
+		for _, addr := range []*string{&m.From, &m.To, &m.Counterparty} {
+			if *addr == "" {
+				continue // unset, so matching any address
+			}
+			if *addr, err = normalizeAddress(*addr); err != nil {
+				return nil, fail(err)
+			}
+		}
+		switch m.Direction {
+		case "", "in", "out":
+		default:
+			return nil, fail(fmt.Errorf("direction must be in or out, not %q", m.Direction))
+		}
+		if m.Method != "" && !methods {
+			return nil, fail(errors.New("matching by method needs --messages"))
+		}
+		if m.Type != "" && !slices.Contains(transferTypes, m.Type) {
+			return nil, fail(fmt.Errorf("unknown type %q", m.Type))
+		}
+		if m.MinAmount != "" {
+			if r.min, err = parseFIL(m.MinAmount); err != nil {
+				return nil, fail(err)
+			}
+		}
+		if m.MaxAmount != "" {
+			if r.max, err = parseFIL(m.MaxAmount); err != nil {
+				return nil, fail(err)
+			}
+		}
+	}
+	return &rs, nil
+}
+
+// matches reports whether x meets all of r's conditions. Amounts are
+// compared by absolute value, in whole units of x's currency.
+func (r *rule) matches(x Transfer) bool {
+	m := r.Match
+	direction := "in"
+	if x.outgoing(x.Wallet) {
+		direction = "out"
+	}
+	switch {
+	case m.From != "" && m.From != x.From,
+		m.To != "" && m.To != x.To,
+		m.Counterparty != "" && m.Counterparty != x.counterparty(),
+		m.Direction != "" && m.Direction != direction,
+		m.Method != "" && !strings.EqualFold(m.Method, x.Method),
+		m.Type != "" && !slices.Contains(x.types(), m.Type),
+		r.min != nil && compareAmount(x, r.min) < 0,
+		r.max != nil && compareAmount(x, r.max) > 0:
+		return false
+	}
+	return true
+}
+
+// compareAmount compares the absolute amount of x with limit, a count of
+// 10^-18 units however many decimals x's currency has.
+func compareAmount(x Transfer, limit *big.Int) int {
+	amount := new(big.Int).Abs(x.Amount)
+	if d := x.decimals(); d < 18 {
+		amount.Mul(amount, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(18-d)), nil))
+	} else if d > 18 {
+		limit = new(big.Int).Mul(limit, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d-18)), nil))
+	}
+	return amount.Cmp(limit)
+}
+
+// apply sets the category and tags of each transfer from the rules it
+// matches.
+func (rs *ruleSet) apply(xfers []Transfer) {
+	for i := range xfers {
+		x := &xfers[i]
+		for _, r := range rs.Rules {
+			if !r.matches(*x) {
+				continue
+			}
+			if x.Category == "" {
+				x.Category = r.Category
+			}
+			for _, tag := range r.Tags {
+				if !slices.Contains(x.Tags, tag) {
+					x.Tags = append(x.Tags, tag)
+				}
+			}
+		}
+	}
+}
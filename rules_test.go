@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testWallet = "f1abjxfbp274xpdqcpuaykwkfb43omjotacm2p3za"
+	testOther  = "f16gheqz4loi7ibuts6n5wjrdybnqmy5ge2xybxsi"
+)
+
+func writeTestFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRulesUnsetAddresses(t *testing.T) {
+	path := writeTestFile(t, "rules.yaml", `
+rules:
+  - name: x
+    match:
+      direction: in
+    category: income
+`)
+	rs, err := loadRules(path, false)
+	if err != nil {
+		t.Fatalf("loadRules: %v", err)
+	}
+	m := rs.Rules[0].Match
+	if m.From != "" || m.To != "" || m.Counterparty != "" {
+		t.Errorf("unset addresses became %q, %q, %q", m.From, m.To, m.Counterparty)
+	}
+
+	xfers := []Transfer{{Wallet: testWallet, Kind: KindTransfer, From: testOther, To: testWallet, Amount: big.NewInt(1)}}
+	rs.apply(xfers)
+	if xfers[0].Category != "income" {
+		t.Errorf("category = %q, want income", xfers[0].Category)
+	}
+}
+
+func TestLoadRulesInvalidAddress(t *testing.T) {
+	path := writeTestFile(t, "rules.yaml", `
+rules:
+  - match:
+      counterparty: f1bad
+`)
+	if _, err := loadRules(path, false); err == nil {
+		t.Error("loadRules accepted an invalid counterparty")
+	}
+}
+
+func TestLoadRulesMethodNeedsMessages(t *testing.T) {
+	path := writeTestFile(t, "rules.yaml", `
+rules:
+  - match:
+      method: PublishStorageDeals
+    category: deals
+`)
+	if _, err := loadRules(path, false); err == nil {
+		t.Error("loadRules accepted a method rule without --messages")
+	}
+	if _, err := loadRules(path, true); err != nil {
+		t.Errorf("loadRules with --messages: %v", err)
+	}
+}
+
+func TestRuleAmountsInCurrencyUnits(t *testing.T) {
+	rs, err := loadRules(writeTestFile(t, "rules.yaml", `
+rules:
+  - match:
+      min_amount: "5"
+    category: large
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receive := func(amount int64, token *Token) Transfer {
+		return Transfer{Wallet: testWallet, Kind: KindTransfer, From: testOther, To: testWallet, Amount: big.NewInt(amount), Token: token}
+	}
+	usdc := &Token{Symbol: "USDC", Decimals: 6}
+	tests := []struct {
+		name string
+		xfer Transfer
+		want bool
+	}{
+		{"FIL above", receive(6e18, nil), true},
+		{"FIL below", receive(4e18, nil), false},
+		{"6-decimal token above", receive(6e6, usdc), true},
+		{"6-decimal token below", receive(4e6, usdc), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rs.Rules[0].matches(tt.xfer); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math/big"
+	"slices"
+)
+
+// markSelfTransfers flags the transfers whose sender and recipient are both
+// among own, which includes any with the same sender and recipient. A message
+// sent from an address to itself shows up as a send and a matching receive;
+// only the send is kept, so it's exported once rather than as an IN/OUT pair.
+func markSelfTransfers(xfers []Transfer, own []string) []Transfer {
+	type leg struct{ message, amount string }
+	sent := make(map[leg]bool)
+	for i := range xfers {
+		xfer := &xfers[i]
+		switch xfer.Kind {
+		case KindTransfer, KindInternal, KindToken, KindNFT:
+		default:
+			continue
+		}
+		if xfer.FeeOnly {
+			continue
+		}
+		xfer.Self = xfer.From == xfer.To || (slices.Contains(own, xfer.From) && slices.Contains(own, xfer.To))
+		if xfer.Self && xfer.From == xfer.To && xfer.Amount.Sign() < 0 {
+			sent[leg{xfer.MessageID, new(big.Int).Neg(xfer.Amount).String()}] = true
+		}
+	}
+	return slices.DeleteFunc(xfers, func(x Transfer) bool {
+		return x.Self && x.From == x.To && x.Amount.Sign() > 0 && sent[leg{x.MessageID, x.Amount.String()}]
+	})
+}
+
+// pairInternalTransfers matches each self-transfer leaving one of the user's
+// accounts with the leg arriving in another, keeping only the outgoing leg
+// and recording the account it went to. Without this, moving funds between
+// accounts exported together reads as an expense plus unrelated income. The
+// accounts may be sub-accounts of a wallet, or wallets exported together.
+func pairInternalTransfers(xfers []Transfer) []Transfer {
+	type leg struct {
+		kind     Kind
+		message  string
+		amount   string
+		from, to string
+	}
+	incoming := make(map[leg][]int)
+	for i, x := range xfers {
+		if x.Self && x.Amount.Sign() > 0 {
+			l := leg{x.Kind, x.MessageID, x.Amount.String(), x.From, x.To}
+			incoming[l] = append(incoming[l], i)
+		}
+	}
+
+	paired := make(map[int]bool)
+	for i := range xfers {
+		out := &xfers[i]
+		if !out.Self || out.Amount.Sign() >= 0 || out.ToWallet != "" {
+			continue
+		}
+		l := leg{out.Kind, out.MessageID, new(big.Int).Neg(out.Amount).String(), out.From, out.To}
+		for j, in := range incoming[l] {
+			if xfers[in].Account == out.Account && xfers[in].Wallet == out.Wallet {
+				continue
+			}
+			out.ToAccount = xfers[in].Account
+			out.ToWallet = xfers[in].Wallet
+			paired[in] = true
+			incoming[l] = slices.Delete(incoming[l], j, j+1)
+			break
+		}
+	}
+
+	kept := xfers[:0]
+	for i, x := range xfers {
+		if !paired[i] {
+			kept = append(kept, x)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	registerFormat("sqlite", format{description: "SQLite database, added to with --append", ext: ".db", writeFile: writeSQLite})
+}
+
+// sqliteSchema creates the tables of the SQLite format, if missing. Amounts
+// are TEXT integers in the smallest unit, exact beyond SQLite's 64-bit
+// integers, with REAL approximations in whole units alongside for quick
+// aggregation. A transfer is identified by its wallet, message, kind, parties
+// and amount, so exporting again updates rows rather than duplicating them.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS transfers (
+	id             INTEGER PRIMARY KEY,
+	wallet         TEXT NOT NULL,
+	kind           TEXT NOT NULL,
+	height         INTEGER NOT NULL,
+	timestamp      TEXT NOT NULL, -- RFC 3339, UTC
+	message_id     TEXT NOT NULL,
+	"from"         TEXT NOT NULL,
+	"to"           TEXT NOT NULL,
+	counterparty   TEXT NOT NULL,
+	amount         TEXT NOT NULL,
+	amount_units   REAL NOT NULL,
+	currency       TEXT NOT NULL,
+	token_contract TEXT,
+	token_id       TEXT,
+	fee_only       INTEGER NOT NULL,
+	self           INTEGER NOT NULL,
+	pending        INTEGER NOT NULL,
+	method         TEXT,
+	category       TEXT,
+	tags           TEXT, -- semicolon separated
+	account        TEXT,
+	to_account     TEXT,
+	note           TEXT,
+	exit_code      INTEGER,
+	UNIQUE (wallet, message_id, kind, "from", "to", amount)
+);
+CREATE INDEX IF NOT EXISTS transfers_wallet_timestamp ON transfers (wallet, timestamp);
+CREATE INDEX IF NOT EXISTS transfers_message_id ON transfers (message_id);
+CREATE INDEX IF NOT EXISTS transfers_counterparty ON transfers (counterparty);
+
+CREATE TABLE IF NOT EXISTS fees (
+	transfer_id          INTEGER PRIMARY KEY REFERENCES transfers (id),
+	miner_fee            TEXT,
+	burn_fee             TEXT,
+	total_fil            REAL NOT NULL,
+	base_fee_burn        TEXT,
+	over_estimation_burn TEXT,
+	miner_tip            TEXT
+);
+
+CREATE TABLE IF NOT EXISTS counterparties (
+	address    TEXT PRIMARY KEY,
+	label      TEXT,
+	first_seen TEXT NOT NULL,
+	last_seen  TEXT NOT NULL
+);
+`
+
+// writeSQLite writes xfers to the SQLite database at path, creating it or,
+// with --append, adding to the transfers already in it in a single
+// transaction.
+func writeSQLite(path string, xfers []Transfer, opts exportOptions) (err error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("creating tables in %s: %w", path, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	insertTransfer, err := tx.Prepare(`
+		INSERT INTO transfers (wallet, kind, height, timestamp, message_id, "from", "to", counterparty,
+			amount, amount_units, currency, token_contract, token_id, fee_only, self, pending,
+			method, category, tags, account, to_account, note, exit_code)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (wallet, message_id, kind, "from", "to", amount) DO UPDATE SET
+			pending = excluded.pending,
+			method = coalesce(excluded.method, method),
+			category = coalesce(excluded.category, category),
+			tags = coalesce(excluded.tags, tags),
+			account = coalesce(excluded.account, account),
+			to_account = coalesce(excluded.to_account, to_account),
+			note = coalesce(excluded.note, note),
+			exit_code = coalesce(excluded.exit_code, exit_code)
+		RETURNING id`)
+	if err != nil {
+		return err
+	}
+	insertFees, err := tx.Prepare(`
+		INSERT INTO fees (transfer_id, miner_fee, burn_fee, total_fil, base_fee_burn, over_estimation_burn, miner_tip)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (transfer_id) DO UPDATE SET
+			base_fee_burn = coalesce(excluded.base_fee_burn, base_fee_burn),
+			over_estimation_burn = coalesce(excluded.over_estimation_burn, over_estimation_burn),
+			miner_tip = coalesce(excluded.miner_tip, miner_tip)`)
+	if err != nil {
+		return err
+	}
+	upsertCounterparty, err := tx.Prepare(`
+		INSERT INTO counterparties (address, label, first_seen, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT (address) DO UPDATE SET
+			label = coalesce(excluded.label, label),
+			first_seen = min(excluded.first_seen, first_seen),
+			last_seen = max(excluded.last_seen, last_seen)`)
+	if err != nil {
+		return err
+	}
+
+	for _, x := range xfers {
+		when := x.Timestamp.UTC().Format(time.RFC3339)
+		var contract, id any
+		if x.Token != nil {
+			contract, id = x.Token.Contract, sqlNull(x.Token.ID)
+		}
+		var exitCode any
+		if x.ExitCode != nil {
+			exitCode = *x.ExitCode
+		}
+		units, _ := x.units(x.Amount).Float64()
+
+		var rowID int64
+		err = insertTransfer.QueryRow(x.Wallet, string(x.Kind), x.Height, when, x.MessageID, x.From, x.To, x.counterparty(),
+			x.Amount.String(), units, x.Ticker(), contract, id, x.FeeOnly, x.Self, x.Pending,
+			sqlNull(x.Method), sqlNull(x.Category), sqlNull(strings.Join(x.Tags, ";")),
+			sqlNull(x.Account), sqlNull(x.ToAccount), sqlNull(x.Note), exitCode).Scan(&rowID)
+		if err != nil {
+			return fmt.Errorf("inserting transfer of message %s: %w", x.MessageID, err)
+		}
+
+		if fee := x.fee(); fee.Sign() != 0 {
+			total, _ := attoFILToFIL(fee).Float64()
+			_, err = insertFees.Exec(rowID, sqlInt(x.MinerFee), sqlInt(x.BurnFee), total,
+				sqlInt(x.BaseFeeBurn), sqlInt(x.OverEstimationBurn), sqlInt(x.MinerTip))
+			if err != nil {
+				return fmt.Errorf("inserting fees of message %s: %w", x.MessageID, err)
+			}
+		}
+
+		if _, err = upsertCounterparty.Exec(x.counterparty(), sqlNull(x.Label), when, when); err != nil {
+			return fmt.Errorf("recording counterparty %s: %w", x.counterparty(), err)
+		}
+	}
+	return tx.Commit()
+}
+
+// sqlNull stores empty strings as NULL.
+func sqlNull(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// sqlInt stores v as a TEXT integer, or NULL if unset.
+func sqlInt(v *big.Int) any {
+	if v == nil {
+		return nil
+	}
+	return v.String()
+}
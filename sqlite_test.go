@@ -0,0 +1,51 @@
+package main
+
+import (
+	"database/sql"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sqliteCount(t *testing.T, path string) int {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var n int
+	if err := db.QueryRow(`SELECT count(*) FROM transfers`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSQLiteOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfers.db")
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	first := []Transfer{{Wallet: testWallet, Kind: KindTransfer, Timestamp: at, MessageID: "bafy1", From: testOther, To: testWallet, Amount: big.NewInt(1)}}
+	second := []Transfer{{Wallet: testWallet, Kind: KindTransfer, Timestamp: at, MessageID: "bafy2", From: testOther, To: testWallet, Amount: big.NewInt(2)}}
+	f := formats["sqlite"]
+
+	if err := f.writeTo(path, first, exportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.writeTo(path, second, exportOptions{}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("err = %v, want the existing database refused", err)
+	}
+	if err := f.writeTo(path, second, exportOptions{appending: true}); err != nil {
+		t.Fatal(err)
+	}
+	if n := sqliteCount(t, path); n != 2 {
+		t.Errorf("appended database has %d transfers, want 2", n)
+	}
+	if err := f.writeTo(path, second, exportOptions{overwrite: true}); err != nil {
+		t.Fatal(err)
+	}
+	if n := sqliteCount(t, path); n != 1 {
+		t.Errorf("replaced database has %d transfers, want 1", n)
+	}
+}
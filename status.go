@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// runStatus pings each configured backend, reporting its latency, chain head
+// and any rate limit headers, to tell a local problem from an explorer outage.
+func runStatus(ctx context.Context, w io.Writer, opts fetchOptions) error {
+	backends := []string{opts.backend}
+	if opts.failover != "" {
+		backends = append(backends, opts.failover)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tSTATUS\tLATENCY\tHEIGHT\tHEAD AGE\tRATE LIMIT")
+	failed := 0
+	for _, name := range backends {
+		capture := &headerCapture{}
+		o := opts
+		o.maxRetries = 0 // report the first failure, don't hide it
+		o.wrapTransport = func(next http.RoundTripper) http.RoundTripper {
+			capture.next = next
+			return capture
+		}
+
+		src, err := newTransferSource(name, "", o)
+		if err != nil {
+			return err
+		}
+		hs, ok := source.Find[source.HeadSource](src)
+		if !ok {
+			fmt.Fprintf(tw, "%s\tunsupported\t-\t-\t-\t-\n", name)
+			continue
+		}
+
+		start := time.Now()
+		head, err := hs.ChainHead(ctx)
+		latency := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			failed++
+			fmt.Fprintf(tw, "%s\tDOWN: %v\t%v\t-\t-\t%s\n", name, err, latency, capture.rateLimits())
+			continue
+		}
+		age := time.Since(head.Timestamp).Round(time.Second)
+		fmt.Fprintf(tw, "%s\tOK\t%v\t%d\t%v\t%s\n", name, latency, head.Height, age, capture.rateLimits())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if failed == len(backends) {
+		return fmt.Errorf("no backend is reachable")
+	}
+	return nil
+}
+
+// headerCapture remembers the headers of the last response through it.
+type headerCapture struct {
+	next http.RoundTripper
+
+	mu     sync.Mutex
+	header http.Header
+}
+
+func (c *headerCapture) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := c.next.RoundTrip(req)
+	if err == nil {
+		c.mu.Lock()
+		c.header = resp.Header.Clone()
+		c.mu.Unlock()
+	}
+	return resp, err
+}
+
+// rateLimits formats any rate limit related response headers.
+func (c *headerCapture) rateLimits() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var pairs []string
+	for k, vs := range c.header {
+		lk := strings.ToLower(k)
+		if strings.Contains(lk, "ratelimit") || strings.Contains(lk, "rate-limit") || lk == "retry-after" {
+			pairs = append(pairs, k+"="+strings.Join(vs, ","))
+		}
+	}
+	if len(pairs) == 0 {
+		return "-"
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tableStyle is how transfers are printed to the terminal.
+type tableStyle struct {
+	color   bool          // color incoming green and outgoing red
+	plain   bool          // tab separated, for scripts, rather than aligned
+	columns []tableColumn // of --columns; defaultTableColumns if nil
+}
+
+// newTableStyle picks the style of --no-color and --plain for printing to f,
+// only coloring a terminal, and not where $NO_COLOR is set.
+func newTableStyle(f *os.File, noColor, plain bool) tableStyle {
+	_, noColorEnv := os.LookupEnv("NO_COLOR")
+	return tableStyle{
+		color: !noColor && !plain && !noColorEnv && isTerminal(f),
+		plain: plain,
+	}
+}
+
+// tableColumn is a column of the table transfers are printed in.
+type tableColumn struct {
+	name   string // as given to --columns
+	header string
+	value  func(x Transfer, opts exportOptions) string
+	right  bool // aligned right, as numbers are
+}
+
+// tableColumns are the columns --columns can choose from, in the order
+// usage lists them.
+var tableColumns = []tableColumn{
+	{name: "date", header: "DATE", value: func(x Transfer, opts exportOptions) string {
+		return opts.localTime(x.Timestamp).Format("2006-01-02 15:04:05")
+	}},
+	{name: "height", header: "HEIGHT", right: true, value: func(x Transfer, opts exportOptions) string {
+		return strconv.Itoa(x.Height)
+	}},
+	{name: "wallet", header: "WALLET", value: func(x Transfer, opts exportOptions) string {
+		return cmp.Or(opts.names[x.Wallet], x.Wallet)
+	}},
+	{name: "kind", header: "KIND", value: func(x Transfer, opts exportOptions) string {
+		if x.Pending {
+			return string(x.Kind) + " (pending)"
+		}
+		return string(x.Kind)
+	}},
+	{name: "flow", header: "FLOW", value: func(x Transfer, opts exportOptions) string { return x.flow().String() }},
+	{name: "amount", header: "AMOUNT", right: true, value: func(x Transfer, opts exportOptions) string {
+		amount, decimals := x.moved()
+		return opts.amounts.format(amount, decimals)
+	}},
+	{name: "currency", header: "CURRENCY", value: func(x Transfer, opts exportOptions) string { return x.Ticker() }},
+	{name: "fee", header: "FEE", right: true, value: func(x Transfer, opts exportOptions) string {
+		if fee := x.fee(); fee.Sign() != 0 {
+			return opts.amounts.format(fee, 18)
+		}
+		return ""
+	}},
+	{name: "balance", header: "BALANCE", right: true, value: func(x Transfer, opts exportOptions) string {
+		if x.Balance == nil {
+			return ""
+		}
+		return opts.amounts.format(x.Balance, 18)
+	}},
+	{name: "from", header: "FROM", value: func(x Transfer, opts exportOptions) string { return x.From }},
+	{name: "to", header: "TO", value: func(x Transfer, opts exportOptions) string { return x.To }},
+	{name: "counterparty", header: "COUNTERPARTY", value: func(x Transfer, opts exportOptions) string {
+		return cmp.Or(x.Label, x.counterparty())
+	}},
+	{name: "label", header: "LABEL", value: func(x Transfer, opts exportOptions) string { return x.Label }},
+	{name: "method", header: "METHOD", value: func(x Transfer, opts exportOptions) string { return x.Method }},
+	{name: "category", header: "CATEGORY", value: func(x Transfer, opts exportOptions) string { return x.Category }},
+	{name: "tags", header: "TAGS", value: func(x Transfer, opts exportOptions) string { return strings.Join(x.Tags, ",") }},
+	{name: "note", header: "NOTE", value: func(x Transfer, opts exportOptions) string { return x.Note }},
+	{name: "cid", header: "MESSAGE", value: func(x Transfer, opts exportOptions) string { return x.MessageID }},
+}
+
+// defaultTableColumns are printed unless --columns says otherwise.
+const defaultTableColumns = "date,kind,flow,amount,currency,fee,counterparty,cid"
+
+// tableColumnNames lists the names of tableColumns.
+func tableColumnNames() []string {
+	names := make([]string, len(tableColumns))
+	for i, c := range tableColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// parseTableColumns reads --columns, comma separated names of tableColumns
+// in the order to print them.
+func parseTableColumns(s string) ([]tableColumn, error) {
+	var columns []tableColumn
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		i := slices.IndexFunc(tableColumns, func(c tableColumn) bool { return c.name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown --columns column %q, want some of %s", name, strings.Join(tableColumnNames(), ","))
+		}
+		columns = append(columns, tableColumns[i])
+	}
+	return columns, nil
+}
+
+// transferTable prints transfers to w in rows, one per transfer, under a
+// header printed with the first of them.
+type transferTable struct {
+	w       io.Writer
+	style   tableStyle
+	opts    exportOptions
+	columns []tableColumn
+	started bool
+}
+
+func newTransferTable(w io.Writer, opts exportOptions) *transferTable {
+	columns := opts.table.columns
+	if columns == nil {
+		columns, _ = parseTableColumns(defaultTableColumns)
+	}
+	return &transferTable{w: w, style: opts.table, opts: opts, columns: columns}
+}
+
+// write prints a row for each of xfers, aligning them with each other. A
+// table printed a batch at a time, as watch does, is aligned by batch.
+func (t *transferTable) write(xfers []Transfer) error {
+	if len(xfers) == 0 {
+		return nil
+	}
+	rows := make([][]string, 0, len(xfers)+1)
+	if !t.started {
+		header := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			header[i] = c.header
+		}
+		rows = append(rows, header)
+	}
+	for _, x := range xfers {
+		row := make([]string, len(t.columns))
+		for i, c := range t.columns {
+			row[i] = c.value(x, t.opts)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(t.columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	var b strings.Builder
+	for r, row := range rows {
+		header := !t.started && r == 0
+		var line strings.Builder
+		for i, cell := range row {
+			switch {
+			case t.style.plain:
+				if i > 0 {
+					line.WriteByte('\t')
+				}
+				line.WriteString(cell)
+				continue
+			case i > 0:
+				line.WriteString("  ")
+			}
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			if t.columns[i].right {
+				line.WriteString(pad + cell)
+			} else if i < len(row)-1 {
+				line.WriteString(cell + pad)
+			} else {
+				line.WriteString(cell) // no trailing spaces
+			}
+		}
+		s := line.String()
+		if t.style.color && header {
+			s = "\033[1m" + s + "\033[0m" // bold
+		} else if t.style.color {
+			s = t.color(xfers[r-len(rows)+len(xfers)]) + s + "\033[0m"
+		}
+		b.WriteString(s + "\n")
+	}
+	t.started = true
+	_, err := io.WriteString(t.w, b.String())
+	return err
+}
+
+// color is the escape sequence coloring the row of x by its flow.
+func (t *transferTable) color(x Transfer) string {
+	switch {
+	case x.Pending:
+		return "\033[33m" // yellow
+	case x.flow() == flowIn:
+		return "\033[32m" // green
+	case x.flow() == flowOut:
+		return "\033[31m" // red
+	}
+	return "\033[2m" // dim
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// isEthAccount reports whether address is an f410 delegated address, the only
+// kind that can hold FEVM tokens.
+func isEthAccount(address string) bool {
+	return strings.HasPrefix(address, "f410") || strings.HasPrefix(address, "t410")
+}
+
+// tokenTransfers converts the token transfers of wallet into Transfers of
+// KindToken, negative when leaving wallet.
+func tokenTransfers(wallet string, recs []source.TokenTransfer) ([]Transfer, error) {
+	xfers := make([]Transfer, 0, len(recs))
+	for _, r := range recs {
+		amount, ok := new(big.Int).SetString(r.Value, 10)
+		if !ok {
+			return nil, fmt.Errorf("Failed to parse %s amount %s", r.Symbol, r.Value)
+		}
+		if r.From == wallet {
+			amount.Neg(amount)
+		}
+		xfers = append(xfers, Transfer{
+			Kind:      KindToken,
+			Height:    r.Height,
+			Timestamp: time.Unix(r.Timestamp, 0).UTC(),
+			MessageID: r.Message,
+			From:      r.From,
+			To:        r.To,
+			Amount:    amount,
+			Token: &Token{
+				Contract: r.Contract,
+				Symbol:   r.Symbol,
+				Decimals: r.Decimals,
+			},
+		})
+	}
+	return xfers, nil
+}
+
+// nftTransfers converts NFT transfers into zero-value Transfers of KindNFT.
+func nftTransfers(recs []source.NFTTransfer) []Transfer {
+	xfers := make([]Transfer, 0, len(recs))
+	for _, r := range recs {
+		xfers = append(xfers, Transfer{
+			Kind:      KindNFT,
+			Height:    r.Height,
+			Timestamp: time.Unix(r.Timestamp, 0).UTC(),
+			MessageID: r.Message,
+			From:      r.From,
+			To:        r.To,
+			Amount:    new(big.Int),
+			Token: &Token{
+				Contract: r.Contract,
+				Symbol:   r.Symbol,
+				ID:       r.TokenID,
+			},
+		})
+	}
+	return xfers
+}
+
+// internalTransfers converts contract internal transfer records into
+// Transfers of KindInternal, dropping any that duplicate the top level value
+// transfer of a message already in existing.
+func internalTransfers(recs []source.Record, existing []Transfer) ([]Transfer, error) {
+	// A message's fee records may precede its send, so a Transfer's From and
+	// To aren't reliable here; match on the signed amount instead
+	type leg struct{ message, amount string }
+	known := make(map[leg]bool, len(existing))
+	for _, x := range existing {
+		if x.Kind == KindTransfer {
+			known[leg{x.MessageID, x.Amount.String()}] = true
+		}
+	}
+
+	var xfers []Transfer
+	for _, r := range recs {
+		amount, ok := new(big.Int).SetString(r.Value, 10)
+		if !ok {
+			return nil, fmt.Errorf("Failed to parse amount %s", r.Value)
+		}
+		if known[leg{r.Message, amount.String()}] {
+			continue
+		}
+		xfer := newTransfer(KindInternal, r)
+		xfer.Amount = amount
+		xfers = append(xfers, xfer)
+	}
+	return xfers, nil
+}
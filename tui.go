@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runTUI retrieves the transfers of wallets and lets them be browsed in the
+// terminal: scrolled, searched, filtered by flow, marked and tagged. The
+// transfers shown, or those marked if any are, can then be exported as
+// eopts says, with the tags added.
+func runTUI(ctx context.Context, wallets []string, opts fetchOptions, eopts exportOptions) error {
+	xfers, src, err := collectTransfers(ctx, wallets, opts, &eopts)
+	if err != nil {
+		return err
+	}
+	if len(xfers) == 0 {
+		return fmt.Errorf("no transfers of %s to browse", strings.Join(wallets, ", "))
+	}
+
+	m := newBrowser(xfers, eopts)
+	final, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	if err != nil {
+		return err
+	}
+	m = final.(*browser)
+	if !m.export {
+		return nil
+	}
+
+	eopts.selected = len(m.shown) < len(m.xfers)
+	if m.tagged && eopts.rules == nil {
+		eopts.rules = &ruleSet{} // for the Tags column
+	}
+	return writeExport(ctx, wallets, m.selection(), src, eopts)
+}
+
+// browser is the bubbletea model of runTUI.
+type browser struct {
+	xfers []Transfer
+	opts  exportOptions
+
+	shown  []int // indexes of xfers matching query and flow
+	query  string
+	flow   flow
+	byFlow bool // whether flow filters shown
+
+	marked map[int]bool
+	tagged bool // a tag was added, to be exported
+
+	cursor, top   int // index into shown of the selected and first row
+	width, height int
+
+	input  string // being typed after / or t
+	typing rune   // / for a search, t for a tag, or 0
+	status string // shown in the footer until the next key
+
+	export bool // quit to export the selection
+}
+
+func newBrowser(xfers []Transfer, opts exportOptions) *browser {
+	m := &browser{xfers: xfers, opts: opts, marked: make(map[int]bool), height: 24, width: 80}
+	m.refilter()
+	return m
+}
+
+func (m *browser) Init() tea.Cmd { return nil }
+
+func (m *browser) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.scroll()
+	case tea.KeyMsg:
+		m.status = ""
+		if m.typing != 0 {
+			m.edit(msg)
+			return m, nil
+		}
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "e":
+			if len(m.selection()) == 0 {
+				m.status = "Nothing to export"
+				break
+			}
+			m.export = true
+			return m, tea.Quit
+		case "up", "k":
+			m.move(-1)
+		case "down", "j":
+			m.move(1)
+		case "pgup", "ctrl+b":
+			m.move(-m.rows())
+		case "pgdown", "ctrl+f":
+			m.move(m.rows())
+		case "home", "g":
+			m.move(-len(m.shown))
+		case "end", "G":
+			m.move(len(m.shown))
+		case "/":
+			m.typing, m.input = '/', m.query
+		case "t":
+			if len(m.shown) > 0 {
+				m.typing, m.input = 't', ""
+			}
+		case "f":
+			m.cycleFlow()
+		case "esc":
+			m.query = ""
+			m.refilter()
+		case " ":
+			if len(m.shown) > 0 {
+				i := m.shown[m.cursor]
+				m.marked[i] = !m.marked[i]
+				if !m.marked[i] {
+					delete(m.marked, i)
+				}
+				m.move(1)
+			}
+		}
+	}
+	return m, nil
+}
+
+// edit handles a key typed into the search or tag input.
+func (m *browser) edit(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		switch m.typing {
+		case '/':
+			m.query = m.input
+			m.refilter()
+		case 't':
+			m.tag(strings.TrimSpace(m.input))
+		}
+		m.typing = 0
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.typing = 0
+	case tea.KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.input += string(msg.Runes)
+	}
+}
+
+// tag adds tag to the marked transfers, or else the selected one.
+func (m *browser) tag(tag string) {
+	if tag == "" {
+		return
+	}
+	targets := []int{m.shown[m.cursor]}
+	if len(m.marked) > 0 {
+		targets = targets[:0]
+		for i := range m.marked {
+			targets = append(targets, i)
+		}
+	}
+	for _, i := range targets {
+		if x := &m.xfers[i]; !slices.Contains(x.Tags, tag) {
+			x.Tags = append(slices.Clip(x.Tags), tag)
+			m.tagged = true
+		}
+	}
+	m.status = fmt.Sprintf("Tagged %d transfers %s", len(targets), tag)
+}
+
+// cycleFlow steps the flow filter through in, out, internal and off.
+func (m *browser) cycleFlow() {
+	switch {
+	case !m.byFlow:
+		m.byFlow, m.flow = true, flowIn
+	case m.flow == flowIn:
+		m.flow = flowOut
+	case m.flow == flowOut:
+		m.flow = flowNone
+	default:
+		m.byFlow = false
+	}
+	m.refilter()
+}
+
+// refilter recomputes the shown transfers, keeping the selected one if it
+// still matches.
+func (m *browser) refilter() {
+	selected := -1
+	if m.cursor < len(m.shown) {
+		selected = m.shown[m.cursor]
+	}
+	query := strings.ToLower(m.query)
+	m.shown = m.shown[:0]
+	m.cursor = 0
+	for i, x := range m.xfers {
+		if m.byFlow && x.flow() != m.flow || query != "" && !strings.Contains(m.searchText(x), query) {
+			continue
+		}
+		if i == selected {
+			m.cursor = len(m.shown)
+		}
+		m.shown = append(m.shown, i)
+	}
+	m.scroll()
+}
+
+// searchText is what a search matches x against, lowercased.
+func (m *browser) searchText(x Transfer) string {
+	amount, decimals := x.moved()
+	fields := []string{x.MessageID, x.From, x.To, string(x.Kind), m.opts.amounts.format(amount, decimals),
+		x.Method, x.Label, x.Category, x.Note}
+	if x.Token != nil {
+		fields = append(fields, x.Token.Symbol, x.Token.Contract)
+	}
+	fields = append(fields, x.Tags...)
+	return strings.ToLower(strings.Join(fields, "\n"))
+}
+
+// selection is what is exported: the marked transfers still shown, or
+// else all of those shown.
+func (m *browser) selection() []Transfer {
+	var xfers []Transfer
+	for _, i := range m.shown {
+		if len(m.marked) == 0 || m.marked[i] {
+			xfers = append(xfers, m.xfers[i])
+		}
+	}
+	return xfers
+}
+
+func (m *browser) move(n int) {
+	m.cursor = max(0, min(m.cursor+n, len(m.shown)-1))
+	m.scroll()
+}
+
+// rows is how many transfers fit between the header and footer.
+func (m *browser) rows() int {
+	return max(1, m.height-3)
+}
+
+// scroll keeps the cursor on screen.
+func (m *browser) scroll() {
+	switch {
+	case m.cursor < m.top:
+		m.top = m.cursor
+	case m.cursor >= m.top+m.rows():
+		m.top = m.cursor - m.rows() + 1
+	}
+	m.top = max(0, min(m.top, len(m.shown)-m.rows()))
+}
+
+func (m *browser) View() string {
+	var b strings.Builder
+	header := fmt.Sprintf("%d of %d transfers", len(m.shown), len(m.xfers))
+	if m.query != "" {
+		header += fmt.Sprintf(" matching %q", m.query)
+	}
+	if m.byFlow {
+		header += ", " + m.flow.String()
+	}
+	if len(m.marked) > 0 {
+		header += fmt.Sprintf(", %d marked", len(m.marked))
+	}
+	b.WriteString(m.fit(header) + "\n\n")
+
+	for row := m.top; row < min(m.top+m.rows(), len(m.shown)); row++ {
+		i := m.shown[row]
+		line := m.line(m.xfers[i], m.marked[i])
+		if row == m.cursor {
+			line = "\033[7m" + m.fit(line) + "\033[0m" // reversed
+		} else {
+			line = m.fit(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	for row := len(m.shown) - m.top; row < m.rows(); row++ {
+		b.WriteString("\n")
+	}
+
+	switch {
+	case m.typing == '/':
+		b.WriteString(m.fit("Search: " + m.input + "_"))
+	case m.typing == 't':
+		b.WriteString(m.fit("Tag: " + m.input + "_"))
+	case m.status != "":
+		b.WriteString(m.fit(m.status))
+	default:
+		b.WriteString(m.fit("↑↓ scroll  / search  esc clear  f flow  space mark  t tag  e export  q quit"))
+	}
+	return b.String()
+}
+
+// line is the row of the list showing x.
+func (m *browser) line(x Transfer, marked bool) string {
+	mark := " "
+	if marked {
+		mark = "*"
+	}
+	amount, decimals := x.moved()
+	s := fmt.Sprintf("%s %s  %-8s %24s %-6s %-8s", mark, m.opts.localTime(x.Timestamp).Format("2006-01-02 15:04"),
+		x.Kind, m.opts.amounts.format(amount, decimals), x.Ticker(), x.flow())
+	if x.Label != "" {
+		s += " " + x.Label
+	} else {
+		s += " " + x.counterparty()
+	}
+	if len(x.Tags) > 0 {
+		s += " [" + strings.Join(x.Tags, ", ") + "]"
+	}
+	if x.Pending {
+		s += " (pending)"
+	}
+	return s
+}
+
+// fit cuts s to the width of the terminal.
+func (m *browser) fit(s string) string {
+	if r := []rune(s); len(r) > m.width && m.width > 0 {
+		return string(r[:m.width])
+	}
+	return s
+}
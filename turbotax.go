@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+func init() {
+	registerFormat("turbotax", format{description: "TurboTax digital asset CSV", ext: ".csv", write: writeTurboTaxCSV, csv: true, appendKey: "Transaction Hash"})
+}
+
+// writeTurboTaxCSV writes xfers in TurboTax Online's crypto CSV layout.
+// Rewards are Mining income, penalties and fee-only messages Expense, and
+// everything else a Deposit or Withdrawal. The form asks for UTC, so
+// --timezone doesn't apply.
+func writeTurboTaxCSV(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	writer := opts.csvWriter(w)
+	defer writer.Flush()
+
+	headers := []string{
+		"Date",
+		"Type",
+		"Sent Asset",
+		"Sent Amount",
+		"Received Asset",
+		"Received Amount",
+		"Fee Asset",
+		"Fee Amount",
+		"Market Value Currency", // Omitted, cost basis is imported from another source
+		"Market Value",
+		"Description",
+		"Transaction Hash",
+		"Transaction ID",
+	}
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
+
+	for _, m := range opts.movements(xfers) {
+		var typ string
+		switch {
+		case m.feeOnly, m.Kind == KindPenalty:
+			typ = "Expense"
+		case m.Kind == KindReward:
+			typ = "Mining"
+		case m.received != "":
+			typ = "Deposit"
+		default:
+			typ = "Withdrawal"
+		}
+
+		record := []string{
+			opts.date(m.Timestamp.UTC(), time.DateTime),
+			typ,
+			m.sentCurrency,
+			m.sent,
+			m.receivedCurrency,
+			m.received,
+			m.feeCurrency,
+			m.fee,
+			"",
+			"",
+			m.description(),
+			m.MessageID,
+			"",
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
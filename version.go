@@ -0,0 +1,84 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// version and buildDate are stamped at build time via -ldflags
+// "-X main.version=... -X main.buildDate=...", as the Makefile does. Builds
+// without them, such as go install, fall back on their build info.
+var (
+	version   = ""
+	buildDate = ""
+)
+
+// buildInfo describes the build of filfoxy running, for version and the
+// User-Agent, so an export can be traced to the code that produced it.
+type buildInfo struct {
+	version   string // release tag or module version, or dev
+	commit    string // VCS revision, if known
+	modified  bool   // built from a working tree with uncommitted changes
+	date      string // when built, or else when the commit was made
+	goVersion string
+}
+
+func readBuildInfo() buildInfo {
+	b := buildInfo{version: version, date: buildDate, goVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		b.version = cmp.Or(b.version, "dev")
+		return b
+	}
+	if b.version == "" && bi.Main.Version != "(devel)" {
+		b.version = bi.Main.Version
+	}
+	b.version = cmp.Or(b.version, "dev")
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.commit = s.Value
+		case "vcs.time":
+			b.date = cmp.Or(b.date, s.Value)
+		case "vcs.modified":
+			b.modified = s.Value == "true"
+		}
+	}
+	return b
+}
+
+// defaultUserAgent identifies filfoxy traffic, and its build, to explorer
+// operators.
+func defaultUserAgent() string {
+	b := readBuildInfo()
+	ua := "filfoxy/" + b.version + " ("
+	if b.commit != "" {
+		ua += b.commit[:min(len(b.commit), 12)]
+		if b.modified {
+			ua += "-dirty"
+		}
+		ua += "; "
+	}
+	return ua + "+https://github.com/mroth/filfoxy)"
+}
+
+// runVersion prints the build of filfoxy, for bug reports.
+func runVersion(w io.Writer) error {
+	b := readBuildInfo()
+	fmt.Fprintf(w, "filfoxy %s\n", b.version)
+	if b.commit != "" {
+		commit := b.commit
+		if b.modified {
+			commit += " (modified)"
+		}
+		fmt.Fprintf(w, "commit:  %s\n", commit)
+	}
+	if b.date != "" {
+		fmt.Fprintf(w, "built:   %s\n", b.date)
+	}
+	_, err := fmt.Fprintf(w, "go:      %s %s/%s\n", b.goVersion, runtime.GOOS, runtime.GOARCH)
+	return err
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mroth/filfoxy/pkg/source"
+)
+
+// vestingPoint is one row of the vesting command's output.
+type vestingPoint struct {
+	Height    int       `json:"height"`
+	Time      time.Time `json:"time"`
+	Locked    string    `json:"locked"` // attoFIL
+	LockedFIL string    `json:"locked_fil"`
+}
+
+// runVesting prints the remaining locked balance of a vesting multisig over
+// the course of its schedule.
+func runVesting(ctx context.Context, w io.Writer, args []string, opts fetchOptions) error {
+	fs := flag.NewFlagSet("vesting", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return usageError{errors.New("usage: vesting [--json] <address>")}
+	}
+	address, err := normalizeAddress(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	vesting, err := lookupVesting(ctx, address, opts)
+	if err != nil {
+		return err
+	}
+
+	schedule := vesting.Schedule()
+	points := make([]vestingPoint, len(schedule))
+	for i, p := range schedule {
+		points[i] = vestingPoint{
+			Height:    p.Height,
+			Time:      source.HeightTime(p.Height),
+			Locked:    p.Locked.String(),
+			LockedFIL: attoFILToFIL(p.Locked).Text('f', -1),
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(points)
+	}
+
+	if len(points) == 0 {
+		fmt.Fprintf(w, "%s has no vesting schedule\n", address)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tHEIGHT\tLOCKED (FIL)")
+	for _, p := range points {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", p.Time.Format(time.DateOnly), p.Height, p.LockedFIL)
+	}
+	return tw.Flush()
+}
+
+// lookupVesting retrieves the vesting schedule of address from the configured
+// backend.
+func lookupVesting(ctx context.Context, address string, opts fetchOptions) (source.Vesting, error) {
+	src, err := newSource(address, opts)
+	if err != nil {
+		return source.Vesting{}, err
+	}
+	vs, ok := source.Find[source.VestingSource](src)
+	if !ok {
+		return source.Vesting{}, fmt.Errorf("backend %s does not support vesting schedules", src.Name())
+	}
+	return vs.Vesting(ctx, address)
+}
+
+// annotateVestedUnlocks notes which outgoing transfers of a vesting account
+// withdrew funds while its schedule was still unlocking them, along with how
+// much remained locked at the time.
+func annotateVestedUnlocks(xfers []Transfer, wallet string, vesting source.Vesting) {
+	if vesting.IsZero() {
+		return
+	}
+	for i := range xfers {
+		xfer := &xfers[i]
+		if xfer.Kind != KindTransfer || xfer.From != wallet || xfer.Amount.Sign() >= 0 {
+			continue
+		}
+		if xfer.Height <= vesting.StartHeight || xfer.Height > vesting.EndHeight() {
+			continue
+		}
+		locked := vesting.LockedAt(xfer.Height)
+		xfer.Note = fmt.Sprintf("vested unlock (%s FIL still locked)", attoFILToFIL(locked).Text('f', -1))
+	}
+}
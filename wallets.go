@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// walletArgs expands the wallets given to a command: addresses or account
+// names, - for a list read from stdin, and the list in file, if set. Each
+// wallet is kept once, in the order first given.
+func walletArgs(args []string, file string, stdin io.Reader) ([]string, error) {
+	var wallets []string
+	add := func(s string) error {
+		wallet, err := normalizeAddress(s)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(wallets, wallet) {
+			wallets = append(wallets, wallet)
+		}
+		return nil
+	}
+
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		list, err := readWalletList(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, s := range list {
+			if err := add(s); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+	for _, arg := range args {
+		if arg != "-" {
+			if err := add(arg); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		list, err := readWalletList(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("stdin: %w", err)
+		}
+		for _, s := range list {
+			if err := add(s); err != nil {
+				return nil, fmt.Errorf("stdin: %w", err)
+			}
+		}
+	}
+	return wallets, nil
+}
+
+// readWalletList reads whitespace separated wallets, skipping # comments.
+func readWalletList(r io.Reader) ([]string, error) {
+	var list []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		list = append(list, strings.Fields(line)...)
+	}
+	return list, scanner.Err()
+}
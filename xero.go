@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io"
+)
+
+func init() {
+	registerFormat("xero", format{description: "Xero bank statement CSV, valued in the --countervalue-currency", ext: ".csv", write: writeXeroCSV, csv: true, appendKey: "Reference", fiat: true})
+}
+
+// writeXeroCSV writes xfers as a Xero bank statement of a FIL holding kept in
+// fiat, made of fiatLines.
+func writeXeroCSV(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	writer := opts.csvWriter(w)
+	defer writer.Flush()
+
+	if err := writer.Write([]string{"Date", "Amount", "Payee", "Description", "Reference"}); err != nil {
+		return err
+	}
+	for _, line := range fiatLines(xfers, opts) {
+		const xeroDate = "02/01/2006"
+		record := []string{
+			opts.date(opts.localTime(line.time), xeroDate),
+			opts.fiat(line.value),
+			line.payee,
+			line.description,
+			line.reference,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
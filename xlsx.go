@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func init() {
+	registerFormat("xlsx", format{description: "Excel workbook with a summary sheet", ext: ".xlsx", write: writeXLSX})
+}
+
+// xlsxColumns are the columns of the Transfers sheet, with their widths.
+var xlsxColumns = []struct {
+	name  string
+	width float64
+}{
+	{"Date", 20},
+	{"Wallet", 16},
+	{"Kind", 10},
+	{"Flow", 10},
+	{"Amount", 24},
+	{"Currency", 10},
+	{"Fee", 20},
+	{"Counterparty", 16},
+	{"Label", 20},
+	{"Method", 16},
+	{"Category", 16},
+	{"Tags", 16},
+	{"Note", 24},
+	{"Status", 10},
+	{"Message", 64},
+}
+
+// writeXLSX writes xfers as an Excel workbook: a Transfers sheet with a
+// frozen, filterable header and typed date and amount cells formatted with
+// their currency, and a Summary sheet totalling what each currency brought
+// in, sent out and paid in fees. Dates are in the export's time zone, which
+// Excel has no notion of. Amounts are Excel numbers, so only about 15
+// significant digits survive.
+func writeXLSX(w io.Writer, xfers []Transfer, opts exportOptions) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheet, summary = "Transfers", "Summary"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+	styles, err := newXLSXStyles(f)
+	if err != nil {
+		return err
+	}
+
+	header := make([]any, len(xlsxColumns))
+	for i, c := range xlsxColumns {
+		header[i] = c.name
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		if err := f.SetColWidth(sheet, col, col, c.width); err != nil {
+			return err
+		}
+	}
+	if err := writeXLSXHeader(f, sheet, header, styles.header); err != nil {
+		return err
+	}
+
+	for i, xfer := range xfers {
+		row := i + 2
+		amount, decimals := xfer.moved()
+		var fee any
+		if paid := xfer.fee(); paid.Sign() != 0 {
+			fee = xlsxNumber(opts.amounts.format(paid, 18))
+		}
+		status := "Confirmed"
+		if xfer.Pending {
+			status = "Pending"
+		}
+
+		local := opts.localTime(xfer.Timestamp)
+		values := []any{
+			// Excel serial dates are wall clock times, without a zone
+			time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, time.UTC),
+			xfer.Wallet,
+			string(xfer.Kind),
+			xfer.flow().String(),
+			xlsxNumber(opts.amounts.format(amount, decimals)),
+			xfer.Ticker(),
+			fee,
+			xfer.counterparty(),
+			xfer.Label,
+			xfer.Method,
+			xfer.Category,
+			strings.Join(xfer.Tags, ", "),
+			xfer.Note,
+			status,
+			xfer.MessageID,
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		if err := f.SetSheetRow(sheet, cell, &values); err != nil {
+			return err
+		}
+
+		amountStyle, err := styles.currency(xfer.Ticker())
+		if err != nil {
+			return err
+		}
+		for col, style := range map[string]int{"A": styles.date, "E": amountStyle, "G": styles.fil} {
+			cell := col + strconv.Itoa(row)
+			if err := f.SetCellStyle(sheet, cell, cell, style); err != nil {
+				return err
+			}
+		}
+	}
+
+	last, _ := excelize.CoordinatesToCellName(len(xlsxColumns), max(len(xfers)+1, 2))
+	if err := f.AutoFilter(sheet, "A1:"+last, nil); err != nil {
+		return err
+	}
+	if err := f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+
+	if err := writeXLSXSummary(f, summary, totalsByCurrency(xfers), opts, styles); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+// writeXLSXSummary adds a sheet totalling each currency, FIL first.
+func writeXLSXSummary(f *excelize.File, sheet string, totals []*currencyTotals, opts exportOptions, styles *xlsxStyles) error {
+	if _, err := f.NewSheet(sheet); err != nil {
+		return err
+	}
+	header := []any{"Currency", "Transfers", "Total In", "Total Out", "Fees", "Net"}
+	if err := writeXLSXHeader(f, sheet, header, styles.header); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "A", "B", 12); err != nil {
+		return err
+	}
+	if err := f.SetColWidth(sheet, "C", "F", 24); err != nil {
+		return err
+	}
+
+	for i, t := range totals {
+		row := []any{
+			t.ticker,
+			t.count,
+			xlsxNumber(opts.amounts.format(t.in, t.decimals)),
+			xlsxNumber(opts.amounts.format(t.out, t.decimals)),
+			xlsxNumber(opts.amounts.format(t.fee, t.decimals)),
+			xlsxNumber(opts.amounts.format(t.net(), t.decimals)),
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+		style, err := styles.currency(t.ticker)
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(sheet, fmt.Sprintf("C%d", i+2), fmt.Sprintf("F%d", i+2), style); err != nil {
+			return err
+		}
+	}
+	return f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
+
+// writeXLSXHeader writes the bold header row of sheet.
+func writeXLSXHeader(f *excelize.File, sheet string, header []any, style int) error {
+	if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return err
+	}
+	last, _ := excelize.CoordinatesToCellName(len(header), 1)
+	return f.SetCellStyle(sheet, "A1", last, style)
+}
+
+// xlsxStyles are the cell styles of a workbook, with a number format per
+// currency created as it is first needed.
+type xlsxStyles struct {
+	f          *excelize.File
+	header     int
+	date       int
+	fil        int
+	currencies map[string]int
+}
+
+func newXLSXStyles(f *excelize.File) (*xlsxStyles, error) {
+	s := &xlsxStyles{f: f, currencies: make(map[string]int)}
+	var err error
+	if s.header, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return nil, err
+	}
+	dateFormat := "yyyy-mm-dd hh:mm:ss"
+	if s.date, err = f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat}); err != nil {
+		return nil, err
+	}
+	if s.fil, err = s.currency("FIL"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// currency is the style showing amounts with thousands separators and the
+// ticker, such as 1,234.50 FIL.
+func (s *xlsxStyles) currency(ticker string) (int, error) {
+	if id, ok := s.currencies[ticker]; ok {
+		return id, nil
+	}
+	// Excel shows at most 15 significant digits anyway
+	numFmt := fmt.Sprintf(`#,##0.00########\ "%s"`, strings.ReplaceAll(ticker, `"`, ""))
+	id, err := s.f.NewStyle(&excelize.Style{CustomNumFmt: &numFmt})
+	if err != nil {
+		return 0, err
+	}
+	s.currencies[ticker] = id
+	return id, nil
+}
+
+// xlsxNumber turns a formatted amount into a cell number.
+func xlsxNumber(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}